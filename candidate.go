@@ -3,6 +3,7 @@ package inferrouter
 import (
 	"context"
 	"fmt"
+	"slices"
 )
 
 // resolveModel resolves a model name to the ordered steps of its ladder.
@@ -45,8 +46,9 @@ func buildCandidates(
 	providers map[string]Provider,
 	quotaStore QuotaStore,
 	health *HealthTracker,
-	spend *SpendTracker,
+	spend SpendStore,
 	inflight *InflightTracker,
+	latency *LatencyTracker,
 	requestModel string,
 ) ([]Candidate, error) {
 	refs, err := resolveModel(cfg, requestModel)
@@ -64,7 +66,9 @@ func buildCandidates(
 			if !ok {
 				continue
 			}
-			candidates = append(candidates, newCandidate(ctx, acc, prov, ref.Model, quotaStore, health, spend, inflight))
+			c := newCandidate(ctx, acc, prov, ref.Model, quotaStore, health, spend, inflight, latency)
+			c.Info = modelInfo(acc, ref)
+			candidates = append(candidates, c)
 		}
 	}
 
@@ -80,13 +84,14 @@ func newCandidate(
 	model string,
 	quotaStore QuotaStore,
 	health *HealthTracker,
-	spend *SpendTracker,
+	spend SpendStore,
 	inflight *InflightTracker,
+	latency *LatencyTracker,
 ) Candidate {
 	remaining, remainErr := quotaStore.Remaining(ctx, acc.ID)
 	// Fail-open: if we can't check remaining quota, assume free if configured.
 	// Reserve() will enforce the actual limit.
-	free := acc.DailyFree > 0 && (remaining > 0 || remainErr != nil)
+	free := acc.dailyQuota() > 0 && (remaining > 0 || remainErr != nil)
 
 	return Candidate{
 		Provider:               prov,
@@ -97,7 +102,9 @@ func newCandidate(
 		Remaining:              remaining,
 		QuotaUnit:              acc.QuotaUnit,
 		Health:                 health.GetHealth(acc.ID),
+		ProviderHealth:         health.GetProviderHealth(acc.Provider),
 		Inflight:               inflight.Get(acc.ID),
+		Latency:                latency.Get(acc.ID, model),
 		CostPerToken:           acc.CostPerToken,
 		CostPerInputToken:      acc.CostPerInputToken,
 		CostPerOutputToken:     acc.CostPerOutputToken,
@@ -105,31 +112,112 @@ func newCandidate(
 		CostPerImageInputToken: resolveModalityCost(acc.CostPerImageInputToken, acc.CostPerInputToken),
 		CostPerVideoInputToken: resolveModalityCost(acc.CostPerVideoInputToken, acc.CostPerInputToken),
 		MaxDailySpend:          acc.MaxDailySpend,
-		CurrentSpend:           spend.GetSpend(acc.ID),
+		CurrentSpend:           currentSpend(ctx, spend, acc),
 	}
 }
 
-// filterCandidates removes unhealthy candidates, enforces paid/spend limits,
-// and (when needMultimodal is true) drops providers that don't advertise
-// multimodal support.
-func filterCandidates(candidates []Candidate, allowPaid, needMultimodal bool) []Candidate {
-	filtered := make([]Candidate, 0, len(candidates))
-	for _, c := range candidates {
-		if c.Health == HealthUnhealthy {
-			continue
+// routeNeeds lists the provider capabilities a request depends on. It is
+// computed once per request and used to filter candidates.
+type routeNeeds struct {
+	multimodal bool
+	tools      bool
+
+	media   []PartType // distinct media part types in the messages
+	json    bool       // ResponseFormat is set
+	choices bool       // N above 1
+	stream  bool       // ChatCompletionStream
+}
+
+// chatNeeds derives the capability requirements of a chat request. A
+// conversation that already contains tool calls or tool results needs a
+// tool-capable provider even when this turn declares no tools: a text-only
+// backend cannot represent the history.
+func chatNeeds(req ChatRequest) routeNeeds {
+	n := routeNeeds{
+		multimodal: messagesHaveMedia(req.Messages),
+		tools:      len(req.Tools) > 0,
+		json:       req.ResponseFormat != nil,
+		choices:    req.N != nil && *req.N > 1,
+	}
+	for _, m := range req.Messages {
+		for _, p := range m.Parts {
+			if p.IsMedia() && !slices.Contains(n.media, p.Type) {
+				n.media = append(n.media, p.Type)
+			}
 		}
-		if !c.Free && !allowPaid {
-			continue
+	}
+	if !n.tools {
+		for _, m := range req.Messages {
+			if len(m.ToolCalls) > 0 || m.Role == "tool" {
+				n.tools = true
+				break
+			}
 		}
-		if !c.Free && c.MaxDailySpend > 0 && c.CurrentSpend >= c.MaxDailySpend {
-			continue
+	}
+	return n
+}
+
+// filterCandidates removes unhealthy candidates (by account or provider-wide
+// circuit), enforces paid/spend limits, and drops providers that lack a
+// capability the request needs (multimodal input, tool calling, several
+// completions), or whose model the catalog says lacks it.
+//
+// The checks run one after another over the whole list. When nothing is left,
+// the error names the check that removed the last candidates:
+// ErrMultimodalUnavailable or ErrToolsUnavailable for a capability,
+// ErrNoCandidates otherwise.
+func filterCandidates(candidates []Candidate, allowPaid bool, needs routeNeeds) ([]Candidate, error) {
+	filtered := slices.DeleteFunc(slices.Clone(candidates), func(c Candidate) bool {
+		return c.Health == HealthUnhealthy || c.ProviderHealth == HealthUnhealthy ||
+			(!c.Free && !allowPaid) ||
+			(!c.Free && c.MaxDailySpend > 0 && c.CurrentSpend >= c.MaxDailySpend)
+	})
+	if len(filtered) == 0 {
+		return nil, ErrNoCandidates
+	}
+	if needs.multimodal {
+		filtered = slices.DeleteFunc(filtered, func(c Candidate) bool {
+			return !supportsMultimodal(c.Provider, c.Model) || !c.Info.accepts(needs.media)
+		})
+		if len(filtered) == 0 {
+			return nil, ErrMultimodalUnavailable
+		}
+	}
+	if needs.tools {
+		filtered = slices.DeleteFunc(filtered, func(c Candidate) bool {
+			return !supportsTools(c.Provider) || isFalse(c.Info.Tools)
+		})
+		if len(filtered) == 0 {
+			return nil, ErrToolsUnavailable
 		}
-		if needMultimodal && !c.Provider.SupportsMultimodal() {
-			continue
+	}
+	if needs.choices {
+		filtered = slices.DeleteFunc(filtered, func(c Candidate) bool { return !supportsChoices(c.Provider) })
+		if len(filtered) == 0 {
+			return nil, fmt.Errorf("%w: none returns more than one completion (n)", ErrNoCandidates)
 		}
-		filtered = append(filtered, c)
 	}
-	return filtered
+	filtered = slices.DeleteFunc(filtered, func(c Candidate) bool { return !c.Info.serves(needs) })
+	if len(filtered) == 0 {
+		return nil, ErrNoCandidates
+	}
+	return filtered, nil
+}
+
+// fitCandidates drops the candidates whose model cannot take a prompt of
+// estimatedTokens plus maxTokens of output. It reports ErrContextWindowExceeded
+// when that leaves none.
+func fitCandidates(candidates []Candidate, estimatedTokens int64, maxTokens int) ([]Candidate, error) {
+	fitted := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Info.fits(estimatedTokens, maxTokens) {
+			fitted = append(fitted, c)
+		}
+	}
+	if len(fitted) == 0 && len(candidates) > 0 {
+		return nil, fmt.Errorf("%w: ~%d prompt tokens, max_tokens %d", ErrContextWindowExceeded, estimatedTokens, maxTokens)
+	}
+	return fitted, nil
 }
 
 // resolveModalityCost returns the specific per-modality rate if configured,
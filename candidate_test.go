@@ -2,6 +2,7 @@ package inferrouter
 
 import (
 	"context"
+	"errors"
 	"testing"
 )
 
@@ -12,6 +13,22 @@ type testProvider struct {
 	multimodal bool
 }
 
+// toolTestProvider adds the optional ToolProvider capability.
+type toolTestProvider struct {
+	testProvider
+	tools bool
+}
+
+func (p *toolTestProvider) SupportsTools() bool { return p.tools }
+
+// modelMediaTestProvider adds the optional MultimodalModelProvider capability.
+type modelMediaTestProvider struct {
+	testProvider
+	vision string
+}
+
+func (p *modelMediaTestProvider) SupportsMultimodalModel(model string) bool { return model == p.vision }
+
 func (p *testProvider) Name() string              { return p.name }
 func (p *testProvider) SupportsModel(string) bool { return true }
 func (p *testProvider) SupportsMultimodal() bool  { return p.multimodal }
@@ -31,7 +48,7 @@ func TestFilterCandidatesDropsUnhealthy(t *testing.T) {
 		{Provider: text, AccountID: "b", Free: true, Health: HealthUnhealthy},
 		{Provider: text, AccountID: "c", Free: true, Health: HealthHalfOpen},
 	}
-	out := filterCandidates(in, false, false)
+	out, _ := filterCandidates(in, false, routeNeeds{})
 	if len(out) != 2 {
 		t.Fatalf("len = %d, want 2 (healthy + half-open)", len(out))
 	}
@@ -42,13 +59,25 @@ func TestFilterCandidatesDropsUnhealthy(t *testing.T) {
 	}
 }
 
+func TestFilterCandidatesDropsTrippedProvider(t *testing.T) {
+	text := &testProvider{name: "text"}
+	in := []Candidate{
+		{Provider: text, AccountID: "a", Free: true, ProviderHealth: HealthUnhealthy},
+		{Provider: text, AccountID: "b", Free: true, ProviderHealth: HealthHalfOpen},
+	}
+	out, _ := filterCandidates(in, false, routeNeeds{})
+	if len(out) != 1 || out[0].AccountID != "b" {
+		t.Fatalf("got %+v, want only the half-open probe candidate b", out)
+	}
+}
+
 func TestFilterCandidatesDropsPaidWhenDisallowed(t *testing.T) {
 	text := &testProvider{name: "text"}
 	in := []Candidate{
 		{Provider: text, AccountID: "free", Free: true},
 		{Provider: text, AccountID: "paid", Free: false},
 	}
-	out := filterCandidates(in, false, false)
+	out, _ := filterCandidates(in, false, routeNeeds{})
 	if len(out) != 1 || out[0].AccountID != "free" {
 		t.Errorf("got %+v, want only free", ids(out))
 	}
@@ -60,7 +89,7 @@ func TestFilterCandidatesAllowsPaidWhenEnabled(t *testing.T) {
 		{Provider: text, AccountID: "free", Free: true},
 		{Provider: text, AccountID: "paid", Free: false},
 	}
-	out := filterCandidates(in, true, false)
+	out, _ := filterCandidates(in, true, routeNeeds{})
 	if len(out) != 2 {
 		t.Errorf("expected both, got %v", ids(out))
 	}
@@ -73,7 +102,7 @@ func TestFilterCandidatesDropsPaidOverSpendCap(t *testing.T) {
 		{Provider: text, AccountID: "paid-over", Free: false, MaxDailySpend: 10.0, CurrentSpend: 10.0},
 		{Provider: text, AccountID: "paid-unlimited", Free: false, MaxDailySpend: 0, CurrentSpend: 1e9},
 	}
-	out := filterCandidates(in, true, false)
+	out, _ := filterCandidates(in, true, routeNeeds{})
 	got := ids(out)
 	if len(got) != 2 || got[0] != "paid-under" || got[1] != "paid-unlimited" {
 		t.Errorf("got %v, want [paid-under paid-unlimited]", got)
@@ -88,7 +117,7 @@ func TestFilterCandidatesNeedMultimodal(t *testing.T) {
 		{Provider: vision, AccountID: "vision-a", Free: true},
 		{Provider: text, AccountID: "text-b", Free: true},
 	}
-	out := filterCandidates(in, false, true)
+	out, _ := filterCandidates(in, false, routeNeeds{multimodal: true})
 	if len(out) != 1 || out[0].AccountID != "vision-a" {
 		t.Errorf("got %v, want only vision-a", ids(out))
 	}
@@ -101,22 +130,105 @@ func TestFilterCandidatesNeedMultimodalEmpty(t *testing.T) {
 		{Provider: text, AccountID: "a", Free: true},
 		{Provider: text, AccountID: "b", Free: true},
 	}
-	out := filterCandidates(in, false, true)
+	out, err := filterCandidates(in, false, routeNeeds{multimodal: true})
 	if len(out) != 0 {
 		t.Errorf("got %v, want empty", ids(out))
 	}
+	if !errors.Is(err, ErrMultimodalUnavailable) {
+		t.Errorf("err = %v, want ErrMultimodalUnavailable", err)
+	}
+}
+
+func TestFilterCandidatesReportsTheCheckThatEmptiedTheList(t *testing.T) {
+	// Media leaves vision candidates; tools then removes them, so the
+	// request is short of tools, not of media.
+	vision := &toolTestProvider{testProvider: testProvider{name: "vision", multimodal: true}}
+	both := routeNeeds{multimodal: true, tools: true, media: []PartType{PartImage}}
+	_, err := filterCandidates([]Candidate{{Provider: vision, AccountID: "v", Free: true}}, false, both)
+	if !errors.Is(err, ErrToolsUnavailable) {
+		t.Errorf("err = %v, want ErrToolsUnavailable", err)
+	}
+
+	// No vision candidate at all: media empties the list first.
+	text := &toolTestProvider{testProvider: testProvider{name: "text"}, tools: true}
+	_, err = filterCandidates([]Candidate{{Provider: text, AccountID: "t", Free: true}}, false, both)
+	if !errors.Is(err, ErrMultimodalUnavailable) {
+		t.Errorf("err = %v, want ErrMultimodalUnavailable", err)
+	}
+
+	// Health removes everyone before any capability is checked.
+	unhealthy := []Candidate{{Provider: vision, AccountID: "v", Free: true, Health: HealthUnhealthy}}
+	_, err = filterCandidates(unhealthy, false, both)
+	if !errors.Is(err, ErrNoCandidates) {
+		t.Errorf("err = %v, want ErrNoCandidates", err)
+	}
+}
+
+func TestFilterCandidatesNeedMultimodalPerModel(t *testing.T) {
+	// The per-model answer wins over the provider-wide SupportsMultimodal.
+	p := &modelMediaTestProvider{testProvider: testProvider{name: "compat", multimodal: true}, vision: "vl"}
+	in := []Candidate{
+		{Provider: p, AccountID: "a", Model: "text", Free: true},
+		{Provider: p, AccountID: "b", Model: "vl", Free: true},
+	}
+	out, _ := filterCandidates(in, false, routeNeeds{multimodal: true})
+	if len(out) != 1 || out[0].Model != "vl" {
+		t.Errorf("got %v, want only the vl model", ids(out))
+	}
 }
 
 func TestFilterCandidatesTextOnlyRequestPassesTextProviders(t *testing.T) {
 	// needMultimodal=false must NOT drop text-only providers.
 	text := &testProvider{name: "text", multimodal: false}
 	in := []Candidate{{Provider: text, AccountID: "a", Free: true}}
-	out := filterCandidates(in, false, false)
+	out, _ := filterCandidates(in, false, routeNeeds{})
 	if len(out) != 1 {
 		t.Errorf("text provider dropped for text-only request")
 	}
 }
 
+func TestFilterCandidatesNeedTools(t *testing.T) {
+	text := &testProvider{name: "text"}
+	declined := &toolTestProvider{testProvider: testProvider{name: "declined"}, tools: false}
+	capable := &toolTestProvider{testProvider: testProvider{name: "capable"}, tools: true}
+	in := []Candidate{
+		{Provider: text, AccountID: "text", Free: true},
+		{Provider: declined, AccountID: "declined", Free: true},
+		{Provider: capable, AccountID: "capable", Free: true},
+	}
+	out, _ := filterCandidates(in, false, routeNeeds{tools: true})
+	if len(out) != 1 || out[0].AccountID != "capable" {
+		t.Errorf("got %v, want only capable", ids(out))
+	}
+
+	// Without tools every provider stays.
+	if out, _ := filterCandidates(in, false, routeNeeds{}); len(out) != 3 {
+		t.Errorf("got %v, want all three", ids(out))
+	}
+}
+
+func TestChatNeedsTools(t *testing.T) {
+	cases := []struct {
+		name string
+		req  ChatRequest
+		want bool
+	}{
+		{"plain", ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}}, false},
+		{"declared tools", ChatRequest{Tools: []Tool{{Type: "function", Function: ToolFunction{Name: "f"}}}}, true},
+		{"history with call", ChatRequest{Messages: []Message{
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "1", Function: FunctionCall{Name: "f"}}}},
+		}}, true},
+		{"history with result", ChatRequest{Messages: []Message{{Role: "tool", ToolCallID: "1", Content: "{}"}}}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := chatNeeds(tc.req).tools; got != tc.want {
+				t.Errorf("chatNeeds().tools = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
 func ids(cs []Candidate) []string {
 	out := make([]string, len(cs))
 	for i, c := range cs {
@@ -156,8 +268,9 @@ func TestBuildCandidatesResolvesModalityCostFallback(t *testing.T) {
 		provs,
 		&noopQuotaStore{},
 		NewHealthTracker(),
-		NewSpendTracker(),
+		NewSpendTracker().Store(),
 		NewInflightTracker(),
+		NewLatencyTracker(),
 		"gemini-2.5-flash-lite",
 	)
 	if err != nil {
@@ -205,8 +318,9 @@ func TestBuildCandidatesExplicitModalityRatesWin(t *testing.T) {
 		provs,
 		&noopQuotaStore{},
 		NewHealthTracker(),
-		NewSpendTracker(),
+		NewSpendTracker().Store(),
 		NewInflightTracker(),
+		NewLatencyTracker(),
 		"gemini-2.5-flash-lite",
 	)
 	if err != nil {
@@ -0,0 +1,214 @@
+// Command inferrouter-gateway serves a Router over an OpenAI-compatible HTTP
+// API, so services in any language can route through one config:
+//
+//	inferrouter-gateway -config inferrouter.yaml -addr :8080
+//
+// Endpoints: POST /v1/chat/completions (sync and SSE), POST /v1/embeddings,
+// GET /v1/models. Every response carries the routing decision in
+// X-Inferrouter-* headers; streams repeat them as trailers, which reflect the
+// final step if the stream failed over.
+//
+// Providers are built from the config: accounts with a base_url become
+// openaicompat providers (one per provider name), and accounts of provider
+// "gemini" or "anthropic" without one use the native adapter. Accounts of
+// provider "ollama" always use the native Ollama adapter, with base_url as
+// the server address.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/policy"
+	"github.com/ineyio/inferrouter/provider/anthropic"
+	"github.com/ineyio/inferrouter/provider/gemini"
+	"github.com/ineyio/inferrouter/provider/ollama"
+	"github.com/ineyio/inferrouter/provider/openaicompat"
+)
+
+func main() {
+	configPath := flag.String("config", "inferrouter.yaml", "path to the router config")
+	addr := flag.String("addr", ":8080", "listen address")
+	policyName := flag.String("policy", "", "routing policy: free-first, cost-first, least-busy or latency (default: config order)")
+	upstreamTimeout := flag.Duration("upstream-timeout", 120*time.Second, "HTTP client timeout for upstream providers")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := run(*configPath, *addr, *policyName, *upstreamTimeout, logger); err != nil {
+		logger.Error("gateway stopped", "err", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath, addr, policyName string, upstreamTimeout time.Duration, logger *slog.Logger) error {
+	cfg, err := ir.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	providers, err := buildProviders(cfg.Accounts, &http.Client{Timeout: upstreamTimeout})
+	if err != nil {
+		return err
+	}
+
+	var opts []ir.Option
+	if policyName != "" {
+		p, err := parsePolicy(policyName)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, ir.WithPolicy(p))
+	}
+
+	router, err := ir.NewRouter(cfg, providers, opts...)
+	if err != nil {
+		return err
+	}
+	for _, w := range router.ConfigWarnings() {
+		logger.Warn("config", "warning", w)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newServer(router, cfg, os.Getenv("INFERROUTER_GATEWAY_API_KEY")),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		logger.Info("listening", "addr", addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// buildProviders constructs one provider per provider name in accounts.
+func buildProviders(accounts []ir.AccountConfig, client *http.Client) ([]ir.Provider, error) {
+	// Ollama accounts bypass openaicompat even with a base_url: there it is
+	// the address of the native API, not of an OpenAI-compatible endpoint.
+	var compat, local []ir.AccountConfig
+	for _, acc := range accounts {
+		if acc.Provider == "ollama" {
+			local = append(local, acc)
+		} else {
+			compat = append(compat, acc)
+		}
+	}
+
+	providers, err := openaicompat.FromAccounts(compat, openaicompat.WithHTTPClient(client))
+	if err != nil {
+		return nil, err
+	}
+	if len(local) > 0 {
+		p, err := buildOllama(local, client)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+
+	built := make(map[string]bool, len(providers))
+	for _, p := range providers {
+		built[p.Name()] = true
+	}
+	for _, acc := range compat {
+		if built[acc.Provider] {
+			continue
+		}
+		switch acc.Provider {
+		case "gemini":
+			providers = append(providers, gemini.New(gemini.WithHTTPClient(client)))
+			built[acc.Provider] = true
+		case "anthropic":
+			providers = append(providers, anthropic.New(anthropic.WithHTTPClient(client)))
+			built[acc.Provider] = true
+		default:
+			return nil, fmt.Errorf("account %q: provider %q has no base_url and no built-in adapter", acc.ID, acc.Provider)
+		}
+	}
+	return providers, nil
+}
+
+// buildOllama builds the native Ollama provider for the "ollama" accounts.
+// base_url is the server address (default http://localhost:11434); a
+// trailing /v1, the address of Ollama's OpenAI shim, is dropped so configs
+// written for openaicompat keep working. model_info marks multimodal and
+// embedding models as it does for openaicompat.FromAccounts.
+func buildOllama(accounts []ir.AccountConfig, client *http.Client) (ir.Provider, error) {
+	var baseURL string
+	var media, embed []string
+	for _, acc := range accounts {
+		if url := strings.TrimSuffix(strings.TrimRight(acc.BaseURL, "/"), "/v1"); url != "" {
+			if baseURL != "" && baseURL != url {
+				return nil, fmt.Errorf("account %q: provider %q has conflicting base URLs: %q and %q", acc.ID, acc.Provider, baseURL, url)
+			}
+			baseURL = url
+		}
+		for model, info := range acc.ModelInfo {
+			hasMedia := slices.ContainsFunc(info.Input, func(t ir.PartType) bool { return t != ir.PartText })
+			if hasMedia && !slices.Contains(media, model) {
+				media = append(media, model)
+			}
+			if info.Embedding && !slices.Contains(embed, model) {
+				embed = append(embed, model)
+			}
+		}
+	}
+
+	opts := []ollama.Option{ollama.WithHTTPClient(client)}
+	if baseURL != "" {
+		opts = append(opts, ollama.WithBaseURL(baseURL))
+	}
+	if len(media) > 0 {
+		slices.Sort(media)
+		opts = append(opts, ollama.WithMultimodalModels(media...))
+	}
+	if len(embed) > 0 {
+		slices.Sort(embed)
+		opts = append(opts, ollama.WithEmbeddingModels(embed...))
+	}
+	return ollama.New(opts...), nil
+}
+
+func parsePolicy(name string) (ir.Policy, error) {
+	switch name {
+	case "free-first":
+		return &policy.FreeFirstPolicy{}, nil
+	case "cost-first":
+		return &policy.CostFirstPolicy{}, nil
+	case "least-busy":
+		return &policy.LeastBusyPolicy{}, nil
+	case "latency":
+		// TTFT falls back to full latency by itself while some account has
+		// not streamed, so it suits mixed sync and stream traffic.
+		return &policy.LatencyPolicy{Exploration: 0.05, UseTTFT: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown policy %q", name)
+	}
+}
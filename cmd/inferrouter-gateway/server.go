@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ir "github.com/ineyio/inferrouter"
+)
+
+// maxBodyBytes bounds a request body; inline images make chat requests large.
+const maxBodyBytes = 32 << 20
+
+// Routing headers. Set on every routed response, and as trailers on streams.
+const (
+	headerProvider = "X-Inferrouter-Provider"
+	headerAccount  = "X-Inferrouter-Account"
+	headerModel    = "X-Inferrouter-Model"
+	headerAttempts = "X-Inferrouter-Attempts"
+	headerFree     = "X-Inferrouter-Free"
+)
+
+// server exposes a Router over the OpenAI HTTP API.
+type server struct {
+	router *ir.Router
+	models []string
+	apiKey string // empty: no client authentication
+}
+
+// newServer returns the gateway's HTTP handler. /v1/models lists the aliases
+// declared in cfg; apiKey, when set, is required as a bearer token.
+func newServer(router *ir.Router, cfg ir.Config, apiKey string) http.Handler {
+	s := &server{router: router, apiKey: apiKey}
+	seen := make(map[string]bool)
+	for _, m := range cfg.Models {
+		if !seen[m.Alias] {
+			seen[m.Alias] = true
+			s.models = append(s.models, m.Alias)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	mux.HandleFunc("POST /v1/embeddings", s.handleEmbeddings)
+	mux.HandleFunc("GET /v1/models", s.handleModels)
+	return s.authenticate(mux)
+}
+
+func (s *server) authenticate(next http.Handler) http.Handler {
+	if s.apiKey == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.apiKey)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", "missing or invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var wire chatCompletionRequest
+	if !decodeBody(w, r, &wire) {
+		return
+	}
+	req, err := wire.toChatRequest()
+	if err != nil {
+		writeRouterError(w, err)
+		return
+	}
+
+	if req.Stream {
+		s.streamChat(r.Context(), w, req)
+		return
+	}
+
+	resp, err := s.router.ChatCompletion(r.Context(), req)
+	if err != nil {
+		writeRouterError(w, err)
+		return
+	}
+
+	out := chatCompletionResponse{
+		ID:      resp.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   resp.Model,
+		Choices: make([]wireChoice, len(resp.Choices)),
+		Usage:   fromUsage(resp.Usage),
+	}
+	for i, c := range resp.Choices {
+		msg := responseMessage{Role: "assistant", Content: &c.Message.Content, ToolCalls: c.Message.ToolCalls}
+		if c.Message.Content == "" && len(c.Message.ToolCalls) > 0 {
+			msg.Content = nil
+		}
+		out.Choices[i] = wireChoice{Index: c.Index, Message: msg, FinishReason: c.FinishReason}
+	}
+
+	setRouting(w.Header(), resp.Routing)
+	writeJSON(w, http.StatusOK, out)
+}
+
+// streamChat relays a RouterStream as server-sent events. Errors before the
+// first byte get a normal error response; after that the status is already
+// sent, so the error goes out as a final data event.
+func (s *server) streamChat(ctx context.Context, w http.ResponseWriter, req ir.ChatRequest) {
+	stream, err := s.router.ChatCompletionStream(ctx, req)
+	if err != nil {
+		writeRouterError(w, err)
+		return
+	}
+	defer stream.Close()
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	setRouting(h, stream.Routing())
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	created := time.Now().Unix()
+	for {
+		chunk, err := stream.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			_, _, code, kind := classify(err)
+			writeEvent(w, map[string]any{"error": errorBody{Message: err.Error(), Type: kind, Code: code}})
+			break
+		}
+
+		out := chatCompletionChunk{
+			ID:      chunk.ID,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   chunk.Model,
+			Choices: chunk.Choices,
+		}
+		if out.Choices == nil {
+			out.Choices = []ir.StreamDelta{}
+		}
+		if chunk.Usage != nil {
+			out.Usage = fromUsage(*chunk.Usage)
+		}
+		writeEvent(w, out)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+
+	// The stream may have failed over; the trailers name who finished it.
+	setRoutingTrailers(h, stream.Routing())
+}
+
+func (s *server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var wire embeddingsRequest
+	if !decodeBody(w, r, &wire) {
+		return
+	}
+	inputs, err := stringOrList(wire.Input)
+	if err != nil || len(inputs) == 0 {
+		writeRouterError(w, badRequest("input: expected a non-empty string or array of strings"))
+		return
+	}
+	if wire.EncodingFormat != "" && wire.EncodingFormat != "float" && wire.EncodingFormat != "base64" {
+		writeRouterError(w, badRequest("encoding_format: unsupported value %q", wire.EncodingFormat))
+		return
+	}
+
+	resp, err := s.router.EmbedBatch(r.Context(), ir.EmbedRequest{
+		Model:                wire.Model,
+		Inputs:               inputs,
+		TaskType:             wire.TaskType,
+		OutputDimensionality: wire.Dimensions,
+	})
+	if err != nil {
+		writeRouterError(w, err)
+		return
+	}
+
+	out := embeddingsResponse{
+		Object: "list",
+		Data:   make([]wireEmbedding, len(resp.Embeddings)),
+		Model:  resp.Model,
+		Usage:  embeddingsUsage{PromptTokens: resp.Usage.InputTokens, TotalTokens: resp.Usage.TotalTokens},
+	}
+	for i, vec := range resp.Embeddings {
+		var embedding any = vec
+		if wire.EncodingFormat == "base64" {
+			embedding = encodeEmbedding(vec)
+		}
+		out.Data[i] = wireEmbedding{Object: "embedding", Index: i, Embedding: embedding}
+	}
+
+	setRouting(w.Header(), resp.Routing)
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *server) handleModels(w http.ResponseWriter, _ *http.Request) {
+	out := modelList{Object: "list", Data: make([]wireModel, len(s.models))}
+	for i, alias := range s.models {
+		out.Data[i] = wireModel{ID: alias, Object: "model", OwnedBy: "inferrouter"}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", "request_too_large", err.Error())
+			return false
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid_json", "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func setRouting(h http.Header, info ir.RoutingInfo) {
+	if info.Provider == "" {
+		return
+	}
+	h.Set(headerProvider, info.Provider)
+	h.Set(headerAccount, info.AccountID)
+	h.Set(headerModel, info.Model)
+	h.Set(headerAttempts, strconv.Itoa(info.Attempts))
+	h.Set(headerFree, strconv.FormatBool(info.Free))
+}
+
+// setRoutingTrailers sends the routing headers again as HTTP trailers.
+func setRoutingTrailers(h http.Header, info ir.RoutingInfo) {
+	trailers := make(http.Header)
+	setRouting(trailers, info)
+	for k, v := range trailers {
+		h[http.TrailerPrefix+k] = v
+	}
+}
+
+type errorBody struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+// writeRouterError maps a router error to an HTTP status and OpenAI error body.
+func writeRouterError(w http.ResponseWriter, err error) {
+	status, attempts, code, kind := classify(err)
+	if attempts > 0 {
+		w.Header().Set(headerAttempts, strconv.Itoa(attempts))
+	}
+	if status == http.StatusTooManyRequests {
+		if d := retryAfter(err); d > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+		}
+	}
+	writeError(w, status, kind, code, err.Error())
+}
+
+// retryAfter is the soonest any rate-limited candidate said it would take
+// requests again, or zero when none said.
+func retryAfter(err error) time.Duration {
+	var rerr *ir.RouterError
+	if !errors.As(err, &rerr) || len(rerr.Tried) == 0 {
+		var rle *ir.RateLimitError
+		if errors.As(err, &rle) {
+			return rle.RetryAfter
+		}
+		return 0
+	}
+	var soonest time.Duration
+	for _, t := range rerr.Tried {
+		var rle *ir.RateLimitError
+		if errors.As(t.Err, &rle) && rle.RetryAfter > 0 && (soonest == 0 || rle.RetryAfter < soonest) {
+			soonest = rle.RetryAfter
+		}
+	}
+	return soonest
+}
+
+// classify returns the HTTP status, attempts made, and OpenAI error code and
+// type for err.
+func classify(err error) (status, attempts int, code, kind string) {
+	var rerr *ir.RouterError
+	if errors.As(err, &rerr) {
+		attempts = rerr.Attempts
+	}
+
+	switch {
+	case errors.Is(err, errBadRequest), errors.Is(err, ir.ErrInvalidRequest), errors.Is(err, ir.ErrBatchTooLarge):
+		return http.StatusBadRequest, attempts, "invalid_request", "invalid_request_error"
+	case errors.Is(err, ir.ErrContextWindowExceeded):
+		return http.StatusBadRequest, attempts, "context_length_exceeded", "invalid_request_error"
+	case errors.Is(err, ir.ErrUnknownAlias), errors.Is(err, ir.ErrModelNotFound), errors.Is(err, ir.ErrNoEmbeddingProviders):
+		return http.StatusNotFound, attempts, "model_not_found", "invalid_request_error"
+	case errors.Is(err, ir.ErrMultimodalUnavailable), errors.Is(err, ir.ErrToolsUnavailable):
+		return http.StatusUnprocessableEntity, attempts, "capability_unavailable", "invalid_request_error"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, attempts, "timeout", "api_error"
+	case errors.Is(err, context.Canceled):
+		// The client is gone; the status is for logs only.
+		return 499, attempts, "canceled", "api_error"
+	case isRateLimit(err):
+		return http.StatusTooManyRequests, attempts, "rate_limit_exceeded", "rate_limit_error"
+	case errors.Is(err, ir.ErrAuthFailed):
+		// Upstream credentials are the gateway's problem, not the client's.
+		return http.StatusBadGateway, attempts, "upstream_auth_failed", "api_error"
+	case errors.Is(err, ir.ErrNoCandidates), errors.Is(err, ir.ErrProviderUnavailable):
+		return http.StatusServiceUnavailable, attempts, "no_capacity", "api_error"
+	case errors.Is(err, ir.ErrAllFailed):
+		if rerr != nil && allRateLimited(rerr.Tried) {
+			return http.StatusTooManyRequests, attempts, "rate_limit_exceeded", "rate_limit_error"
+		}
+		if rerr != nil && allContextLength(rerr.Tried) {
+			return http.StatusBadRequest, attempts, "context_length_exceeded", "invalid_request_error"
+		}
+		return http.StatusBadGateway, attempts, "upstream_failed", "api_error"
+	default:
+		return http.StatusInternalServerError, attempts, "internal_error", "api_error"
+	}
+}
+
+func isRateLimit(err error) bool {
+	return errors.Is(err, ir.ErrRateLimited) ||
+		errors.Is(err, ir.ErrRPMExceeded) ||
+		errors.Is(err, ir.ErrTPMExceeded) ||
+		errors.Is(err, ir.ErrConcurrencyExceeded) ||
+		errors.Is(err, ir.ErrQuotaExceeded) ||
+		errors.Is(err, ir.ErrNoFreeQuota)
+}
+
+// allRateLimited reports whether every candidate was turned away by a limit,
+// so the client should back off rather than treat the gateway as broken.
+func allRateLimited(tried []ir.CandidateError) bool {
+	if len(tried) == 0 {
+		return false
+	}
+	for _, t := range tried {
+		if !isRateLimit(t.Err) {
+			return false
+		}
+	}
+	return true
+}
+
+// allContextLength reports whether every step rejected the request as too
+// long: no model on the ladder could take it, which is the client's problem.
+func allContextLength(tried []ir.CandidateError) bool {
+	if len(tried) == 0 {
+		return false
+	}
+	for _, t := range tried {
+		if !errors.Is(t.Err, ir.ErrContextLengthExceeded) {
+			return false
+		}
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, status int, kind, code, msg string) {
+	writeJSON(w, status, map[string]errorBody{"error": {Message: msg, Type: kind, Code: code}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeEvent(w io.Writer, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/policy"
+	"github.com/ineyio/inferrouter/provider/mock"
+	"github.com/ineyio/inferrouter/provider/ollama"
+	"github.com/ineyio/inferrouter/quota"
+)
+
+// embedStub makes a mock.EmbedProvider usable as a router provider.
+type embedStub struct{ *mock.EmbedProvider }
+
+func (embedStub) SupportsModel(string) bool { return false }
+func (embedStub) SupportsMultimodal() bool  { return false }
+func (embedStub) ChatCompletion(context.Context, ir.ProviderRequest) (ir.ProviderResponse, error) {
+	return ir.ProviderResponse{}, errors.New("embed only")
+}
+func (embedStub) ChatCompletionStream(context.Context, ir.ProviderRequest) (ir.ProviderStream, error) {
+	return nil, errors.New("embed only")
+}
+
+func testConfig() ir.Config {
+	return ir.Config{
+		AllowPaid: true,
+		Models: []ir.ModelMapping{
+			{Alias: "chat", Models: []ir.ModelRef{{Provider: "chat-mock", Model: "mock-model"}}},
+			{Alias: "embed", Models: []ir.ModelRef{{Provider: "embed-mock", Model: "mock-embed"}}},
+		},
+		Accounts: []ir.AccountConfig{
+			{Provider: "chat-mock", ID: "chat-acc", DailyFree: 1000, QuotaUnit: ir.QuotaRequests},
+			{Provider: "embed-mock", ID: "embed-acc", DailyFree: 1000, QuotaUnit: ir.QuotaRequests},
+		},
+	}
+}
+
+func newTestGateway(t *testing.T, chat *mock.Provider, apiKey string) *httptest.Server {
+	t.Helper()
+	embed := embedStub{mock.NewEmbed(
+		mock.WithEmbedName("embed-mock"),
+		mock.WithEmbedSupportedModels("mock-embed"),
+		mock.WithEmbedDimensions(4),
+	)}
+	cfg := testConfig()
+	router, err := ir.NewRouter(cfg, []ir.Provider{chat, embed},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(newServer(router, cfg, apiKey))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func chatMock(opts ...mock.Option) *mock.Provider {
+	return mock.New(append([]mock.Option{mock.WithName("chat-mock"), mock.WithModels("mock-model")}, opts...)...)
+}
+
+func post(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestChatCompletions(t *testing.T) {
+	srv := newTestGateway(t, chatMock(mock.WithResponseFunc(func(req ir.ProviderRequest) (ir.ProviderResponse, error) {
+		if len(req.Messages) != 1 || req.Messages[0].Content != "hi" || req.MaxTokens == nil || *req.MaxTokens != 16 {
+			return ir.ProviderResponse{}, fmt.Errorf("%w: unexpected request %+v", ir.ErrInvalidRequest, req)
+		}
+		return ir.ProviderResponse{
+			ID: "resp-1", Content: "hello", Model: "mock-model", FinishReason: "stop",
+			Usage: ir.Usage{PromptTokens: 2, CompletionTokens: 3, TotalTokens: 5},
+		}, nil
+	})), "")
+
+	resp := post(t, srv.URL+"/v1/chat/completions",
+		`{"model":"chat","messages":[{"role":"user","content":"hi"}],"max_completion_tokens":16}`)
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status = %d: %s", resp.StatusCode, b)
+	}
+
+	var out chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Object != "chat.completion" || len(out.Choices) != 1 ||
+		out.Choices[0].Message.Content == nil || *out.Choices[0].Message.Content != "hello" ||
+		out.Choices[0].FinishReason != "stop" {
+		t.Errorf("response = %+v", out)
+	}
+	if out.Usage == nil || out.Usage.TotalTokens != 5 {
+		t.Errorf("usage = %+v", out.Usage)
+	}
+
+	for header, want := range map[string]string{
+		headerProvider: "chat-mock",
+		headerAccount:  "chat-acc",
+		headerModel:    "mock-model",
+		headerAttempts: "1",
+		headerFree:     "true",
+	} {
+		if got := resp.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestChatCompletionsN(t *testing.T) {
+	var gotN *int
+	srv := newTestGateway(t, chatMock(mock.WithChoices(true), mock.WithResponseFunc(func(req ir.ProviderRequest) (ir.ProviderResponse, error) {
+		gotN = req.N
+		return ir.ProviderResponse{
+			ID: "resp-1", Content: "a", Model: "mock-model", FinishReason: "stop",
+			Choices: []ir.Choice{
+				{Index: 0, Message: ir.Message{Role: "assistant", Content: "a"}, FinishReason: "stop"},
+				{Index: 1, Message: ir.Message{Role: "assistant", Content: "b"}, FinishReason: "length"},
+			},
+		}, nil
+	})), "")
+
+	resp := post(t, srv.URL+"/v1/chat/completions",
+		`{"model":"chat","n":2,"messages":[{"role":"user","content":"hi"}]}`)
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status = %d: %s", resp.StatusCode, b)
+	}
+	var out chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if gotN == nil || *gotN != 2 {
+		t.Errorf("provider n = %v, want 2", gotN)
+	}
+	if len(out.Choices) != 2 {
+		t.Fatalf("choices = %+v, want 2", out.Choices)
+	}
+	for i, want := range []string{"a", "b"} {
+		c := out.Choices[i]
+		if c.Index != i || c.Message.Content == nil || *c.Message.Content != want {
+			t.Errorf("choice %d = %+v, want index %d content %q", i, c, i, want)
+		}
+	}
+	if out.Choices[1].FinishReason != "length" {
+		t.Errorf("choice 1 finish_reason = %q", out.Choices[1].FinishReason)
+	}
+
+	for _, body := range []string{
+		`{"model":"chat","n":2,"stream":true,"messages":[{"role":"user","content":"hi"}]}`,
+		`{"model":"chat","n":0,"messages":[{"role":"user","content":"hi"}]}`,
+	} {
+		resp := post(t, srv.URL+"/v1/chat/completions", body)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestChatCompletionsStream(t *testing.T) {
+	srv := newTestGateway(t, chatMock(mock.WithResponseFunc(func(ir.ProviderRequest) (ir.ProviderResponse, error) {
+		return ir.ProviderResponse{
+			ID: "resp-1", Content: "streamed", Model: "mock-model", FinishReason: "stop",
+			Usage: ir.Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2},
+		}, nil
+	})), "")
+
+	resp := post(t, srv.URL+"/v1/chat/completions",
+		`{"model":"chat","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("content type = %q", ct)
+	}
+	if got := resp.Header.Get(headerProvider); got != "chat-mock" {
+		t.Errorf("provider header = %q", got)
+	}
+
+	var text strings.Builder
+	var done bool
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("chunk %q: %v", data, err)
+		}
+		if chunk.Object != "chat.completion.chunk" {
+			t.Errorf("object = %q", chunk.Object)
+		}
+		for _, c := range chunk.Choices {
+			text.WriteString(c.Delta.Content)
+		}
+	}
+	if !done {
+		t.Error("stream did not end with [DONE]")
+	}
+	if text.String() != "streamed" {
+		t.Errorf("text = %q", text.String())
+	}
+	if got := resp.Trailer.Get(headerProvider); got != "chat-mock" {
+		t.Errorf("provider trailer = %q", got)
+	}
+}
+
+func TestEmbeddings(t *testing.T) {
+	srv := newTestGateway(t, chatMock(), "")
+
+	resp := post(t, srv.URL+"/v1/embeddings", `{"model":"embed","input":["a","b"]}`)
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status = %d: %s", resp.StatusCode, b)
+	}
+	var out struct {
+		Object string `json:"object"`
+		Data   []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Object != "list" || len(out.Data) != 2 || out.Data[1].Index != 1 || len(out.Data[0].Embedding) != 4 {
+		t.Errorf("response = %+v", out)
+	}
+	if got := resp.Header.Get(headerProvider); got != "embed-mock" {
+		t.Errorf("provider header = %q", got)
+	}
+
+	resp = post(t, srv.URL+"/v1/embeddings", `{"model":"embed","input":"a","encoding_format":"base64"}`)
+	var b64 struct {
+		Data []struct {
+			Embedding string `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&b64); err != nil {
+		t.Fatal(err)
+	}
+	if len(b64.Data) != 1 || len(b64.Data[0].Embedding) != 24 { // 4 float32 = 16 bytes = 24 base64 chars
+		t.Errorf("base64 response = %+v", b64)
+	}
+}
+
+func TestModels(t *testing.T) {
+	srv := newTestGateway(t, chatMock(), "")
+
+	resp, err := http.Get(srv.URL + "/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out modelList
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Data) != 2 || out.Data[0].ID != "chat" || out.Data[1].ID != "embed" {
+		t.Errorf("models = %+v", out)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider *mock.Provider
+		body     string
+		want     int
+		wantCode string
+	}{
+		{"invalid json", chatMock(), `{`, http.StatusBadRequest, "invalid_json"},
+		{"unknown alias", chatMock(), `{"model":"nope","messages":[{"role":"user","content":"hi"}]}`,
+			http.StatusNotFound, "model_not_found"},
+		{"remote image", chatMock(), `{"model":"chat","messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://x/y.png"}}]}]}`,
+			http.StatusBadRequest, "invalid_request"},
+		{"rate limited", chatMock(mock.WithError(ir.ErrRateLimited)),
+			`{"model":"chat","messages":[{"role":"user","content":"hi"}]}`, http.StatusTooManyRequests, "rate_limit_exceeded"},
+		{"upstream down", chatMock(mock.WithError(ir.ErrProviderUnavailable)),
+			`{"model":"chat","messages":[{"role":"user","content":"hi"}]}`, http.StatusBadGateway, "upstream_failed"},
+		{"upstream auth", chatMock(mock.WithError(ir.ErrAuthFailed)),
+			`{"model":"chat","messages":[{"role":"user","content":"hi"}]}`, http.StatusBadGateway, "upstream_auth_failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestGateway(t, tt.provider, "")
+			resp := post(t, srv.URL+"/v1/chat/completions", tt.body)
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			var out map[string]errorBody
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Fatal(err)
+			}
+			if out["error"].Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", out["error"].Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRateLimitedRetryAfter(t *testing.T) {
+	srv := newTestGateway(t, chatMock(mock.WithError(&ir.RateLimitError{RetryAfter: 2500 * time.Millisecond})), "")
+	resp := post(t, srv.URL+"/v1/chat/completions", `{"model":"chat","messages":[{"role":"user","content":"hi"}]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "3" {
+		t.Errorf("Retry-After = %q, want 3 (rounded up)", got)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	srv := newTestGateway(t, chatMock(), "secret")
+
+	resp, err := http.Get(srv.URL + "/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no key: status = %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("with key: status = %d", resp.StatusCode)
+	}
+}
+
+func TestToChatRequestContentParts(t *testing.T) {
+	var wire chatCompletionRequest
+	err := json.Unmarshal([]byte(`{
+		"model": "chat",
+		"stop": "END",
+		"seed": 7,
+		"presence_penalty": 0.5,
+		"tool_choice": {"type": "function", "function": {"name": "lookup"}},
+		"response_format": {"type": "json_schema", "json_schema": {"name": "out", "schema": {"type": "object"}, "strict": true}},
+		"messages": [{"role": "user", "content": [
+			{"type": "text", "text": "what is this?"},
+			{"type": "image_url", "image_url": {"url": "data:image/png;base64,AQID"}}
+		]}]
+	}`), &wire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := wire.toChatRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := req.Messages[0].Parts
+	if len(parts) != 2 || parts[0].Text != "what is this?" || parts[1].Type != ir.PartImage ||
+		parts[1].MIMEType != "image/png" || string(parts[1].Data) != "\x01\x02\x03" {
+		t.Errorf("parts = %+v", parts)
+	}
+	if len(req.Stop) != 1 || req.Stop[0] != "END" {
+		t.Errorf("stop = %v", req.Stop)
+	}
+	if req.Seed == nil || *req.Seed != 7 || req.PresencePenalty == nil || *req.PresencePenalty != 0.5 || req.FrequencyPenalty != nil {
+		t.Errorf("seed = %v, penalties = %v, %v", req.Seed, req.PresencePenalty, req.FrequencyPenalty)
+	}
+	if req.ToolChoice == nil || req.ToolChoice.Function != "lookup" {
+		t.Errorf("tool choice = %+v", req.ToolChoice)
+	}
+	rf := req.ResponseFormat
+	if rf == nil || rf.Type != ir.ResponseFormatJSONSchema || rf.Name != "out" || !rf.Strict || string(rf.Schema) != `{"type": "object"}` {
+		t.Errorf("response format = %+v", rf)
+	}
+}
+
+func TestBuildProviders(t *testing.T) {
+	providers, err := buildProviders([]ir.AccountConfig{
+		{Provider: "pool", ID: "a", BaseURL: "https://a.example/v1"},
+		{Provider: "pool", ID: "b", BaseURL: "https://a.example/v1"},
+		{Provider: "gemini", ID: "g1"},
+		{Provider: "gemini", ID: "g2"},
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 2 || providers[0].Name() != "pool" || providers[1].Name() != "gemini" {
+		t.Errorf("providers = %v", providers)
+	}
+
+	if _, err := buildProviders([]ir.AccountConfig{{Provider: "custom", ID: "c"}}, http.DefaultClient); err == nil {
+		t.Error("expected an error for a provider without base_url or adapter")
+	}
+}
+
+func TestBuildProvidersOllama(t *testing.T) {
+	providers, err := buildProviders([]ir.AccountConfig{
+		{Provider: "ollama", ID: "o1", BaseURL: "http://gpu-box:11434/v1", ModelInfo: map[string]ir.ModelInfo{
+			"llava":            {Input: []ir.PartType{ir.PartText, ir.PartImage}},
+			"nomic-embed-text": {Embedding: true},
+		}},
+		{Provider: "ollama", ID: "o2", BaseURL: "http://gpu-box:11434"},
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 1 {
+		t.Fatalf("providers = %v, want one native ollama provider", providers)
+	}
+	p, ok := providers[0].(*ollama.Provider)
+	if !ok {
+		t.Fatalf("provider type = %T, want *ollama.Provider", providers[0])
+	}
+	if !p.SupportsMultimodalModel("llava") || p.SupportsMultimodalModel("nomic-embed-text") {
+		t.Error("llava should be the only multimodal model")
+	}
+	if !p.SupportsEmbeddingModel("nomic-embed-text") || p.SupportsEmbeddingModel("llava") {
+		t.Error("nomic-embed-text should be the only embedding model")
+	}
+
+	if _, err := buildProviders([]ir.AccountConfig{
+		{Provider: "ollama", ID: "o1", BaseURL: "http://a:11434"},
+		{Provider: "ollama", ID: "o2", BaseURL: "http://b:11434"},
+	}, http.DefaultClient); err == nil {
+		t.Error("expected an error for conflicting ollama base URLs")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, name := range []string{"free-first", "cost-first", "least-busy", "latency"} {
+		if _, err := parsePolicy(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	p, _ := parsePolicy("latency")
+	if _, ok := p.(*policy.LatencyPolicy); !ok {
+		t.Errorf("latency policy type = %T", p)
+	}
+	if _, err := parsePolicy("fastest"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	ir "github.com/ineyio/inferrouter"
+)
+
+// OpenAI wire format. Only what the router can act on is decoded; unknown
+// fields are ignored, as the OpenAI API itself does for most clients.
+
+type chatCompletionRequest struct {
+	Model               string          `json:"model"`
+	Messages            []wireMessage   `json:"messages"`
+	Temperature         *float64        `json:"temperature,omitempty"`
+	MaxTokens           *int            `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int            `json:"max_completion_tokens,omitempty"`
+	TopP                *float64        `json:"top_p,omitempty"`
+	N                   *int            `json:"n,omitempty"`
+	Stop                json.RawMessage `json:"stop,omitempty"` // string or []string
+	Stream              bool            `json:"stream,omitempty"`
+	Tools               []ir.Tool       `json:"tools,omitempty"`
+	ToolChoice          json.RawMessage `json:"tool_choice,omitempty"` // mode string or {"type":"function",...}
+	ResponseFormat      *wireRespFormat `json:"response_format,omitempty"`
+	Seed                *int            `json:"seed,omitempty"`
+	PresencePenalty     *float64        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float64        `json:"frequency_penalty,omitempty"`
+}
+
+type wireMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content,omitempty"` // string, []contentPart or null
+	Name       string          `json:"name,omitempty"`
+	ToolCalls  []ir.ToolCall   `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+type contentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+	InputAudio *struct {
+		Data   string `json:"data"`
+		Format string `json:"format"`
+	} `json:"input_audio,omitempty"`
+}
+
+type wireRespFormat struct {
+	Type       string `json:"type"`
+	JSONSchema *struct {
+		Name   string          `json:"name"`
+		Schema json.RawMessage `json:"schema"`
+		Strict bool            `json:"strict"`
+	} `json:"json_schema,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []wireChoice `json:"choices"`
+	Usage   *wireUsage   `json:"usage,omitempty"`
+}
+
+type wireChoice struct {
+	Index        int             `json:"index"`
+	Message      responseMessage `json:"message"`
+	FinishReason string          `json:"finish_reason"`
+}
+
+type responseMessage struct {
+	Role      string        `json:"role"`
+	Content   *string       `json:"content"`
+	ToolCalls []ir.ToolCall `json:"tool_calls,omitempty"`
+}
+
+type chatCompletionChunk struct {
+	ID      string           `json:"id"`
+	Object  string           `json:"object"`
+	Created int64            `json:"created"`
+	Model   string           `json:"model"`
+	Choices []ir.StreamDelta `json:"choices"`
+	Usage   *wireUsage       `json:"usage,omitempty"`
+}
+
+type wireUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+type embeddingsRequest struct {
+	Model          string          `json:"model"`
+	Input          json.RawMessage `json:"input"` // string or []string
+	Dimensions     int             `json:"dimensions,omitempty"`
+	EncodingFormat string          `json:"encoding_format,omitempty"` // float (default) or base64
+
+	// TaskType is an extension passed through to EmbedRequest.TaskType.
+	TaskType string `json:"task_type,omitempty"`
+}
+
+type embeddingsResponse struct {
+	Object string          `json:"object"`
+	Data   []wireEmbedding `json:"data"`
+	Model  string          `json:"model"`
+	Usage  embeddingsUsage `json:"usage"`
+}
+
+type wireEmbedding struct {
+	Object    string `json:"object"`
+	Index     int    `json:"index"`
+	Embedding any    `json:"embedding"` // []float32, or a base64 string
+}
+
+type embeddingsUsage struct {
+	PromptTokens int64 `json:"prompt_tokens"`
+	TotalTokens  int64 `json:"total_tokens"`
+}
+
+type modelList struct {
+	Object string      `json:"object"`
+	Data   []wireModel `json:"data"`
+}
+
+type wireModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// errBadRequest marks a request the gateway could not translate.
+var errBadRequest = errors.New("bad request")
+
+func badRequest(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", errBadRequest, fmt.Sprintf(format, args...))
+}
+
+// toChatRequest translates the wire request into the router's.
+func (w chatCompletionRequest) toChatRequest() (ir.ChatRequest, error) {
+	req := ir.ChatRequest{
+		Model:       w.Model,
+		Temperature: w.Temperature,
+		MaxTokens:   w.MaxTokens,
+		TopP:        w.TopP,
+		N:           w.N,
+		Stream:      w.Stream,
+		Tools:       w.Tools,
+
+		Seed:             w.Seed,
+		PresencePenalty:  w.PresencePenalty,
+		FrequencyPenalty: w.FrequencyPenalty,
+	}
+	if req.MaxTokens == nil {
+		req.MaxTokens = w.MaxCompletionTokens
+	}
+	if w.N != nil {
+		switch {
+		case *w.N < 1:
+			return ir.ChatRequest{}, badRequest("n: must be at least 1")
+		case *w.N > 1 && w.Stream:
+			return ir.ChatRequest{}, badRequest("n: a stream carries one completion")
+		}
+	}
+
+	stop, err := stringOrList(w.Stop)
+	if err != nil {
+		return ir.ChatRequest{}, badRequest("stop: %v", err)
+	}
+	req.Stop = stop
+
+	if req.ToolChoice, err = toolChoice(w.ToolChoice); err != nil {
+		return ir.ChatRequest{}, err
+	}
+
+	if rf := w.ResponseFormat; rf != nil {
+		req.ResponseFormat = &ir.ResponseFormat{Type: ir.ResponseFormatType(rf.Type)}
+		if rf.JSONSchema != nil {
+			req.ResponseFormat.Name = rf.JSONSchema.Name
+			req.ResponseFormat.Schema = rf.JSONSchema.Schema
+			req.ResponseFormat.Strict = rf.JSONSchema.Strict
+		}
+	}
+
+	req.Messages = make([]ir.Message, len(w.Messages))
+	for i, m := range w.Messages {
+		msg := ir.Message{Role: m.Role, Name: m.Name, ToolCalls: m.ToolCalls, ToolCallID: m.ToolCallID}
+		if err := decodeContent(m.Content, &msg); err != nil {
+			return ir.ChatRequest{}, badRequest("messages[%d].content: %v", i, err)
+		}
+		req.Messages[i] = msg
+	}
+	return req, nil
+}
+
+func toolChoice(raw json.RawMessage) (*ir.ToolChoice, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var mode string
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		return &ir.ToolChoice{Mode: ir.ToolChoiceMode(mode)}, nil
+	}
+	var forced struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &forced); err != nil || forced.Function.Name == "" {
+		return nil, badRequest("tool_choice: expected a mode or a function")
+	}
+	return &ir.ToolChoice{Function: forced.Function.Name}, nil
+}
+
+// decodeContent fills msg from an OpenAI content field: a plain string, or an
+// array of typed parts. Media must be inline (data URLs, base64 audio); the
+// router has no way to fetch remote URLs on a provider's behalf.
+func decodeContent(raw json.RawMessage, msg *ir.Message) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &msg.Content); err == nil {
+		return nil
+	}
+
+	var parts []contentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return errors.New("expected a string or an array of content parts")
+	}
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			msg.Parts = append(msg.Parts, ir.Part{Type: ir.PartText, Text: p.Text})
+		case "image_url":
+			if p.ImageURL == nil {
+				return errors.New("image_url part without image_url")
+			}
+			mime, data, err := decodeDataURL(p.ImageURL.URL)
+			if err != nil {
+				return err
+			}
+			msg.Parts = append(msg.Parts, ir.Part{Type: ir.PartImage, MIMEType: mime, Data: data})
+		case "input_audio":
+			if p.InputAudio == nil {
+				return errors.New("input_audio part without input_audio")
+			}
+			data, err := base64.StdEncoding.DecodeString(p.InputAudio.Data)
+			if err != nil {
+				return fmt.Errorf("input_audio: %v", err)
+			}
+			msg.Parts = append(msg.Parts, ir.Part{Type: ir.PartAudio, MIMEType: "audio/" + p.InputAudio.Format, Data: data})
+		default:
+			return fmt.Errorf("unsupported content part type %q", p.Type)
+		}
+	}
+	return nil
+}
+
+// decodeDataURL splits a base64 data URL into its MIME type and bytes.
+func decodeDataURL(url string) (string, []byte, error) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return "", nil, errors.New("only data: URLs are supported for images")
+	}
+	meta, payload, ok := strings.Cut(rest, ",")
+	mime, isBase64 := strings.CutSuffix(meta, ";base64")
+	if !ok || !isBase64 || mime == "" {
+		return "", nil, errors.New("malformed data URL: want data:<mime>;base64,<data>")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("data URL: %v", err)
+	}
+	return mime, data, nil
+}
+
+func stringOrList(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var one string
+	if err := json.Unmarshal(raw, &one); err == nil {
+		return []string{one}, nil
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return nil, errors.New("expected a string or an array of strings")
+	}
+	return many, nil
+}
+
+func fromUsage(u ir.Usage) *wireUsage {
+	return &wireUsage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
+
+// encodeEmbedding renders a vector the way OpenAI's encoding_format=base64
+// does: little-endian float32s, base64-encoded.
+func encodeEmbedding(vec []float32) string {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -33,6 +33,10 @@ type ModelMapping struct {
 type ModelRef struct {
 	Provider string `yaml:"provider"`
 	Model    string `yaml:"model"`
+
+	// Info overrides the built-in catalog entry for Model on this step;
+	// see ModelInfo.
+	Info ModelInfo `yaml:"info"`
 }
 
 // AccountConfig configures a single provider account.
@@ -50,6 +54,11 @@ type AccountConfig struct {
 	DailyFree int64     `yaml:"daily_free"`
 	QuotaUnit QuotaUnit `yaml:"quota_unit"`
 
+	// DailyFreeDollars is the daily allowance of a quota_unit: dollars
+	// account, which is drawn down by the cost of each request as computed
+	// from the cost rates below. DailyFree is for tokens and requests only.
+	DailyFreeDollars float64 `yaml:"daily_free_dollars"`
+
 	// AttemptTimeout overrides Config.AttemptTimeout for this account. Useful
 	// when one step is known to be much slower than its neighbours.
 	AttemptTimeout time.Duration `yaml:"attempt_timeout"`
@@ -91,9 +100,15 @@ type AccountConfig struct {
 	RPM int `yaml:"rpm"`
 
 	// ModelLimits configures per-model rate limits for this account.
-	// When set, each model has independent RPM/RPH/RPD budgets.
+	// When set, each model has independent RPM/RPH/RPD and TPM/TPD budgets
+	// and its own max_concurrency.
 	// Models not listed fall back to the account-level RPM.
 	ModelLimits map[string]Limits `yaml:"model_limits"`
+
+	// ModelInfo overrides the catalog entry of a model on this account, for
+	// a gateway that serves it with a smaller window than the vendor, say.
+	// It takes precedence over ModelRef.Info.
+	ModelInfo map[string]ModelInfo `yaml:"model_info"`
 }
 
 // LoadConfig reads and parses a YAML config file.
@@ -144,6 +159,15 @@ func (c Config) Validate() error {
 			return fmt.Errorf("inferrouter: config: account[%d] (%s): invalid quota_unit %q", i, acc.ID, acc.QuotaUnit)
 		}
 
+		if acc.QuotaUnit == QuotaDollars && acc.DailyFree != 0 {
+			return fmt.Errorf("inferrouter: config: account[%d] (%s): quota_unit dollars takes daily_free_dollars, not daily_free", i, acc.ID)
+		}
+		if acc.QuotaUnit != QuotaDollars && acc.DailyFreeDollars != 0 {
+			return fmt.Errorf("inferrouter: config: account[%d] (%s): daily_free_dollars requires quota_unit dollars", i, acc.ID)
+		}
+		if acc.DailyFreeDollars < 0 {
+			return fmt.Errorf("inferrouter: config: account[%d] (%s): daily_free_dollars must be >= 0", i, acc.ID)
+		}
 		if acc.DailyFree < 0 {
 			return fmt.Errorf("inferrouter: config: account[%d] (%s): daily_free must be >= 0", i, acc.ID)
 		}
@@ -175,10 +199,16 @@ func (c Config) Validate() error {
 			return fmt.Errorf("inferrouter: config: account[%d] (%s): rpm must be >= 0", i, acc.ID)
 		}
 		for model, limits := range acc.ModelLimits {
-			if limits.RPM < 0 || limits.RPH < 0 || limits.RPD < 0 {
+			if limits.RPM < 0 || limits.RPH < 0 || limits.RPD < 0 ||
+				limits.TPM < 0 || limits.TPD < 0 || limits.MaxConcurrency < 0 {
 				return fmt.Errorf("inferrouter: config: account[%d] (%s): model_limits[%s]: values must be >= 0", i, acc.ID, model)
 			}
 		}
+		for model, info := range acc.ModelInfo {
+			if info.ContextWindow < 0 || info.MaxOutputTokens < 0 {
+				return fmt.Errorf("inferrouter: config: account[%d] (%s): model_info[%s]: values must be >= 0", i, acc.ID, model)
+			}
+		}
 	}
 
 	for i, m := range c.Models {
@@ -188,6 +218,11 @@ func (c Config) Validate() error {
 		if len(m.Models) == 0 {
 			return fmt.Errorf("inferrouter: config: models[%d] (%s): at least one model ref is required", i, m.Alias)
 		}
+		for j, ref := range m.Models {
+			if ref.Info.ContextWindow < 0 || ref.Info.MaxOutputTokens < 0 {
+				return fmt.Errorf("inferrouter: config: models[%d] (%s): models[%d]: info values must be >= 0", i, m.Alias, j)
+			}
+		}
 	}
 
 	return nil
@@ -204,3 +239,12 @@ func (c *Config) NormalizeCosts() {
 		}
 	}
 }
+
+// dailyQuota is the account's daily free allowance in its QuotaUnit; zero
+// means it has none.
+func (a AccountConfig) dailyQuota() int64 {
+	if a.QuotaUnit == QuotaDollars {
+		return ToNanodollars(a.DailyFreeDollars)
+	}
+	return a.DailyFree
+}
@@ -49,6 +49,45 @@ accounts:
 	}
 }
 
+func TestLoadConfigParsesModelInfo(t *testing.T) {
+	yamlCfg := `
+default_model: ladder
+models:
+  - alias: ladder
+    models:
+      - provider: gw
+        model: small
+        info:
+          context_window: 8192
+          input: [text]
+          tools: false
+accounts:
+  - provider: gw
+    id: gw-1
+    quota_unit: tokens
+    daily_free: 1000
+    model_info:
+      small:
+        max_output_tokens: 1024
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yamlCfg), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	info := modelInfo(cfg.Accounts[0], cfg.Models[0].Models[0])
+	if info.ContextWindow != 8192 || info.MaxOutputTokens != 1024 {
+		t.Errorf("window %d, output %d; want 8192, 1024", info.ContextWindow, info.MaxOutputTokens)
+	}
+	if len(info.Input) != 1 || info.Input[0] != PartText || !isFalse(info.Tools) {
+		t.Errorf("info = %+v, want text-only without tools", info)
+	}
+}
+
 func TestConfigValidateRequiresAccount(t *testing.T) {
 	cfg := Config{}
 	if err := cfg.Validate(); err == nil {
@@ -198,3 +237,31 @@ func TestNormalizeCostsLegacyFallback(t *testing.T) {
 		t.Errorf("CostPerOutputToken = %v, want 0.002", got.CostPerOutputToken)
 	}
 }
+
+func TestConfigValidateDollarQuota(t *testing.T) {
+	acc := validAccount()
+	acc.QuotaUnit = QuotaDollars
+	acc.DailyFree = 0
+	acc.DailyFreeDollars = 2.5
+	if err := (&Config{Accounts: []AccountConfig{acc}}).Validate(); err != nil {
+		t.Fatalf("daily_free_dollars with quota_unit dollars: %v", err)
+	}
+	if got := acc.dailyQuota(); got != 2_500_000_000 {
+		t.Errorf("dailyQuota = %d, want 2.5e9 nanodollars", got)
+	}
+
+	cases := map[string]func(*AccountConfig){
+		"daily_free with dollars": func(a *AccountConfig) { a.QuotaUnit = QuotaDollars; a.DailyFree = 100 },
+		"dollars with tokens":     func(a *AccountConfig) { a.QuotaUnit = QuotaTokens; a.DailyFreeDollars = 1 },
+		"negative dollars":        func(a *AccountConfig) { a.QuotaUnit = QuotaDollars; a.DailyFree = 0; a.DailyFreeDollars = -1 },
+	}
+	for name, mut := range cases {
+		t.Run(name, func(t *testing.T) {
+			acc := validAccount()
+			mut(&acc)
+			if err := (&Config{Accounts: []AccountConfig{acc}}).Validate(); err == nil {
+				t.Fatal("expected a validation error")
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package inferrouter
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultRateLimitCooldown is how long an (account, model) pair is left alone
+// after a 429 that did not say when to come back.
+const DefaultRateLimitCooldown = 10 * time.Second
+
+// cooldownTracker remembers which (account, model) pairs answered 429 and
+// until when they should not be tried. It is deliberately separate from
+// HealthTracker: a rate limit is a budget signal, not a fault, and an account
+// that asked for thirty seconds of quiet is healthy the moment they pass.
+type cooldownTracker struct {
+	mu    sync.Mutex
+	until map[string]time.Time // key: "accountID:model"
+	now   func() time.Time
+}
+
+func newCooldownTracker() *cooldownTracker {
+	return &cooldownTracker{until: make(map[string]time.Time), now: time.Now}
+}
+
+// start puts the pair in cooldown for d, extending an existing cooldown but
+// never shortening it.
+func (t *cooldownTracker) start(accountID, model string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := accountID + ":" + model
+	if until := t.now().Add(d); until.After(t.until[key]) {
+		t.until[key] = until
+	}
+}
+
+// remaining returns how long the pair is still cooling down, or zero.
+func (t *cooldownTracker) remaining(accountID, model string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := accountID + ":" + model
+	until, ok := t.until[key]
+	if !ok {
+		return 0
+	}
+	left := until.Sub(t.now())
+	if left <= 0 {
+		delete(t.until, key)
+		return 0
+	}
+	return left
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/provider/mock"
 	"github.com/ineyio/inferrouter/quota"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -123,3 +124,125 @@ func TestFreeQuotaStillEnforced(t *testing.T) {
 	require.Error(t, err)
 	assert.Empty(t, log.snapshot(), "the declared allowance must still stop the request")
 }
+
+// dollarQuotaLadder is one free account whose allowance is in dollars. The
+// mock reports 10 prompt + 20 completion tokens, so a request costs $0.03.
+func dollarQuotaLadder() ir.Config {
+	return ir.Config{
+		DefaultModel: "test-model",
+		Accounts: []ir.AccountConfig{{
+			Provider:           "mock",
+			ID:                 "dollar-free",
+			QuotaUnit:          ir.QuotaDollars,
+			DailyFreeDollars:   0.05,
+			CostPerInputToken:  0.001,
+			CostPerOutputToken: 0.001,
+		}},
+	}
+}
+
+func TestNanodollars(t *testing.T) {
+	assert.EqualValues(t, 30_000_000, ir.ToNanodollars(0.03))
+	assert.EqualValues(t, 1, ir.ToNanodollars(0.0000000006), "rounds to the nearest nanodollar")
+	assert.InDelta(t, 0.03, ir.FromNanodollars(30_000_000), 1e-12)
+}
+
+func TestDollarQuota_CommitsCostNotTokens(t *testing.T) {
+	qs := quota.NewMemoryQuotaStore()
+	r, err := ir.NewRouter(declareLadder(dollarQuotaLadder()),
+		[]ir.Provider{mock.New(mock.WithModels("test-model"))},
+		ir.WithQuotaStore(qs))
+	require.NoError(t, err)
+	req := ir.ChatRequest{Messages: []ir.Message{{Role: "user", Content: "hello"}}}
+
+	resp, err := r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, resp.Routing.Free)
+
+	remaining, err := qs.Remaining(context.Background(), "dollar-free")
+	require.NoError(t, err)
+	assert.EqualValues(t, ir.ToNanodollars(0.02), remaining, "$0.05 - $0.03")
+
+	// $0.02 left covers the estimate ($0.008) but not the real cost; the
+	// overrun is committed, and the allowance is gone after that.
+	_, err = r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	_, err = r.ChatCompletion(context.Background(), req)
+	assert.ErrorIs(t, err, ir.ErrNoCandidates)
+}
+
+func TestDollarQuota_EstimateReserved(t *testing.T) {
+	cfg := dollarQuotaLadder()
+	cfg.Accounts[0].DailyFreeDollars = 0.005 // below the $0.008 estimate for "hello"
+	r, err := ir.NewRouter(declareLadder(cfg),
+		[]ir.Provider{mock.New(mock.WithModels("test-model"))},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	require.NoError(t, err)
+
+	_, err = r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ir.ErrAllFailed)
+}
+
+func TestDollarQuota_Stream(t *testing.T) {
+	qs := quota.NewMemoryQuotaStore()
+	r, err := ir.NewRouter(declareLadder(dollarQuotaLadder()),
+		[]ir.Provider{mock.New(mock.WithModels("test-model"))},
+		ir.WithQuotaStore(qs))
+	require.NoError(t, err)
+
+	stream, err := r.ChatCompletionStream(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+	for {
+		if _, err := stream.Next(); err != nil {
+			break
+		}
+	}
+	require.NoError(t, stream.Close())
+
+	remaining, err := qs.Remaining(context.Background(), "dollar-free")
+	require.NoError(t, err)
+	assert.EqualValues(t, ir.ToNanodollars(0.02), remaining)
+}
+
+func TestDollarQuota_Embeddings(t *testing.T) {
+	embedProv := mock.NewEmbed(mock.WithEmbedSupportedModels("text-embedding-004"))
+	cfg := ir.Config{
+		DefaultModel: "text-embedding-004",
+		Accounts: []ir.AccountConfig{{
+			Provider: "mock-embed", ID: "dollar-free",
+			QuotaUnit: ir.QuotaDollars, DailyFreeDollars: 1,
+			CostPerEmbeddingInputToken: 0.0001,
+		}},
+	}
+	qs := quota.NewMemoryQuotaStore()
+	r, err := ir.NewRouter(declareLadder(cfg), []ir.Provider{embedProviderAsProvider(embedProv)},
+		ir.WithQuotaStore(qs))
+	require.NoError(t, err)
+
+	// Three inputs at the mock's 4 tokens each: 12 tokens, $0.0012.
+	_, err = r.EmbedBatch(context.Background(), ir.EmbedRequest{Inputs: []string{"a", "b", "c"}})
+	require.NoError(t, err)
+
+	remaining, err := qs.Remaining(context.Background(), "dollar-free")
+	require.NoError(t, err)
+	assert.EqualValues(t, ir.ToNanodollars(1-0.0012), remaining)
+}
+
+func TestConfigWarnings_DollarQuotaWithoutPrice(t *testing.T) {
+	cfg := dollarQuotaLadder()
+	cfg.Accounts[0].CostPerInputToken = 0
+	cfg.Accounts[0].CostPerOutputToken = 0
+
+	r, err := ir.NewRouter(declareLadder(cfg), []ir.Provider{mock.New(mock.WithModels("test-model"))},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	require.NoError(t, err)
+
+	warnings := r.ConfigWarnings()
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "never drawn down")
+}
@@ -22,17 +22,18 @@ func EstimateEmbedTokens(inputs []string) int64 {
 // embedding request. Symmetric to Candidate for chat, but references
 // EmbeddingProvider and uses the embedding-specific cost field.
 type EmbedCandidate struct {
-	Provider      EmbeddingProvider
-	AccountID     string
-	Auth          Auth
-	Model         string
-	Free          bool
-	Remaining     int64
-	QuotaUnit     QuotaUnit
-	Health        HealthState
-	Cost          float64 // CostPerEmbeddingInputToken
-	MaxDailySpend float64
-	CurrentSpend  float64
+	Provider       EmbeddingProvider
+	AccountID      string
+	Auth           Auth
+	Model          string
+	Free           bool
+	Remaining      int64
+	QuotaUnit      QuotaUnit
+	Health         HealthState
+	ProviderHealth HealthState // provider-wide circuit, see Candidate.ProviderHealth
+	Cost           float64     // CostPerEmbeddingInputToken
+	MaxDailySpend  float64
+	CurrentSpend   float64
 }
 
 // buildEmbedCandidates creates the list of possible embedding candidates for
@@ -48,7 +49,7 @@ func buildEmbedCandidates(
 	embedProviders map[string]EmbeddingProvider,
 	quotaStore QuotaStore,
 	health *HealthTracker,
-	spend *SpendTracker,
+	spend SpendStore,
 	requestModel string,
 ) ([]EmbedCandidate, error) {
 	refs, err := resolveModel(cfg, requestModel)
@@ -68,26 +69,27 @@ func buildEmbedCandidates(
 			// Account must have either free quota or a non-zero embedding cost
 			// to be a valid candidate. Zero-cost paid accounts are explicitly
 			// treated as "embeddings disabled" per config spec.
-			if acc.DailyFree == 0 && acc.CostPerEmbeddingInputToken == 0 {
+			if acc.dailyQuota() == 0 && acc.CostPerEmbeddingInputToken == 0 {
 				continue
 			}
 
 			remaining, remainErr := quotaStore.Remaining(ctx, acc.ID)
 			// Fail-open: assume free if we can't check.
-			free := acc.DailyFree > 0 && (remaining > 0 || remainErr != nil)
+			free := acc.dailyQuota() > 0 && (remaining > 0 || remainErr != nil)
 
 			candidates = append(candidates, EmbedCandidate{
-				Provider:      prov,
-				AccountID:     acc.ID,
-				Auth:          acc.Auth,
-				Model:         model,
-				Free:          free,
-				Remaining:     remaining,
-				QuotaUnit:     acc.QuotaUnit,
-				Health:        health.GetHealth(acc.ID),
-				Cost:          acc.CostPerEmbeddingInputToken,
-				MaxDailySpend: acc.MaxDailySpend,
-				CurrentSpend:  spend.GetSpend(acc.ID),
+				Provider:       prov,
+				AccountID:      acc.ID,
+				Auth:           acc.Auth,
+				Model:          model,
+				Free:           free,
+				Remaining:      remaining,
+				QuotaUnit:      acc.QuotaUnit,
+				Health:         health.GetHealth(acc.ID),
+				ProviderHealth: health.GetProviderHealth(acc.Provider),
+				Cost:           acc.CostPerEmbeddingInputToken,
+				MaxDailySpend:  acc.MaxDailySpend,
+				CurrentSpend:   currentSpend(ctx, spend, acc),
 			})
 		}
 	}
@@ -108,12 +110,13 @@ func embedModelsForAccount(refs []ModelRef, acc AccountConfig, prov EmbeddingPro
 	return models
 }
 
-// filterEmbedCandidates removes unhealthy candidates and enforces paid/spend
-// limits. Symmetric to filterCandidates for chat; no multimodal dimension.
+// filterEmbedCandidates removes unhealthy candidates (by account or
+// provider-wide circuit) and enforces paid/spend limits. Symmetric to
+// filterCandidates for chat; no multimodal dimension.
 func filterEmbedCandidates(candidates []EmbedCandidate, allowPaid bool) []EmbedCandidate {
 	filtered := make([]EmbedCandidate, 0, len(candidates))
 	for _, c := range candidates {
-		if c.Health == HealthUnhealthy {
+		if c.Health == HealthUnhealthy || c.ProviderHealth == HealthUnhealthy {
 			continue
 		}
 		if !c.Free && !allowPaid {
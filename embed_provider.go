@@ -9,9 +9,9 @@ import "context"
 // EmbeddingProvider (for embeddings), or only one of them. The router
 // discovers embedding capability via type assertion at NewRouter time.
 //
-// Chat-only providers (e.g. openaicompat in its current form, gonka) do
-// not implement this interface — this is honest via compile-time absence,
-// not via a runtime "return ErrNotSupported" stub.
+// Chat-only providers (e.g. anthropic) do not implement this interface —
+// this is honest via compile-time absence, not via a runtime
+// "return ErrNotSupported" stub.
 //
 // See RFC docs/proposals/inferrouter-embeddings.md §3.1.
 type EmbeddingProvider interface {
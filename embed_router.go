@@ -2,10 +2,9 @@ package inferrouter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 // validateEmbeddingAliases enforces the single-model invariant for embedding
@@ -50,49 +49,43 @@ func validateEmbeddingAliases(cfg Config, embedProviders map[string]EmbeddingPro
 // so accounts are tried as the alias and cfg.Accounts declare them. Embeddings
 // have no pluggable Policy — if deliberate reordering is ever needed here,
 // parallel the chat Policy interface at that point.
-func (r *Router) prepareEmbedRoute(ctx context.Context, requestModel string) ([]EmbedCandidate, error) {
-	candidates, err := buildEmbedCandidates(ctx, r.cfg, r.embedProviders, r.quotaStore, r.health, r.spend, requestModel)
+func (r *Router) prepareEmbedRoute(ctx context.Context, req EmbedRequest) ([]EmbedCandidate, error) {
+	candidates, err := buildEmbedCandidates(ctx, r.cfg, r.embedProviders, r.quotaStore, r.health, r.spend, req.Model)
 	if err != nil {
 		return nil, err
 	}
 
-	candidates = filterEmbedCandidates(candidates, r.cfg.AllowPaid)
+	candidates = filterEmbedCandidates(candidates, req.Route.allowPaid(r.cfg.AllowPaid))
 	if len(candidates) == 0 {
 		return nil, ErrNoEmbeddingProviders
 	}
-
-	return candidates, nil
-}
-
-// acquireEmbed attempts RPM check and quota reservation for an embed candidate.
-func (r *Router) acquireEmbed(ctx context.Context, c EmbedCandidate, estimatedTokens int64) (Reservation, *CandidateError) {
-	if !r.rateLimiter.Allow(c.AccountID, c.Model) {
-		return Reservation{}, &CandidateError{
-			Provider: c.Provider.Name(), AccountID: c.AccountID, Model: c.Model,
-			Err: ErrRPMExceeded,
-		}
+	candidates = applyEmbedRouteOptions(req.Route, candidates, EstimateEmbedTokens(req.Inputs))
+	if len(candidates) == 0 {
+		return nil, ErrNoCandidates
 	}
 
-	reserveAmount := estimatedTokens
-	if c.QuotaUnit == QuotaRequests {
-		reserveAmount = 1
-	}
+	return capAttempts(req.Route, candidates), nil
+}
 
-	reservation, err := r.quotaStore.Reserve(ctx, c.AccountID, reserveAmount, c.QuotaUnit, uuid.New().String())
-	if err != nil {
-		return Reservation{}, &CandidateError{
-			Provider: c.Provider.Name(), AccountID: c.AccountID, Model: c.Model,
-			Err: err,
-		}
-	}
-	return reservation, nil
+// acquireEmbed is acquire for an embed candidate: cooldown, concurrency and
+// rate limits, then quota. Symmetric to acquire for chat.
+func (r *Router) acquireEmbed(ctx context.Context, c EmbedCandidate, estimatedTokens int64) (grant, *CandidateError) {
+	reserveAmount := quotaAmount(c.QuotaUnit, estimatedTokens, float64(estimatedTokens)*c.Cost)
+	return r.acquireFor(ctx, c.Provider.Name(), c.AccountID, c.Model, c.QuotaUnit, estimatedTokens, reserveAmount)
 }
 
 // settleEmbedFailure handles rollback, health tracking, and metering after
 // an embedding provider error. Symmetric to settleFailure for chat.
-func (r *Router) settleEmbedFailure(ctx context.Context, c EmbedCandidate, reservation Reservation, providerErr error, duration time.Duration, attempt int) (*RouterError, CandidateError) {
-	rollbackErr := r.quotaStore.Rollback(ctx, reservation)
-	r.health.RecordFailure(c.AccountID)
+func (r *Router) settleEmbedFailure(ctx context.Context, c EmbedCandidate, g grant, providerErr error, duration time.Duration, attempt int) (*RouterError, CandidateError) {
+	ctx, span := r.tracer.Start(ctx, SpanSettle)
+	defer span.End()
+
+	rollbackErr := r.quotaStore.Rollback(ctx, g.Reservation)
+	if rollbackErr != nil {
+		span.RecordError(rollbackErr)
+	}
+	r.rateLimiter.AdjustTokens(ctx, c.AccountID, c.Model, -g.tokens)
+	r.recordFailureHealth(ctx, c.Provider.Name(), c.AccountID, c.Model, providerErr)
 
 	resultErr := providerErr
 	if rollbackErr != nil {
@@ -128,23 +121,27 @@ func (r *Router) settleEmbedFailure(ctx context.Context, c EmbedCandidate, reser
 
 // settleEmbedSuccess handles quota commit, health tracking, spend recording,
 // and metering after a successful embedding provider response.
-func (r *Router) settleEmbedSuccess(ctx context.Context, c EmbedCandidate, reservation Reservation, usage EmbedUsage, duration time.Duration) {
-	actualTokens := usage.TotalTokens
-	if c.QuotaUnit == QuotaRequests {
-		actualTokens = 1
-	}
-	commitErr := r.quotaStore.Commit(ctx, reservation, actualTokens)
-	r.health.RecordSuccess(c.AccountID)
+func (r *Router) settleEmbedSuccess(ctx context.Context, c EmbedCandidate, g grant, usage EmbedUsage, duration time.Duration) {
+	ctx, span := r.tracer.Start(ctx, SpanSettle)
+	defer span.End()
 
 	dollarCost := float64(usage.InputTokens) * c.Cost
-	if dollarCost > 0 {
-		r.spend.RecordSpend(c.AccountID, dollarCost)
-	}
+	attemptSpan(ctx).SetAttributes(usageAttributes(Usage{PromptTokens: usage.InputTokens}, dollarCost)...)
+	commitErr := r.quotaStore.Commit(ctx, g.Reservation, quotaAmount(c.QuotaUnit, usage.TotalTokens, dollarCost))
+	r.rateLimiter.AdjustTokens(ctx, c.AccountID, c.Model, usage.TotalTokens-g.tokens)
+	r.health.RecordSuccess(c.AccountID)
+	r.health.RecordProviderSuccess(c.Provider.Name())
+
+	spendErr := recordSpend(ctx, r.spend, c.AccountID, dollarCost)
 
 	var meterErr error
 	if commitErr != nil {
 		meterErr = fmt.Errorf("quota commit failed: %w", commitErr)
 	}
+	meterErr = errors.Join(meterErr, spendErr)
+	if meterErr != nil {
+		span.RecordError(meterErr)
+	}
 
 	// Reuse chat Usage type for the meter event (embedding fills only input
 	// tokens). Meter consumers that care about embedding-vs-chat distinction
@@ -154,7 +151,7 @@ func (r *Router) settleEmbedSuccess(ctx context.Context, c EmbedCandidate, reser
 		AccountID:  c.AccountID,
 		Model:      c.Model,
 		Free:       c.Free,
-		Success:    commitErr == nil,
+		Success:    meterErr == nil,
 		Duration:   duration,
 		Usage:      Usage{PromptTokens: usage.InputTokens, TotalTokens: usage.TotalTokens},
 		Error:      meterErr,
@@ -183,12 +180,15 @@ func buildEmbedProviderRequest(c EmbedCandidate, req EmbedRequest, inputs []stri
 //
 // Returns ErrBatchTooLarge if len(req.Inputs) exceeds any available
 // provider's MaxBatchSize — callers should switch to EmbedBatch instead.
-func (r *Router) Embed(ctx context.Context, req EmbedRequest) (EmbedResponse, error) {
+func (r *Router) Embed(ctx context.Context, req EmbedRequest) (resp EmbedResponse, err error) {
+	ctx, span := r.startCall(ctx, SpanEmbed, req.Model)
+	defer func() { endCall(span, resp.Routing, err) }()
+
 	if len(req.Inputs) == 0 {
 		return EmbedResponse{}, fmt.Errorf("%w: empty inputs", ErrInvalidRequest)
 	}
 
-	ordered, err := r.prepareEmbedRoute(ctx, req.Model)
+	ordered, err := r.prepareEmbedRoute(ctx, req)
 	if err != nil {
 		return EmbedResponse{}, err
 	}
@@ -203,7 +203,7 @@ func (r *Router) Embed(ctx context.Context, req EmbedRequest) (EmbedResponse, er
 	}
 
 	estimatedTokens := EstimateEmbedTokens(req.Inputs)
-	resp, _, err := r.embedOnce(ctx, ordered, req, req.Inputs, estimatedTokens)
+	resp, _, err = r.embedOnce(ctx, ordered, req, req.Inputs, estimatedTokens)
 	return resp, err
 }
 
@@ -229,12 +229,15 @@ func (r *Router) Embed(ctx context.Context, req EmbedRequest) (EmbedResponse, er
 //
 // Full failure path (no successful sub-batches): returns zero-value
 // EmbedResponse with a non-*ErrPartialBatch error (RouterError or sentinel).
-func (r *Router) EmbedBatch(ctx context.Context, req EmbedRequest) (EmbedResponse, error) {
+func (r *Router) EmbedBatch(ctx context.Context, req EmbedRequest) (out EmbedResponse, err error) {
+	ctx, span := r.startCall(ctx, SpanEmbedBatch, req.Model)
+	defer func() { endCall(span, out.Routing, err) }()
+
 	if len(req.Inputs) == 0 {
 		return EmbedResponse{}, fmt.Errorf("%w: empty inputs", ErrInvalidRequest)
 	}
 
-	ordered, err := r.prepareEmbedRoute(ctx, req.Model)
+	ordered, err := r.prepareEmbedRoute(ctx, req)
 	if err != nil {
 		return EmbedResponse{}, err
 	}
@@ -298,8 +301,10 @@ func (r *Router) EmbedBatch(ctx context.Context, req EmbedRequest) (EmbedRespons
 func (r *Router) embedOnce(ctx context.Context, ordered []EmbedCandidate, req EmbedRequest, inputs []string, estimatedTokens int64) (EmbedResponse, RoutingInfo, error) {
 	var tried []CandidateError
 	for attempt, c := range ordered {
-		reservation, skip := r.acquireEmbed(ctx, c, estimatedTokens)
+		actx, aspan := r.startAttempt(ctx, c.Provider.Name(), c.AccountID, c.Model, c.Free, attempt+1, estimatedTokens)
+		g, skip := r.acquireEmbed(actx, c, estimatedTokens)
 		if skip != nil {
+			endSpan(aspan, skip)
 			tried = append(tried, *skip)
 			continue
 		}
@@ -313,12 +318,16 @@ func (r *Router) embedOnce(ctx context.Context, ordered []EmbedCandidate, req Em
 			EstimatedIn: estimatedTokens,
 		})
 
+		callCtx, callSpan := r.tracer.Start(actx, SpanProviderCall)
 		start := time.Now()
-		provResp, err := c.Provider.Embed(ctx, buildEmbedProviderRequest(c, req, inputs))
+		provResp, err := c.Provider.Embed(callCtx, buildEmbedProviderRequest(c, req, inputs))
 		duration := time.Since(start)
+		endSpan(callSpan, err)
+		r.inflight.Release(c.AccountID, c.Model)
 
 		if err != nil {
-			fatal, ce := r.settleEmbedFailure(ctx, c, reservation, err, duration, attempt)
+			fatal, ce := r.settleEmbedFailure(actx, c, g, err, duration, attempt)
+			endSpan(aspan, &ce)
 			if fatal != nil {
 				return EmbedResponse{}, RoutingInfo{}, fatal
 			}
@@ -326,7 +335,8 @@ func (r *Router) embedOnce(ctx context.Context, ordered []EmbedCandidate, req Em
 			continue
 		}
 
-		r.settleEmbedSuccess(ctx, c, reservation, provResp.Usage, duration)
+		r.settleEmbedSuccess(actx, c, g, provResp.Usage, duration)
+		aspan.End()
 
 		routing := RoutingInfo{
 			Provider:  c.Provider.Name(),
@@ -29,6 +29,9 @@ type EmbedRequest struct {
 	// text-embedding-004 supports [1..768]. Ignored by providers that
 	// don't support truncation.
 	OutputDimensionality int
+
+	// Route overrides routing for this request only; see RouteOptions.
+	Route *RouteOptions
 }
 
 // EmbedResponse is the public API response.
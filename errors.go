@@ -3,6 +3,8 @@ package inferrouter
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
 // Sentinel errors.
@@ -18,12 +20,54 @@ var (
 	ErrAllFailed           = errors.New("inferrouter: all candidates failed")
 	ErrRPMExceeded         = errors.New("inferrouter: requests per minute limit exceeded")
 
+	// ErrTPMExceeded is recorded for a candidate skipped because the request's
+	// estimated tokens do not fit its TPM/TPD window. Retryable, like
+	// ErrRPMExceeded: the next candidate has its own budget.
+	ErrTPMExceeded = errors.New("inferrouter: tokens per minute limit exceeded")
+
+	// ErrConcurrencyExceeded is recorded for a candidate skipped because it
+	// already has Limits.MaxConcurrency requests in flight.
+	ErrConcurrencyExceeded = errors.New("inferrouter: max concurrent requests exceeded")
+
 	// ErrMultimodalUnavailable is returned when a request contains media parts
 	// but no multimodal-capable candidate is available (all filtered out or
 	// unhealthy). Not retryable with text-only fallback — callers should catch
 	// this explicitly and either degrade (strip media) or fail the request.
 	ErrMultimodalUnavailable = errors.New("inferrouter: no multimodal-capable candidates available")
 
+	// ErrToolsUnavailable is returned when a request uses tool calling but no
+	// tool-capable candidate is available. Symmetric to
+	// ErrMultimodalUnavailable: callers decide whether to drop the tools or
+	// fail the request.
+	ErrToolsUnavailable = errors.New("inferrouter: no tool-capable candidates available")
+
+	// ErrContextWindowExceeded is returned when the model catalog says no
+	// step of the ladder can take the request: the estimated prompt plus
+	// ChatRequest.MaxTokens exceeds every candidate's context window, or
+	// MaxTokens exceeds every candidate's output limit. Unknown limits never
+	// count against a step, so a ladder without catalog entries never
+	// returns it.
+	ErrContextWindowExceeded = errors.New("inferrouter: request exceeds every candidate's context window")
+
+	// ErrContextLengthExceeded is recorded for a candidate whose provider
+	// rejected the request as too long for the model. Retryable, unlike the
+	// ErrInvalidRequest it would otherwise be: the next step of the ladder
+	// may be a model with a larger window. The account's health is left
+	// alone. ErrContextWindowExceeded is the router's own verdict, made
+	// before any call from the model catalog.
+	ErrContextLengthExceeded = errors.New("inferrouter: context length exceeded")
+
+	// ErrResponseFormatMismatch is recorded for a candidate whose answer did
+	// not satisfy ChatRequest.ResponseFormat (with Validate set). Retryable:
+	// the ladder moves to the next step, and the candidate's health is left
+	// alone — a model that drifted from a schema is not an outage.
+	ErrResponseFormatMismatch = errors.New("inferrouter: response does not match requested format")
+
+	// ErrHedgeLost is never returned to callers. It marks, in meter
+	// ResultEvents, an attempt the router cancelled because another attempt
+	// of the same hedged request answered first.
+	ErrHedgeLost = errors.New("inferrouter: attempt lost a hedged race")
+
 	// ErrNoEmbeddingProviders is returned by Router.Embed/EmbedBatch when no
 	// configured provider implements EmbeddingProvider for the requested model.
 	// Symmetric to ErrMultimodalUnavailable — a specific failure mode distinct
@@ -84,6 +128,34 @@ func (e *ErrPartialBatch) Error() string {
 
 func (e *ErrPartialBatch) Unwrap() error { return e.Cause }
 
+// RateLimitError is a 429 from a provider, carrying how long the provider asked
+// to wait. It matches ErrRateLimited under errors.Is, so existing checks keep
+// working; use errors.As to read RetryAfter.
+//
+// The router puts the (account, model) pair that returned it into a cooldown
+// for RetryAfter (or the router's default when the provider did not say) and
+// leaves its health alone: a rate limit is the provider working as intended.
+type RateLimitError struct {
+	// RetryAfter is the wait the provider asked for; zero when it gave none.
+	RetryAfter time.Duration
+
+	// Detail is the provider's message, for diagnostics.
+	Detail string
+}
+
+func (e *RateLimitError) Error() string {
+	msg := ErrRateLimited.Error()
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(" (retry after %s)", e.RetryAfter)
+	}
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+func (e *RateLimitError) Unwrap() error { return ErrRateLimited }
+
 // CandidateError records the error from a single candidate attempt.
 type CandidateError struct {
 	Provider  string
@@ -139,5 +211,34 @@ func IsRetryable(err error) bool {
 	return errors.Is(err, ErrRateLimited) ||
 		errors.Is(err, ErrProviderUnavailable) ||
 		errors.Is(err, ErrQuotaExceeded) ||
-		errors.Is(err, ErrRPMExceeded)
+		errors.Is(err, ErrRPMExceeded) ||
+		errors.Is(err, ErrTPMExceeded) ||
+		errors.Is(err, ErrConcurrencyExceeded) ||
+		errors.Is(err, ErrResponseFormatMismatch) ||
+		errors.Is(err, ErrContextLengthExceeded)
+}
+
+// contextLengthPhrases are what OpenAI, vLLM, llama.cpp, Gemini and Anthropic
+// say when a prompt does not fit, lower-cased.
+var contextLengthPhrases = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context window",
+	"exceeds the available context size",
+	"exceeds the maximum number of tokens",
+	"prompt is too long",
+	"input is too long",
+}
+
+// IsContextLengthMessage reports whether a provider's error text says the
+// request did not fit the model's context window. Providers use it to turn
+// such a 400 into ErrContextLengthExceeded.
+func IsContextLengthMessage(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, p := range contextLengthPhrases {
+		if strings.Contains(msg, p) {
+			return true
+		}
+	}
+	return false
 }
@@ -14,7 +14,8 @@ const (
 
 // EstimateTokens provides a rough token count estimate for messages.
 // Handles both legacy Content strings and multi-part messages including
-// image/audio/video; for media parts, byte-size heuristics are used.
+// image/audio/video; for media parts, byte-size heuristics are used. Tool call
+// arguments on assistant messages count as text.
 func EstimateTokens(messages []Message) int64 {
 	var total int64
 	for _, m := range messages {
@@ -25,6 +26,9 @@ func EstimateTokens(messages []Message) int64 {
 		} else {
 			total += int64(len(m.Content)) / charsPerTextToken
 		}
+		for _, tc := range m.ToolCalls {
+			total += int64(len(tc.Function.Name)+len(tc.Function.Arguments)) / charsPerTextToken
+		}
 		total += perMessageOverhead
 	}
 	total += perRequestOverhead
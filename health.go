@@ -1,6 +1,8 @@
 package inferrouter
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"time"
 )
@@ -10,6 +12,12 @@ type HealthConfig struct {
 	FailureThreshold int           // failures to trip circuit (default: 3)
 	FailureWindow    time.Duration // window for counting failures (default: 5min)
 	UnhealthyPeriod  time.Duration // cooldown before half-open (default: 30s)
+
+	// ProviderFailureThreshold is the number of distinct accounts of one
+	// provider that must fail within FailureWindow to trip the provider-wide
+	// circuit (default: 2; 0 disables it). Only failures that point at the
+	// provider count — see RecordProviderFailure.
+	ProviderFailureThreshold int
 }
 
 // DefaultHealthConfig returns the default circuit breaker settings.
@@ -18,14 +26,20 @@ func DefaultHealthConfig() HealthConfig {
 		FailureThreshold: 3,
 		FailureWindow:    5 * time.Minute,
 		UnhealthyPeriod:  30 * time.Second,
+
+		ProviderFailureThreshold: 2,
 	}
 }
 
-// HealthTracker tracks per-account health using a circuit breaker pattern.
+// HealthTracker tracks per-account health using a circuit breaker pattern,
+// plus a provider-wide circuit on top of it: when several accounts of the same
+// provider fail within the window, the whole provider is taken out of rotation
+// instead of burning one attempt per account on an outage.
 type HealthTracker struct {
-	cfg      HealthConfig
-	mu       sync.RWMutex
-	accounts map[string]*accountHealth
+	cfg       HealthConfig
+	mu        sync.RWMutex
+	accounts  map[string]*accountHealth
+	providers map[string]*providerHealth
 }
 
 type accountHealth struct {
@@ -34,6 +48,12 @@ type accountHealth struct {
 	unhealthyAt time.Time   // when state transitioned to unhealthy
 }
 
+type providerHealth struct {
+	state       HealthState
+	failures    map[string]time.Time // account ID → last provider-wide failure
+	unhealthyAt time.Time
+}
+
 // NewHealthTracker creates a new HealthTracker with default config.
 func NewHealthTracker() *HealthTracker {
 	return NewHealthTrackerWithConfig(DefaultHealthConfig())
@@ -42,8 +62,9 @@ func NewHealthTracker() *HealthTracker {
 // NewHealthTrackerWithConfig creates a new HealthTracker with custom config.
 func NewHealthTrackerWithConfig(cfg HealthConfig) *HealthTracker {
 	return &HealthTracker{
-		cfg:      cfg,
-		accounts: make(map[string]*accountHealth),
+		cfg:       cfg,
+		accounts:  make(map[string]*accountHealth),
+		providers: make(map[string]*providerHealth),
 	}
 }
 
@@ -109,6 +130,7 @@ func (h *HealthTracker) Reset() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	h.accounts = make(map[string]*accountHealth)
+	h.providers = make(map[string]*providerHealth)
 }
 
 // ResetAccount clears health state for a single account.
@@ -118,6 +140,93 @@ func (h *HealthTracker) ResetAccount(accountID string) {
 	delete(h.accounts, accountID)
 }
 
+// GetProviderHealth returns the provider-wide circuit state. Unhealthy means
+// every account of the provider is skipped; after UnhealthyPeriod it turns
+// half-open and requests are let through again as probes.
+func (h *HealthTracker) GetProviderHealth(provider string) HealthState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ph, ok := h.providers[provider]
+	if !ok {
+		return HealthHealthy
+	}
+	if ph.state == HealthUnhealthy && time.Since(ph.unhealthyAt) >= h.cfg.UnhealthyPeriod {
+		ph.state = HealthHalfOpen
+	}
+	return ph.state
+}
+
+// RecordProviderSuccess records that an account of the provider answered,
+// which closes the provider-wide circuit: the provider is up.
+func (h *HealthTracker) RecordProviderSuccess(provider string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.providers, provider)
+}
+
+// RecordProviderFailure records a failure of accountID that points at the
+// provider as a whole (an outage or a timeout, not a per-model 429). The
+// provider trips when ProviderFailureThreshold distinct accounts have failed
+// within FailureWindow; a failed probe while half-open trips it again at once.
+func (h *HealthTracker) RecordProviderFailure(provider, accountID string) {
+	if h.cfg.ProviderFailureThreshold <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ph, ok := h.providers[provider]
+	if !ok {
+		ph = &providerHealth{state: HealthHealthy, failures: make(map[string]time.Time)}
+		h.providers[provider] = ph
+	}
+	if ph.state == HealthUnhealthy {
+		return
+	}
+
+	now := time.Now()
+	if ph.state == HealthHalfOpen {
+		ph.state = HealthUnhealthy
+		ph.unhealthyAt = now
+		return
+	}
+
+	cutoff := now.Add(-h.cfg.FailureWindow)
+	for id, t := range ph.failures {
+		if !t.After(cutoff) {
+			delete(ph.failures, id)
+		}
+	}
+	ph.failures[accountID] = now
+
+	if len(ph.failures) >= h.cfg.ProviderFailureThreshold {
+		ph.state = HealthUnhealthy
+		ph.unhealthyAt = now
+		clear(ph.failures)
+	}
+}
+
+// ResetProvider clears the provider-wide circuit for a single provider.
+// Account-level state is left alone.
+func (h *HealthTracker) ResetProvider(provider string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.providers, provider)
+}
+
+// isProviderFault reports whether a failed attempt says something about the
+// provider as a whole: it was unreachable or answered 5xx, or the attempt ran
+// out its own time budget. Rate limits, auth and request errors are specific
+// to an account or model, and a cancelled caller says nothing at all.
+func isProviderFault(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return errors.Is(err, ErrProviderUnavailable) || errors.Is(err, context.DeadlineExceeded)
+}
+
 func (h *HealthTracker) getOrCreate(accountID string) *accountHealth {
 	ah, ok := h.accounts[accountID]
 	if !ok {
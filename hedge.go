@@ -0,0 +1,332 @@
+package inferrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// HedgeConfig enables hedged requests on ChatCompletion: when the attempt in
+// flight has not answered after a delay, the same request is fired at the next
+// candidate and whichever finishes first wins. Meant for slow gateway pools,
+// where one stuck node otherwise decides the caller's tail latency.
+//
+// At most two attempts run at once. The loser is cancelled; its reservation is
+// rolled back and its health left alone — losing a race is not a failure.
+// Hedging does not apply to ChatCompletionStream.
+type HedgeConfig struct {
+	// Delay is how long an attempt runs alone before it is hedged. With
+	// Percentile set it is the fallback for accounts with too few samples;
+	// zero then means "do not hedge those accounts yet".
+	Delay time.Duration
+
+	// Percentile, in (0, 1), derives the delay per account from its recent
+	// successful latencies: 0.9 hedges an attempt once it is slower than
+	// nine in ten of that account's recent answers.
+	Percentile float64
+
+	// MinSamples is how many successful latencies an account needs before
+	// Percentile applies to it (default 20).
+	MinSamples int
+}
+
+// hedgeWindow is how many recent latencies are kept per account.
+const hedgeWindow = 128
+
+// hedger decides hedge delays and keeps the latency history they come from.
+type hedger struct {
+	cfg HedgeConfig
+
+	mu      sync.Mutex
+	samples map[string]*latencyRing
+}
+
+type latencyRing struct {
+	buf  [hedgeWindow]time.Duration
+	n    int
+	next int
+}
+
+func newHedger(cfg HedgeConfig) *hedger {
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 20
+	}
+	return &hedger{cfg: cfg, samples: make(map[string]*latencyRing)}
+}
+
+// record adds a successful attempt's latency to the account's history.
+func (h *hedger) record(accountID string, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ring, ok := h.samples[accountID]
+	if !ok {
+		ring = &latencyRing{}
+		h.samples[accountID] = ring
+	}
+	ring.buf[ring.next] = d
+	ring.next = (ring.next + 1) % hedgeWindow
+	if ring.n < hedgeWindow {
+		ring.n++
+	}
+}
+
+// delay returns how long an attempt on accountID runs before it is hedged.
+// Zero means it is not hedged.
+func (h *hedger) delay(accountID string) time.Duration {
+	if h.cfg.Percentile <= 0 || h.cfg.Percentile >= 1 {
+		return h.cfg.Delay
+	}
+
+	h.mu.Lock()
+	ring, ok := h.samples[accountID]
+	if !ok || ring.n < h.cfg.MinSamples {
+		h.mu.Unlock()
+		return h.cfg.Delay
+	}
+	sorted := slices.Clone(ring.buf[:ring.n])
+	h.mu.Unlock()
+
+	slices.Sort(sorted)
+	idx := int(math.Ceil(h.cfg.Percentile*float64(len(sorted)))) - 1
+	return sorted[max(idx, 0)]
+}
+
+// hedgeKey marks the context of a hedge attempt so the settle functions can
+// flag its meter events.
+type hedgeKey struct{}
+
+func withHedge(ctx context.Context, hedge bool) context.Context {
+	if !hedge {
+		return ctx
+	}
+	return context.WithValue(ctx, hedgeKey{}, true)
+}
+
+func isHedge(ctx context.Context) bool {
+	hedge, _ := ctx.Value(hedgeKey{}).(bool)
+	return hedge
+}
+
+// hedgeResult is one attempt's outcome as reported back to the race. ctx is
+// the attempt's context, marked as a hedge where it is one and carrying the
+// attempt span, which is ended once the attempt settles. cut is set when the
+// attempt was cancelled before it returned, by the race or by the caller.
+type hedgeResult struct {
+	ctx      context.Context
+	span     Span
+	c        Candidate
+	grant    grant
+	attempt  int
+	hedge    bool
+	resp     ProviderResponse
+	err      error
+	duration time.Duration
+	cut      bool
+}
+
+// hedgedChatCompletion is ChatCompletion's walk down the ladder with hedging:
+// the same failover, but an attempt that outlives its hedge delay gets the
+// next candidate racing alongside it.
+func (r *Router) hedgedChatCompletion(ctx context.Context, req ChatRequest, needs routeNeeds, ordered []Candidate, estimatedTokens int64) (ChatResponse, error) {
+	// Buffered for every candidate, so an attempt nobody waits for any more
+	// never blocks its goroutine.
+	results := make(chan hedgeResult, len(ordered))
+	cancels := make(map[int]context.CancelFunc)
+	var tried []CandidateError
+	next, running := 0, 0
+
+	var timer *time.Timer
+	var hedgeC <-chan time.Time
+	disarm := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		timer, hedgeC = nil, nil
+	}
+	defer disarm()
+
+	// launch starts the next candidate that clears acquire. It reports false
+	// when the ladder is exhausted.
+	launch := func(hedge bool) bool {
+		for next < len(ordered) {
+			attempt, c := next, ordered[next]
+			next++
+
+			actx, aspan := r.startAttempt(withHedge(ctx, hedge), c.Provider.Name(), c.AccountID, c.Model, c.Free, attempt+1, estimatedTokens)
+			g, skip := r.acquire(actx, c, estimatedTokens)
+			if skip != nil {
+				endSpan(aspan, skip)
+				tried = append(tried, *skip)
+				continue
+			}
+
+			r.meter.OnRoute(RouteEvent{
+				Provider:    c.Provider.Name(),
+				AccountID:   c.AccountID,
+				Model:       c.Model,
+				Free:        c.Free,
+				AttemptNum:  attempt + 1,
+				EstimatedIn: estimatedTokens,
+				Hedge:       hedge,
+			})
+
+			var attemptCtx context.Context
+			var cancel context.CancelFunc
+			if budget := r.attemptBudget(c); budget > 0 {
+				attemptCtx, cancel = context.WithTimeout(actx, budget)
+			} else {
+				attemptCtx, cancel = context.WithCancel(actx)
+			}
+			cancels[attempt] = cancel
+			running++
+
+			go func() {
+				callCtx, callSpan := r.tracer.Start(attemptCtx, SpanProviderCall)
+				start := time.Now()
+				resp, err := c.Provider.ChatCompletion(callCtx, buildProviderRequest(c, req, false, needs.multimodal))
+				duration := time.Since(start)
+				cut := errors.Is(attemptCtx.Err(), context.Canceled)
+				endSpan(callSpan, err)
+				r.inflight.Release(c.AccountID, c.Model)
+				cancel()
+				r.rateLimiter.SyncFromProvider(actx, c.AccountID, c.Model, resp.RateLimits)
+				results <- hedgeResult{
+					ctx: actx, span: aspan,
+					c: c, grant: g, attempt: attempt, hedge: hedge,
+					resp: resp, err: err, duration: duration, cut: cut,
+				}
+			}()
+
+			if !hedge {
+				disarm()
+				if d := r.hedger.delay(c.AccountID); d > 0 && next < len(ordered) {
+					timer = time.NewTimer(d)
+					hedgeC = timer.C
+				}
+			}
+			return true
+		}
+		return false
+	}
+
+	// abandon cancels whatever is still running and settles it in the
+	// background: the caller does not wait for a loser to notice.
+	abandon := func(lost bool) {
+		for _, cancel := range cancels {
+			cancel()
+		}
+		pending := running
+		if pending == 0 {
+			return
+		}
+		go func() {
+			for range pending {
+				r.settleAbandoned(<-results, req.ResponseFormat, lost)
+			}
+		}()
+	}
+
+	if !launch(false) {
+		return ChatResponse{}, allFailedError(tried, len(ordered))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			abandon(false)
+			return ChatResponse{}, ctx.Err()
+
+		case <-hedgeC:
+			timer, hedgeC = nil, nil
+			launch(true)
+
+		case res := <-results:
+			running--
+			delete(cancels, res.attempt)
+
+			if res.err == nil {
+				rejectErr := checkResponseChoices(req.ResponseFormat, res.resp)
+				if rejectErr == nil {
+					abandon(true)
+					r.hedger.record(res.c.AccountID, res.duration)
+					r.settleSuccess(res.ctx, res.c, res.grant, res.resp.Usage, res.duration)
+					res.span.End()
+					return chatResponse(res.c, res.resp, next), nil
+				}
+				ce := r.settleRejected(res.ctx, res.c, res.grant, res.resp.Usage, res.duration, rejectErr)
+				endSpan(res.span, &ce)
+				tried = append(tried, ce)
+			} else {
+				fatal, ce := r.settleFailure(res.ctx, res.c, res.grant, res.err, res.duration, res.attempt)
+				endSpan(res.span, &ce)
+				if fatal != nil {
+					abandon(false)
+					return ChatResponse{}, fatal
+				}
+				tried = append(tried, ce)
+			}
+
+			if err := ctx.Err(); err != nil {
+				abandon(false)
+				return ChatResponse{}, err
+			}
+			// The other attempt, if any, carries on; with none left the
+			// next candidate becomes the new primary.
+			if running == 0 && !launch(false) {
+				return ChatResponse{}, allFailedError(tried, len(ordered))
+			}
+		}
+	}
+}
+
+// settleAbandoned settles an attempt the router stopped waiting for. One that
+// completed anyway was served and billed: it settles as a success, or as a
+// rejection when its answer breaks rf, as in the race itself. One cut short by
+// the cancel is rolled back without touching health: it did nothing wrong.
+// Any other failure reached the router before the cancel did and settles as
+// the failure it is. lost marks an attempt that lost the hedge race, as
+// opposed to one dropped because the caller went away.
+func (r *Router) settleAbandoned(res hedgeResult, rf *ResponseFormat, lost bool) {
+	// The caller has moved on; settling must not be cut short with it.
+	ctx := context.WithoutCancel(res.ctx)
+	switch {
+	case res.err == nil:
+		if rejectErr := checkResponseChoices(rf, res.resp); rejectErr != nil {
+			ce := r.settleRejected(ctx, res.c, res.grant, res.resp.Usage, res.duration, rejectErr)
+			endSpan(res.span, &ce)
+			return
+		}
+		r.settleSuccess(ctx, res.c, res.grant, res.resp.Usage, res.duration)
+		res.span.End()
+		return
+	case !res.cut && !errors.Is(res.err, context.Canceled):
+		_, ce := r.settleFailure(ctx, res.c, res.grant, res.err, res.duration, res.attempt)
+		endSpan(res.span, &ce)
+		return
+	}
+
+	resultErr := res.err
+	if lost {
+		resultErr = fmt.Errorf("%w: %v", ErrHedgeLost, res.err)
+	}
+	r.rateLimiter.AdjustTokens(ctx, res.c.AccountID, res.c.Model, -res.grant.tokens)
+	if rollbackErr := r.quotaStore.Rollback(ctx, res.grant.Reservation); rollbackErr != nil {
+		resultErr = fmt.Errorf("%w (rollback failed: %v)", resultErr, rollbackErr)
+	}
+
+	r.meter.OnResult(ResultEvent{
+		Provider:  res.c.Provider.Name(),
+		AccountID: res.c.AccountID,
+		Model:     res.c.Model,
+		Free:      res.c.Free,
+		Success:   false,
+		Duration:  res.duration,
+		Error:     resultErr,
+		Hedge:     res.hedge,
+	})
+	endSpan(res.span, resultErr)
+}
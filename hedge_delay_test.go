@@ -0,0 +1,38 @@
+package inferrouter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHedgerDelay(t *testing.T) {
+	h := newHedger(HedgeConfig{Delay: time.Second, Percentile: 0.9, MinSamples: 10})
+
+	if got := h.delay("acc"); got != time.Second {
+		t.Errorf("no samples: delay = %v, want the fixed fallback", got)
+	}
+
+	for i := 1; i <= 10; i++ {
+		h.record("acc", time.Duration(i)*100*time.Millisecond)
+	}
+	if got := h.delay("acc"); got != 900*time.Millisecond {
+		t.Errorf("p90 of 100ms..1s = %v, want 900ms", got)
+	}
+	if got := h.delay("other"); got != time.Second {
+		t.Errorf("other account: delay = %v, want the fixed fallback", got)
+	}
+
+	// The window forgets old samples.
+	for range hedgeWindow {
+		h.record("acc", 50*time.Millisecond)
+	}
+	if got := h.delay("acc"); got != 50*time.Millisecond {
+		t.Errorf("after window turnover: delay = %v, want 50ms", got)
+	}
+
+	fixed := newHedger(HedgeConfig{Delay: 200 * time.Millisecond})
+	fixed.record("acc", time.Hour)
+	if got := fixed.delay("acc"); got != 200*time.Millisecond {
+		t.Errorf("fixed delay = %v, want 200ms", got)
+	}
+}
@@ -0,0 +1,82 @@
+package inferrouter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// A loser's own fault counts against its account; one caused by the cancel
+// does not.
+func TestSettleAbandonedHealth(t *testing.T) {
+	cases := []struct {
+		name string
+		res  hedgeResult
+		want HealthState
+	}{
+		{"fault before the cancel", hedgeResult{err: ErrProviderUnavailable}, HealthUnhealthy},
+		{"fault after the cancel", hedgeResult{err: ErrProviderUnavailable, cut: true}, HealthHealthy},
+		{"cancelled", hedgeResult{err: context.Canceled}, HealthHealthy},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &testProvider{name: "p"}
+			cfg := DefaultHealthConfig()
+			cfg.FailureThreshold = 1
+			r, err := NewRouter(inflightTestConfig("p"), []Provider{p},
+				WithHealthTracker(NewHealthTrackerWithConfig(cfg)))
+			if err != nil {
+				t.Fatalf("NewRouter: %v", err)
+			}
+
+			res := tc.res
+			res.ctx = context.Background()
+			res.span = noopSpan{}
+			res.c = Candidate{Provider: p, AccountID: "acc-1", Model: "m"}
+			res.duration = time.Millisecond
+			r.settleAbandoned(res, nil, true)
+
+			if got := r.health.GetHealth("acc-1"); got != tc.want {
+				t.Errorf("health = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// recordingMeter keeps the last result it saw.
+type recordingMeter struct {
+	noopMeter
+	last ResultEvent
+}
+
+func (m *recordingMeter) OnResult(e ResultEvent) { m.last = e }
+
+// A loser that completed anyway is held to the response format like the
+// winner.
+func TestSettleAbandonedChecksResponseFormat(t *testing.T) {
+	p := &testProvider{name: "p"}
+	meter := &recordingMeter{}
+	r, err := NewRouter(inflightTestConfig("p"), []Provider{p}, WithMeter(meter))
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	rf := &ResponseFormat{Type: ResponseFormatJSON, Validate: true}
+
+	res := hedgeResult{
+		ctx:  context.Background(),
+		span: noopSpan{},
+		c:    Candidate{Provider: p, AccountID: "acc-1", Model: "m"},
+		resp: ProviderResponse{Content: "not json", Usage: Usage{TotalTokens: 3}},
+	}
+	r.settleAbandoned(res, rf, true)
+	if meter.last.Success || !errors.Is(meter.last.Error, ErrResponseFormatMismatch) {
+		t.Errorf("result = %+v, want a response format mismatch", meter.last)
+	}
+
+	res.resp.Content = `{"ok":true}`
+	r.settleAbandoned(res, rf, true)
+	if !meter.last.Success {
+		t.Errorf("result = %+v, want success", meter.last)
+	}
+}
@@ -0,0 +1,231 @@
+package inferrouter_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/provider/mock"
+	"github.com/ineyio/inferrouter/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventLog is a Meter that keeps every event; hedged attempts settle
+// concurrently, so it locks.
+type eventLog struct {
+	mu      sync.Mutex
+	routes  []ir.RouteEvent
+	results []ir.ResultEvent
+}
+
+func (m *eventLog) OnRoute(e ir.RouteEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes = append(m.routes, e)
+}
+
+func (m *eventLog) OnResult(e ir.ResultEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results = append(m.results, e)
+}
+
+func (m *eventLog) snapshot() ([]ir.RouteEvent, []ir.ResultEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ir.RouteEvent(nil), m.routes...), append([]ir.ResultEvent(nil), m.results...)
+}
+
+func (m *eventLog) resultFor(provider string) (ir.ResultEvent, bool) {
+	_, results := m.snapshot()
+	for _, e := range results {
+		if e.Provider == provider {
+			return e, true
+		}
+	}
+	return ir.ResultEvent{}, false
+}
+
+// slowStep answers after d, or gives up with the context.
+func slowStep(log *attemptLog, name string, d time.Duration) *mock.Provider {
+	return mock.New(
+		mock.WithName(name),
+		mock.WithModels("ladder-model"),
+		mock.WithLatency(d),
+		mock.WithResponseFunc(func(ir.ProviderRequest) (ir.ProviderResponse, error) {
+			log.record(name)
+			return ir.ProviderResponse{
+				Content: "from " + name,
+				Model:   "ladder-model",
+				Usage:   ir.Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2},
+			}, nil
+		}),
+	)
+}
+
+// A slow first step is hedged after the delay; the faster second step wins
+// and the first is cancelled and reported as the loser.
+func TestHedging_SlowPrimaryLoses(t *testing.T) {
+	log := &attemptLog{}
+	meter := &eventLog{}
+	cfg := ladderConfig("stuck", "quick")
+	r, err := ir.NewRouter(cfg, []ir.Provider{
+		slowStep(log, "stuck", 5*time.Second),
+		slowStep(log, "quick", 0),
+	},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithMeter(meter),
+		ir.WithHedging(ir.HedgeConfig{Delay: 20 * time.Millisecond}))
+	require.NoError(t, err)
+
+	start := time.Now()
+	resp, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Model:    "ladder",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), time.Second, "the hedge must not wait for the stuck step")
+	assert.Equal(t, "quick", resp.Routing.Provider)
+	assert.Equal(t, "from quick", resp.Choices[0].Message.Content)
+	assert.Equal(t, 2, resp.Routing.Attempts)
+
+	routes, _ := meter.snapshot()
+	require.Len(t, routes, 2)
+	assert.False(t, routes[0].Hedge)
+	assert.True(t, routes[1].Hedge, "the second attempt is marked as a hedge")
+
+	won, ok := meter.resultFor("quick")
+	require.True(t, ok)
+	assert.True(t, won.Success)
+	assert.True(t, won.Hedge)
+
+	require.Eventually(t, func() bool {
+		_, ok := meter.resultFor("stuck")
+		return ok
+	}, time.Second, 5*time.Millisecond, "the cancelled loser is settled")
+	lost, _ := meter.resultFor("stuck")
+	assert.False(t, lost.Success)
+	assert.False(t, lost.Hedge)
+	assert.True(t, errors.Is(lost.Error, ir.ErrHedgeLost), "got %v", lost.Error)
+}
+
+// deafStep ignores cancellation: it answers with resp or err after d, as a
+// backend that has already committed to the work does.
+func deafStep(name string, d time.Duration, resp ir.ProviderResponse, err error) *mock.Provider {
+	return mock.New(
+		mock.WithName(name),
+		mock.WithModels("ladder-model"),
+		mock.WithResponseFunc(func(ir.ProviderRequest) (ir.ProviderResponse, error) {
+			time.Sleep(d)
+			return resp, err
+		}),
+	)
+}
+
+// A loser that answers anyway is checked like the winner: an answer that
+// breaks the response format is not settled as a success.
+func TestHedging_FinishedLoserIsValidated(t *testing.T) {
+	log := &attemptLog{}
+	meter := &eventLog{}
+	stuck := deafStep("stuck", 100*time.Millisecond, ir.ProviderResponse{
+		Content: `{"city":"Paris"}`,
+		Model:   "ladder-model",
+		Usage:   ir.Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2},
+	}, nil)
+	r, err := ir.NewRouter(ladderConfig("stuck", "quick"), []ir.Provider{
+		stuck,
+		answeringStep(log, "quick", `{"city":"Paris","temp":21.5}`),
+	},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithMeter(meter),
+		ir.WithHedging(ir.HedgeConfig{Delay: 20 * time.Millisecond}))
+	require.NoError(t, err)
+
+	resp, err := r.ChatCompletion(context.Background(), schemaRequest(true))
+	require.NoError(t, err)
+	assert.Equal(t, "quick", resp.Routing.Provider)
+
+	require.Eventually(t, func() bool {
+		_, ok := meter.resultFor("stuck")
+		return ok
+	}, time.Second, 5*time.Millisecond, "the loser is settled")
+	lost, _ := meter.resultFor("stuck")
+	assert.False(t, lost.Success)
+	assert.ErrorIs(t, lost.Error, ir.ErrResponseFormatMismatch)
+	assert.Equal(t, int64(2), lost.Usage.TotalTokens, "the billed tokens are still reported")
+}
+
+// An attempt that answers within the delay is never hedged.
+func TestHedging_FastPrimaryNotHedged(t *testing.T) {
+	log := &attemptLog{}
+	meter := &eventLog{}
+	cfg := ladderConfig("quick", "spare")
+	r, err := ir.NewRouter(cfg, []ir.Provider{
+		slowStep(log, "quick", 0),
+		slowStep(log, "spare", 0),
+	},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithMeter(meter),
+		ir.WithHedging(ir.HedgeConfig{Delay: time.Second}))
+	require.NoError(t, err)
+
+	resp, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Model:    "ladder",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "quick", resp.Routing.Provider)
+	assert.Equal(t, []string{"quick"}, log.snapshot())
+	routes, _ := meter.snapshot()
+	assert.Len(t, routes, 1)
+}
+
+// Failover still works with hedging on: a failing step hands over to the
+// next one immediately, without waiting for the hedge delay.
+func TestHedging_FailureFailsOver(t *testing.T) {
+	log := &attemptLog{}
+	cfg := ladderConfig("broken", "works")
+	r, err := ir.NewRouter(cfg, []ir.Provider{
+		failingStep(log, "broken"),
+		servingStep(log, "works"),
+	},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithHedging(ir.HedgeConfig{Delay: time.Minute}))
+	require.NoError(t, err)
+
+	start := time.Now()
+	resp, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Model:    "ladder",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, "works", resp.Routing.Provider)
+	assert.Equal(t, []string{"broken", "works"}, log.snapshot())
+}
+
+// Hedging everything into failure still ends in ErrAllFailed.
+func TestHedging_AllFail(t *testing.T) {
+	log := &attemptLog{}
+	cfg := ladderConfig("one", "two")
+	r, err := ir.NewRouter(cfg, []ir.Provider{
+		failingStep(log, "one"),
+		failingStep(log, "two"),
+	},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithHedging(ir.HedgeConfig{Delay: time.Millisecond}))
+	require.NoError(t, err)
+
+	_, err = r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Model:    "ladder",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	assert.ErrorIs(t, err, ir.ErrAllFailed)
+}
@@ -10,11 +10,12 @@ import (
 // strongest signal for spreading concurrent requests across a pool of
 // gateways — see policy.LeastBusyPolicy.
 //
-// Best-effort by design: counters are process-local and advisory. They
-// influence candidate ordering but never block a request, so the tracker
-// is fail-open and needs no persistence.
+// Counters are process-local and need no persistence. By themselves they only
+// influence candidate ordering; the one place they gate a request is
+// Limits.MaxConcurrency, which the router enforces through Acquire.
 type InflightTracker struct {
 	counters sync.Map // accountID → *atomic.Int64
+	models   sync.Map // "accountID:model" → *atomic.Int64
 }
 
 // NewInflightTracker creates an empty tracker.
@@ -23,10 +24,14 @@ func NewInflightTracker() *InflightTracker {
 }
 
 func (t *InflightTracker) counter(accountID string) *atomic.Int64 {
-	if c, ok := t.counters.Load(accountID); ok {
+	return loadCounter(&t.counters, accountID)
+}
+
+func loadCounter(m *sync.Map, key string) *atomic.Int64 {
+	if c, ok := m.Load(key); ok {
 		return c.(*atomic.Int64)
 	}
-	c, _ := t.counters.LoadOrStore(accountID, new(atomic.Int64))
+	c, _ := m.LoadOrStore(key, new(atomic.Int64))
 	return c.(*atomic.Int64)
 }
 
@@ -44,3 +49,34 @@ func (t *InflightTracker) Dec(accountID string) {
 func (t *InflightTracker) Get(accountID string) int64 {
 	return t.counter(accountID).Load()
 }
+
+// Acquire registers the start of a request for the (account, model) pair
+// unless limit requests for that pair are already in flight, in which case it
+// reports false and counts nothing. A limit of zero or less never refuses.
+// Every successful Acquire must be paired with Release.
+func (t *InflightTracker) Acquire(accountID, model string, limit int) bool {
+	mc := loadCounter(&t.models, accountID+":"+model)
+	for {
+		n := mc.Load()
+		if limit > 0 && n >= int64(limit) {
+			return false
+		}
+		if mc.CompareAndSwap(n, n+1) {
+			break
+		}
+	}
+	t.counter(accountID).Add(1)
+	return true
+}
+
+// Release registers the end of a request started with Acquire.
+func (t *InflightTracker) Release(accountID, model string) {
+	loadCounter(&t.models, accountID+":"+model).Add(-1)
+	t.counter(accountID).Add(-1)
+}
+
+// GetModel returns the in-flight count for the (account, model) pair, as
+// counted by Acquire.
+func (t *InflightTracker) GetModel(accountID, model string) int64 {
+	return loadCounter(&t.models, accountID+":"+model).Load()
+}
@@ -107,6 +107,78 @@ func TestInflightTrackerConcurrent(t *testing.T) {
 	}
 }
 
+func TestInflightTrackerAcquireLimit(t *testing.T) {
+	tr := NewInflightTracker()
+	if !tr.Acquire("a", "m", 2) || !tr.Acquire("a", "m", 2) {
+		t.Fatal("first two Acquire calls should succeed")
+	}
+	if tr.Acquire("a", "m", 2) {
+		t.Error("third Acquire should be refused at limit 2")
+	}
+	if !tr.Acquire("a", "other", 2) {
+		t.Error("another model on the account has its own limit")
+	}
+	if got := tr.Get("a"); got != 3 {
+		t.Errorf("account counter = %d, want 3", got)
+	}
+
+	tr.Release("a", "m")
+	if got := tr.GetModel("a", "m"); got != 1 {
+		t.Errorf("model counter after Release = %d, want 1", got)
+	}
+	if !tr.Acquire("a", "m", 2) {
+		t.Error("Acquire after Release should succeed")
+	}
+	if !tr.Acquire("a", "m", 0) {
+		t.Error("limit 0 is unlimited")
+	}
+}
+
+func TestRouterMaxConcurrencySkipsBusyAccount(t *testing.T) {
+	p := &blockingProvider{
+		name:    "slow",
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	cfg := inflightTestConfig("slow")
+	cfg.Accounts[0].ModelLimits = map[string]Limits{"m": {MaxConcurrency: 1}}
+	second := cfg.Accounts[0]
+	second.ID = "acc-2"
+	second.ModelLimits = nil
+	cfg.Accounts = append(cfg.Accounts, second)
+
+	r, err := NewRouter(cfg, []Provider{p})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	done := make(chan ChatResponse, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			resp, err := r.ChatCompletion(context.Background(), inflightTestRequest())
+			if err != nil {
+				t.Errorf("ChatCompletion: %v", err)
+			}
+			done <- resp
+		}()
+		<-p.started
+	}
+
+	if got := r.inflight.Get("acc-1"); got != 1 {
+		t.Errorf("acc-1 in flight = %d, want 1", got)
+	}
+	if got := r.inflight.Get("acc-2"); got != 1 {
+		t.Errorf("acc-2 in flight = %d, want 1 (acc-1 was at max_concurrency)", got)
+	}
+
+	close(p.release)
+	<-done
+	<-done
+	if got := r.inflight.GetModel("acc-1", "m"); got != 0 {
+		t.Errorf("acc-1 slot not released: %d", got)
+	}
+}
+
 func TestRouterTracksInflightDuringChat(t *testing.T) {
 	p := &blockingProvider{
 		name:    "slow",
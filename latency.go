@@ -0,0 +1,121 @@
+package inferrouter
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultLatencyAlpha is the EWMA smoothing factor used by NewLatencyTracker:
+// each new sample carries 20% of the weight, so the average follows a
+// provider that got slower within a handful of requests without jumping on a
+// single outlier.
+const DefaultLatencyAlpha = 0.2
+
+// LatencyStats is the observed speed of an account, or of one model on it.
+type LatencyStats struct {
+	// Latency is the EWMA of a full successful ChatCompletion. Streams do
+	// not feed it: their duration is mostly generation length, which would
+	// make an account that serves long streams look slow.
+	Latency time.Duration
+
+	// TTFT is the EWMA of time to first token on streams, from the start of
+	// the attempt. Zero until a stream has been served.
+	TTFT time.Duration
+
+	// Samples and TTFTSamples count the observations behind each average.
+	// An account with neither has not been measured yet.
+	Samples     int64
+	TTFTSamples int64
+
+	// Updated is when the last sample arrived.
+	Updated time.Time
+}
+
+// LatencyTracker keeps EWMA latency per account and per (account, model).
+// The router records into it on every settled attempt that was served, and
+// on attempts that ran out their time budget (a step that hangs must not
+// keep looking fast); failures that return early carry no latency signal and
+// are left to HealthTracker.
+//
+// Like InflightTracker it is process-local and advisory: it only feeds
+// Candidate.Latency for policies such as policy.LatencyPolicy.
+type LatencyTracker struct {
+	alpha float64
+
+	mu    sync.RWMutex
+	stats map[latencyKey]*LatencyStats
+}
+
+// latencyKey identifies a series. An empty model is the account-wide series.
+type latencyKey struct {
+	accountID string
+	model     string
+}
+
+// NewLatencyTracker creates a tracker with DefaultLatencyAlpha.
+func NewLatencyTracker() *LatencyTracker {
+	return NewLatencyTrackerWithAlpha(DefaultLatencyAlpha)
+}
+
+// NewLatencyTrackerWithAlpha creates a tracker with a custom smoothing factor
+// in (0, 1]; higher values weigh recent samples more. Out-of-range values
+// fall back to DefaultLatencyAlpha.
+func NewLatencyTrackerWithAlpha(alpha float64) *LatencyTracker {
+	if alpha <= 0 || alpha > 1 {
+		alpha = DefaultLatencyAlpha
+	}
+	return &LatencyTracker{alpha: alpha, stats: make(map[latencyKey]*LatencyStats)}
+}
+
+// Record adds one observation for the account and model. A zero latency or
+// ttft leaves that average alone: streams record only ttft, unary calls only
+// latency.
+func (t *LatencyTracker) Record(accountID, model string, latency, ttft time.Duration) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.update(latencyKey{accountID: accountID}, latency, ttft, now)
+	if model != "" {
+		t.update(latencyKey{accountID: accountID, model: model}, latency, ttft, now)
+	}
+}
+
+func (t *LatencyTracker) update(key latencyKey, latency, ttft time.Duration, now time.Time) {
+	s, ok := t.stats[key]
+	if !ok {
+		s = &LatencyStats{}
+		t.stats[key] = s
+	}
+	if latency > 0 {
+		s.Latency = t.ewma(s.Latency, latency, s.Samples)
+		s.Samples++
+	}
+	if ttft > 0 {
+		s.TTFT = t.ewma(s.TTFT, ttft, s.TTFTSamples)
+		s.TTFTSamples++
+	}
+	s.Updated = now
+}
+
+// ewma folds sample into avg. The first sample is taken as-is rather than
+// blended with a zero that was never observed.
+func (t *LatencyTracker) ewma(avg, sample time.Duration, samples int64) time.Duration {
+	if samples == 0 {
+		return sample
+	}
+	return time.Duration(t.alpha*float64(sample) + (1-t.alpha)*float64(avg))
+}
+
+// Get returns the stats for the model on the account, or the account-wide
+// stats when that model has not been measured there yet.
+func (t *LatencyTracker) Get(accountID, model string) LatencyStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if s, ok := t.stats[latencyKey{accountID: accountID, model: model}]; ok && model != "" {
+		return *s
+	}
+	if s, ok := t.stats[latencyKey{accountID: accountID}]; ok {
+		return *s
+	}
+	return LatencyStats{}
+}
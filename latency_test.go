@@ -0,0 +1,129 @@
+package inferrouter
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLatencyTrackerEWMA(t *testing.T) {
+	tr := NewLatencyTrackerWithAlpha(0.5)
+	if got := tr.Get("a", "m"); got.Samples != 0 {
+		t.Fatalf("fresh stats = %+v, want zero", got)
+	}
+
+	tr.Record("a", "m", 100*time.Millisecond, 0)
+	if got := tr.Get("a", "m"); got.Latency != 100*time.Millisecond || got.Samples != 1 {
+		t.Errorf("first sample = %+v, want it taken as-is", got)
+	}
+
+	tr.Record("a", "m", 300*time.Millisecond, 40*time.Millisecond)
+	got := tr.Get("a", "m")
+	if got.Latency != 200*time.Millisecond || got.Samples != 2 {
+		t.Errorf("after second sample = %+v, want 200ms over 2 samples", got)
+	}
+	if got.TTFT != 40*time.Millisecond || got.TTFTSamples != 1 {
+		t.Errorf("ttft = %v over %d, want 40ms over 1", got.TTFT, got.TTFTSamples)
+	}
+	if got.Updated.IsZero() {
+		t.Error("Updated not set")
+	}
+}
+
+func TestLatencyTrackerFallsBackToAccount(t *testing.T) {
+	tr := NewLatencyTracker()
+	tr.Record("a", "fast-model", 50*time.Millisecond, 0)
+
+	if got := tr.Get("a", "other-model"); got.Latency != 50*time.Millisecond {
+		t.Errorf("unmeasured model = %+v, want the account-wide stats", got)
+	}
+	if got := tr.Get("b", "fast-model"); got.Samples != 0 {
+		t.Errorf("other account = %+v, want zero", got)
+	}
+}
+
+func TestRouterRecordsLatencyOnSuccess(t *testing.T) {
+	p := &blockingProvider{name: "timed"}
+	r, err := NewRouter(inflightTestConfig("timed"), []Provider{p})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	if _, err := r.ChatCompletion(context.Background(), inflightTestRequest()); err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if got := r.latency.Get("acc-1", "m"); got.Samples != 1 {
+		t.Errorf("latency samples after success = %d, want 1", got.Samples)
+	}
+
+	// Candidates carry the stats to policies.
+	cands, err := r.prepareRoute(context.Background(), RouteContext{Model: "m"}, routeNeeds{})
+	if err != nil {
+		t.Fatalf("prepareRoute: %v", err)
+	}
+	if cands[0].Latency.Samples != 1 {
+		t.Errorf("candidate latency = %+v, want one sample", cands[0].Latency)
+	}
+}
+
+func TestRouterSkipsLatencyOnFastFailure(t *testing.T) {
+	p := &blockingProvider{name: "broken", err: ErrProviderUnavailable}
+	r, err := NewRouter(inflightTestConfig("broken"), []Provider{p})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	_, _ = r.ChatCompletion(context.Background(), inflightTestRequest())
+	if got := r.latency.Get("acc-1", "m"); got.Samples != 0 {
+		t.Errorf("a quick error is not a latency sample, got %+v", got)
+	}
+}
+
+// contentStream delivers one content chunk, then ends.
+type contentStream struct{ sent bool }
+
+func (s *contentStream) Next() (StreamChunk, error) {
+	if s.sent {
+		return StreamChunk{}, io.EOF
+	}
+	s.sent = true
+	time.Sleep(5 * time.Millisecond)
+	return StreamChunk{Choices: []StreamDelta{{Delta: Delta{Content: "hi"}}}}, nil
+}
+func (s *contentStream) Close() error { return nil }
+
+type contentStreamProvider struct{ blockingProvider }
+
+func (p *contentStreamProvider) ChatCompletionStream(context.Context, ProviderRequest) (ProviderStream, error) {
+	return &contentStream{}, nil
+}
+
+func TestRouterRecordsStreamTTFT(t *testing.T) {
+	p := &contentStreamProvider{blockingProvider{name: "streamy"}}
+	r, err := NewRouter(inflightTestConfig("streamy"), []Provider{p})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	stream, err := r.ChatCompletionStream(context.Background(), inflightTestRequest())
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	for {
+		if _, err := stream.Next(); err != nil {
+			break
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got := r.latency.Get("acc-1", "m")
+	if got.Samples != 0 || got.TTFTSamples != 1 {
+		t.Fatalf("stats = %+v, want one TTFT sample and no full-latency one", got)
+	}
+	if got.TTFT < 5*time.Millisecond {
+		t.Errorf("ttft = %v", got.TTFT)
+	}
+}
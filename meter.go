@@ -19,6 +19,9 @@ type RouteEvent struct {
 	Free        bool
 	AttemptNum  int
 	EstimatedIn int64
+
+	// Hedge marks an attempt fired alongside a slow one (see HedgeConfig).
+	Hedge bool
 }
 
 // ResultEvent describes the outcome of a provider call.
@@ -32,4 +35,8 @@ type ResultEvent struct {
 	Usage      Usage
 	Error      error
 	DollarCost float64 // actual dollar cost for this request
+
+	// Hedge marks the result of a hedge attempt. The attempt that lost a
+	// hedged race, hedge or not, reports an Error wrapping ErrHedgeLost.
+	Hedge bool
 }
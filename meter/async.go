@@ -0,0 +1,103 @@
+package meter
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/ineyio/inferrouter"
+)
+
+// AsyncMeter hands events to a background goroutine, so a slow meter (one
+// that writes to a remote sink, say) never holds up the router: the router
+// meters from the request path, including settleSuccess and
+// RouterStream.Close. When the buffer is full, events are dropped and counted
+// rather than waited for.
+//
+// Call Close on shutdown to deliver what is still buffered.
+type AsyncMeter struct {
+	next   inferrouter.Meter
+	events chan asyncEvent
+	done   chan struct{}
+
+	mu     sync.RWMutex // guards closed against sends on a closed channel
+	closed bool
+
+	dropped atomic.Int64
+}
+
+// asyncEvent is one buffered event; exactly one field is set.
+type asyncEvent struct {
+	route  *inferrouter.RouteEvent
+	result *inferrouter.ResultEvent
+}
+
+var _ inferrouter.Meter = (*AsyncMeter)(nil)
+
+// DefaultAsyncBuffer is the buffer size NewAsyncMeter uses for size <= 0.
+const DefaultAsyncBuffer = 1024
+
+// NewAsyncMeter creates an AsyncMeter buffering up to size events for next,
+// and starts its goroutine.
+func NewAsyncMeter(next inferrouter.Meter, size int) *AsyncMeter {
+	if size <= 0 {
+		size = DefaultAsyncBuffer
+	}
+	m := &AsyncMeter{
+		next:   next,
+		events: make(chan asyncEvent, size),
+		done:   make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+func (m *AsyncMeter) run() {
+	defer close(m.done)
+	for e := range m.events {
+		if e.route != nil {
+			m.next.OnRoute(*e.route)
+		} else {
+			m.next.OnResult(*e.result)
+		}
+	}
+}
+
+func (m *AsyncMeter) OnRoute(e inferrouter.RouteEvent) {
+	m.enqueue(asyncEvent{route: &e})
+}
+
+func (m *AsyncMeter) OnResult(e inferrouter.ResultEvent) {
+	m.enqueue(asyncEvent{result: &e})
+}
+
+func (m *AsyncMeter) enqueue(e asyncEvent) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		m.dropped.Add(1)
+		return
+	}
+	select {
+	case m.events <- e:
+	default:
+		m.dropped.Add(1)
+	}
+}
+
+// Dropped returns how many events were discarded because the buffer was full
+// or the meter closed.
+func (m *AsyncMeter) Dropped() int64 {
+	return m.dropped.Load()
+}
+
+// Close stops accepting events and waits until the buffered ones have been
+// delivered. Events that arrive after Close are dropped.
+func (m *AsyncMeter) Close() {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.events)
+	}
+	m.mu.Unlock()
+	<-m.done
+}
@@ -0,0 +1,44 @@
+package meter
+
+import (
+	"slices"
+
+	"github.com/ineyio/inferrouter"
+)
+
+// FilterMeter passes on the events of the (provider, account) pairs keep
+// accepts and drops the rest.
+type FilterMeter struct {
+	next inferrouter.Meter
+	keep func(provider, accountID string) bool
+}
+
+var _ inferrouter.Meter = (*FilterMeter)(nil)
+
+// NewFilterMeter creates a FilterMeter. ForProviders and ForAccounts build
+// the common predicates.
+func NewFilterMeter(next inferrouter.Meter, keep func(provider, accountID string) bool) *FilterMeter {
+	return &FilterMeter{next: next, keep: keep}
+}
+
+// ForProviders accepts the events of the named providers.
+func ForProviders(names ...string) func(provider, accountID string) bool {
+	return func(provider, _ string) bool { return slices.Contains(names, provider) }
+}
+
+// ForAccounts accepts the events of the given accounts.
+func ForAccounts(ids ...string) func(provider, accountID string) bool {
+	return func(_, accountID string) bool { return slices.Contains(ids, accountID) }
+}
+
+func (m *FilterMeter) OnRoute(e inferrouter.RouteEvent) {
+	if m.keep(e.Provider, e.AccountID) {
+		m.next.OnRoute(e)
+	}
+}
+
+func (m *FilterMeter) OnResult(e inferrouter.ResultEvent) {
+	if m.keep(e.Provider, e.AccountID) {
+		m.next.OnResult(e)
+	}
+}
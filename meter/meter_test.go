@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log/slog"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -224,3 +225,173 @@ func TestLogMeterOnResultErrorFields(t *testing.T) {
 		}
 	}
 }
+
+// recordingMeter keeps every event it receives.
+type recordingMeter struct {
+	mu      sync.Mutex
+	routes  []ir.RouteEvent
+	results []ir.ResultEvent
+}
+
+func (m *recordingMeter) OnRoute(e ir.RouteEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes = append(m.routes, e)
+}
+
+func (m *recordingMeter) OnResult(e ir.ResultEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results = append(m.results, e)
+}
+
+func (m *recordingMeter) counts() (routes, results int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.routes), len(m.results)
+}
+
+func TestMultiMeterFansOut(t *testing.T) {
+	a, b := &recordingMeter{}, &recordingMeter{}
+	m := NewMultiMeter(a, nil, b)
+
+	m.OnRoute(ir.RouteEvent{Provider: "p"})
+	m.OnResult(ir.ResultEvent{Provider: "p", Success: true})
+
+	for name, rec := range map[string]*recordingMeter{"a": a, "b": b} {
+		if routes, results := rec.counts(); routes != 1 || results != 1 {
+			t.Errorf("meter %s got %d routes, %d results; want 1, 1", name, routes, results)
+		}
+	}
+}
+
+func TestSampledMeterKeepsFailures(t *testing.T) {
+	rec := &recordingMeter{}
+	m := NewSampledMeter(rec, 0)
+
+	m.OnRoute(ir.RouteEvent{Provider: "p"})
+	m.OnResult(ir.ResultEvent{Provider: "p", Success: true})
+	m.OnResult(ir.ResultEvent{Provider: "p", Success: false, Error: errors.New("x")})
+
+	routes, results := rec.counts()
+	if routes != 1 {
+		t.Errorf("routes = %d, want 1 (not sampled by default)", routes)
+	}
+	if results != 1 || rec.results[0].Success {
+		t.Errorf("results = %+v, want only the failure", rec.results)
+	}
+}
+
+func TestSampledMeterRate(t *testing.T) {
+	rec := &recordingMeter{}
+	m := NewSampledMeter(rec, 0.25)
+	m.SampleRoutes = true
+
+	const n = 10000
+	for range n {
+		m.OnRoute(ir.RouteEvent{Provider: "p"})
+		m.OnResult(ir.ResultEvent{Provider: "p", Success: true})
+	}
+	routes, results := rec.counts()
+	for name, got := range map[string]int{"routes": routes, "results": results} {
+		if got < n/5 || got > n*3/10 {
+			t.Errorf("%s passed = %d of %d, want about a quarter", name, got, n)
+		}
+	}
+
+	all := &recordingMeter{}
+	full := NewSampledMeter(all, 2) // clamped to 1
+	full.OnResult(ir.ResultEvent{Success: true})
+	if _, results := all.counts(); results != 1 {
+		t.Errorf("rate above 1 dropped a success")
+	}
+}
+
+func TestFilterMeter(t *testing.T) {
+	rec := &recordingMeter{}
+	m := NewFilterMeter(rec, ForProviders("gemini"))
+
+	m.OnRoute(ir.RouteEvent{Provider: "gemini", AccountID: "g1"})
+	m.OnRoute(ir.RouteEvent{Provider: "openai", AccountID: "o1"})
+	m.OnResult(ir.ResultEvent{Provider: "openai", AccountID: "o1"})
+
+	if routes, results := rec.counts(); routes != 1 || results != 0 {
+		t.Errorf("got %d routes, %d results; want 1, 0", routes, results)
+	}
+
+	byAccount := &recordingMeter{}
+	m = NewFilterMeter(byAccount, ForAccounts("o1"))
+	m.OnResult(ir.ResultEvent{Provider: "openai", AccountID: "o1"})
+	m.OnResult(ir.ResultEvent{Provider: "openai", AccountID: "o2"})
+	if _, results := byAccount.counts(); results != 1 {
+		t.Errorf("results = %d, want 1", results)
+	}
+}
+
+// blockingMeter blocks every event until release is closed.
+type blockingMeter struct {
+	recordingMeter
+	release chan struct{}
+}
+
+func (m *blockingMeter) OnRoute(e ir.RouteEvent) {
+	<-m.release
+	m.recordingMeter.OnRoute(e)
+}
+
+func (m *blockingMeter) OnResult(e ir.ResultEvent) {
+	<-m.release
+	m.recordingMeter.OnResult(e)
+}
+
+func TestAsyncMeterDoesNotBlock(t *testing.T) {
+	slow := &blockingMeter{release: make(chan struct{})}
+	m := NewAsyncMeter(slow, 2)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// One event is taken by the goroutine and blocks there, two fill
+		// the buffer, the rest are dropped.
+		for range 10 {
+			m.OnResult(ir.ResultEvent{Provider: "p", Success: true})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("OnResult blocked on a slow meter")
+	}
+
+	close(slow.release)
+	m.Close()
+
+	_, results := slow.counts()
+	if int64(results)+m.Dropped() != 10 {
+		t.Errorf("delivered %d + dropped %d, want 10 in total", results, m.Dropped())
+	}
+	if results < 2 || results > 3 {
+		t.Errorf("delivered = %d, want the buffer plus at most the one in flight", results)
+	}
+}
+
+func TestAsyncMeterCloseDrains(t *testing.T) {
+	rec := &recordingMeter{}
+	m := NewAsyncMeter(rec, 0)
+
+	for range 100 {
+		m.OnRoute(ir.RouteEvent{Provider: "p"})
+		m.OnResult(ir.ResultEvent{Provider: "p"})
+	}
+	m.Close()
+	m.Close() // idempotent
+
+	if routes, results := rec.counts(); routes != 100 || results != 100 {
+		t.Errorf("got %d routes, %d results after Close; want 100, 100", routes, results)
+	}
+
+	m.OnResult(ir.ResultEvent{Provider: "p"})
+	if m.Dropped() != 1 {
+		t.Errorf("Dropped = %d, want 1 event after Close", m.Dropped())
+	}
+}
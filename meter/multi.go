@@ -0,0 +1,35 @@
+package meter
+
+import "github.com/ineyio/inferrouter"
+
+// MultiMeter fans every event out to several meters, in order: a LogMeter and
+// a metrics exporter at once, say. A meter that blocks holds up the ones after
+// it; wrap it in an AsyncMeter.
+type MultiMeter struct {
+	meters []inferrouter.Meter
+}
+
+var _ inferrouter.Meter = (*MultiMeter)(nil)
+
+// NewMultiMeter creates a MultiMeter. Nil meters are skipped.
+func NewMultiMeter(meters ...inferrouter.Meter) *MultiMeter {
+	m := &MultiMeter{}
+	for _, mm := range meters {
+		if mm != nil {
+			m.meters = append(m.meters, mm)
+		}
+	}
+	return m
+}
+
+func (m *MultiMeter) OnRoute(e inferrouter.RouteEvent) {
+	for _, mm := range m.meters {
+		mm.OnRoute(e)
+	}
+}
+
+func (m *MultiMeter) OnResult(e inferrouter.ResultEvent) {
+	for _, mm := range m.meters {
+		mm.OnResult(e)
+	}
+}
@@ -0,0 +1,276 @@
+// Package prometheus provides a Prometheus-backed Meter for inferrouter.
+//
+// Meter turns routing events into counters and histograms: routes, attempt
+// depth, results by error class, tokens by kind, dollar cost and latency.
+// State that is not an event — in-flight requests, remaining quota, health —
+// is read from the router on every scrape; see Meter.ObserveRouter.
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ineyio/inferrouter"
+)
+
+// Meter is an inferrouter.Meter that exports Prometheus metrics.
+type Meter struct {
+	namespace  string
+	registerer prom.Registerer
+	buckets    []float64
+
+	routes   *prom.CounterVec
+	attempts *prom.HistogramVec
+	results  *prom.CounterVec
+	tokens   *prom.CounterVec
+	cost     *prom.CounterVec
+	duration *prom.HistogramVec
+}
+
+var _ inferrouter.Meter = (*Meter)(nil)
+
+// Option configures Meter.
+type Option func(*Meter)
+
+// WithNamespace sets the metric name prefix (default "inferrouter").
+func WithNamespace(namespace string) Option {
+	return func(m *Meter) { m.namespace = namespace }
+}
+
+// WithRegisterer sets where the metrics are registered (default
+// prometheus.DefaultRegisterer).
+func WithRegisterer(reg prom.Registerer) Option {
+	return func(m *Meter) { m.registerer = reg }
+}
+
+// WithDurationBuckets sets the request duration histogram buckets, in
+// seconds. The default spans 50ms to two minutes, which covers both fast
+// hosted APIs and slow self-hosted backends.
+func WithDurationBuckets(buckets []float64) Option {
+	return func(m *Meter) { m.buckets = buckets }
+}
+
+// DefaultDurationBuckets are the request duration buckets used by New.
+var DefaultDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Labels shared by the per-call metrics.
+var callLabels = []string{"provider", "account", "model", "free"}
+
+// New creates a Meter and registers its collectors. It returns an error if
+// registration fails, e.g. because a Meter with the same namespace is
+// already registered.
+func New(opts ...Option) (*Meter, error) {
+	m := &Meter{
+		namespace:  "inferrouter",
+		registerer: prom.DefaultRegisterer,
+		buckets:    DefaultDurationBuckets,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.routes = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "routes_total",
+		Help:      "Attempts started, by the candidate the router picked.",
+	}, slices.Concat(callLabels, []string{"hedge"}))
+	m.attempts = prom.NewHistogramVec(prom.HistogramOpts{
+		Namespace: m.namespace,
+		Name:      "attempt_number",
+		Help:      "Position of each attempt in its request's ladder; above 1 means failover.",
+		Buckets:   []float64{1, 2, 3, 4, 5, 8},
+	}, []string{"provider", "account", "model"})
+	m.results = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "results_total",
+		Help:      "Settled attempts, by outcome and error class.",
+	}, slices.Concat(callLabels, []string{"success", "error_class"}))
+	m.tokens = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "tokens_total",
+		Help:      "Tokens reported by providers, by kind (prompt, completion, cached, input_<modality>).",
+	}, slices.Concat(callLabels, []string{"kind"}))
+	m.cost = prom.NewCounterVec(prom.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "cost_dollars_total",
+		Help:      "Dollar cost of settled attempts, from the account's cost rates.",
+	}, callLabels)
+	m.duration = prom.NewHistogramVec(prom.HistogramOpts{
+		Namespace: m.namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Duration of settled attempts.",
+		Buckets:   m.buckets,
+	}, []string{"provider", "account", "model", "success"})
+
+	for _, c := range []prom.Collector{m.routes, m.attempts, m.results, m.tokens, m.cost, m.duration} {
+		if err := m.registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// OnRoute counts the attempt and records how deep into the ladder it is.
+func (m *Meter) OnRoute(e inferrouter.RouteEvent) {
+	m.routes.WithLabelValues(e.Provider, e.AccountID, e.Model, boolLabel(e.Free), boolLabel(e.Hedge)).Inc()
+	m.attempts.WithLabelValues(e.Provider, e.AccountID, e.Model).Observe(float64(e.AttemptNum))
+}
+
+// OnResult counts the outcome and adds its tokens, cost and duration.
+func (m *Meter) OnResult(e inferrouter.ResultEvent) {
+	free := boolLabel(e.Free)
+	success := boolLabel(e.Success)
+
+	m.results.WithLabelValues(e.Provider, e.AccountID, e.Model, free, success, ErrorClass(e.Error)).Inc()
+	if e.Duration > 0 {
+		m.duration.WithLabelValues(e.Provider, e.AccountID, e.Model, success).Observe(e.Duration.Seconds())
+	}
+	if e.DollarCost > 0 {
+		m.cost.WithLabelValues(e.Provider, e.AccountID, e.Model, free).Add(e.DollarCost)
+	}
+
+	addTokens := func(kind string, n int64) {
+		if n > 0 {
+			m.tokens.WithLabelValues(e.Provider, e.AccountID, e.Model, free, kind).Add(float64(n))
+		}
+	}
+	u := e.Usage
+	addTokens("prompt", u.PromptTokens)
+	addTokens("completion", u.CompletionTokens)
+	addTokens("cached", u.CachedTokens)
+	if b := u.InputBreakdown; b != nil {
+		addTokens("input_text", b.Text)
+		addTokens("input_audio", b.Audio)
+		addTokens("input_image", b.Image)
+		addTokens("input_video", b.Video)
+	}
+}
+
+// ErrorClass buckets a result error into a small fixed set of label values,
+// so error messages never become label cardinality. A nil error is "none".
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return "none"
+	case errors.Is(err, inferrouter.ErrHedgeLost):
+		return "hedge_lost"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, inferrouter.ErrRateLimited),
+		errors.Is(err, inferrouter.ErrRPMExceeded),
+		errors.Is(err, inferrouter.ErrTPMExceeded),
+		errors.Is(err, inferrouter.ErrConcurrencyExceeded):
+		return "rate_limited"
+	case errors.Is(err, inferrouter.ErrQuotaExceeded),
+		errors.Is(err, inferrouter.ErrNoFreeQuota):
+		return "quota"
+	case errors.Is(err, inferrouter.ErrAuthFailed):
+		return "auth"
+	case errors.Is(err, inferrouter.ErrContextLengthExceeded):
+		return "context_length"
+	case errors.Is(err, inferrouter.ErrInvalidRequest):
+		return "invalid_request"
+	case errors.Is(err, inferrouter.ErrModelNotFound):
+		return "model_not_found"
+	case errors.Is(err, inferrouter.ErrResponseFormatMismatch):
+		return "response_format"
+	case errors.Is(err, inferrouter.ErrProviderUnavailable):
+		return "unavailable"
+	default:
+		return "other"
+	}
+}
+
+// ObserveRouter registers gauges read from r on every scrape: in-flight
+// requests and remaining free quota per account, and the account and
+// provider circuit breaker states. Call it once per router, after NewRouter.
+func (m *Meter) ObserveRouter(r *inferrouter.Router) error {
+	return m.registerer.Register(newRouterCollector(m.namespace, r))
+}
+
+// scrapeTimeout bounds the QuotaStore reads of one scrape.
+const scrapeTimeout = 5 * time.Second
+
+var healthStates = []inferrouter.HealthState{
+	inferrouter.HealthHealthy,
+	inferrouter.HealthHalfOpen,
+	inferrouter.HealthUnhealthy,
+}
+
+// routerCollector is a prom.Collector over Router.AccountStatuses.
+type routerCollector struct {
+	router *inferrouter.Router
+
+	inflight       *prom.Desc
+	remaining      *prom.Desc
+	health         *prom.Desc
+	providerHealth *prom.Desc
+}
+
+func newRouterCollector(namespace string, r *inferrouter.Router) *routerCollector {
+	return &routerCollector{
+		router: r,
+		inflight: prom.NewDesc(prom.BuildFQName(namespace, "", "inflight_requests"),
+			"Requests currently executing on the account.",
+			[]string{"provider", "account"}, nil),
+		remaining: prom.NewDesc(prom.BuildFQName(namespace, "", "quota_remaining"),
+			"Free quota left today, in the account's quota unit (nanodollars for dollars).",
+			[]string{"provider", "account", "unit"}, nil),
+		health: prom.NewDesc(prom.BuildFQName(namespace, "", "account_health"),
+			"Account circuit breaker state: 1 for the current state, 0 otherwise.",
+			[]string{"provider", "account", "state"}, nil),
+		providerHealth: prom.NewDesc(prom.BuildFQName(namespace, "", "provider_health"),
+			"Provider-wide circuit breaker state: 1 for the current state, 0 otherwise.",
+			[]string{"provider", "state"}, nil),
+	}
+}
+
+func (c *routerCollector) Describe(ch chan<- *prom.Desc) {
+	ch <- c.inflight
+	ch <- c.remaining
+	ch <- c.health
+	ch <- c.providerHealth
+}
+
+func (c *routerCollector) Collect(ch chan<- prom.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	providers := make(map[string]bool)
+	for _, st := range c.router.AccountStatuses(ctx) {
+		ch <- prom.MustNewConstMetric(c.inflight, prom.GaugeValue, float64(st.Inflight), st.Provider, st.AccountID)
+		if st.HasQuota && st.RemainingErr == nil {
+			ch <- prom.MustNewConstMetric(c.remaining, prom.GaugeValue, float64(st.Remaining),
+				st.Provider, st.AccountID, string(st.QuotaUnit))
+		}
+		for _, s := range healthStates {
+			ch <- prom.MustNewConstMetric(c.health, prom.GaugeValue, stateValue(st.Health, s),
+				st.Provider, st.AccountID, s.String())
+		}
+		if !providers[st.Provider] {
+			providers[st.Provider] = true
+			for _, s := range healthStates {
+				ch <- prom.MustNewConstMetric(c.providerHealth, prom.GaugeValue, stateValue(st.ProviderHealth, s),
+					st.Provider, s.String())
+			}
+		}
+	}
+}
+
+func stateValue(current, s inferrouter.HealthState) float64 {
+	if current == s {
+		return 1
+	}
+	return 0
+}
+
+func boolLabel(b bool) string {
+	return strconv.FormatBool(b)
+}
@@ -0,0 +1,192 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/provider/mock"
+	"github.com/ineyio/inferrouter/quota"
+)
+
+func newTestMeter(t *testing.T) (*Meter, *prom.Registry) {
+	t.Helper()
+	reg := prom.NewRegistry()
+	m, err := New(WithRegisterer(reg))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return m, reg
+}
+
+func TestMeterOnRoute(t *testing.T) {
+	m, _ := newTestMeter(t)
+
+	m.OnRoute(ir.RouteEvent{Provider: "gemini", AccountID: "a1", Model: "flash", Free: true, AttemptNum: 1})
+	m.OnRoute(ir.RouteEvent{Provider: "gemini", AccountID: "a1", Model: "flash", Free: true, AttemptNum: 2, Hedge: true})
+
+	if got := testutil.ToFloat64(m.routes.WithLabelValues("gemini", "a1", "flash", "true", "false")); got != 1 {
+		t.Errorf("primary routes = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.routes.WithLabelValues("gemini", "a1", "flash", "true", "true")); got != 1 {
+		t.Errorf("hedge routes = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.attempts); got != 1 {
+		t.Errorf("attempt histogram series = %d, want 1", got)
+	}
+}
+
+func TestMeterOnResult(t *testing.T) {
+	m, _ := newTestMeter(t)
+
+	m.OnResult(ir.ResultEvent{
+		Provider: "gemini", AccountID: "a1", Model: "flash",
+		Success:  true,
+		Duration: 250 * time.Millisecond,
+		Usage: ir.Usage{
+			PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120, CachedTokens: 40,
+			InputBreakdown: &ir.InputTokenBreakdown{Text: 60, Image: 40},
+		},
+		DollarCost: 0.002,
+	})
+	m.OnResult(ir.ResultEvent{
+		Provider: "gemini", AccountID: "a1", Model: "flash",
+		Error: fmt.Errorf("call: %w", ir.ErrRateLimited),
+	})
+
+	tokens := map[string]float64{"prompt": 100, "completion": 20, "cached": 40, "input_text": 60, "input_image": 40}
+	for kind, want := range tokens {
+		if got := testutil.ToFloat64(m.tokens.WithLabelValues("gemini", "a1", "flash", "false", kind)); got != want {
+			t.Errorf("tokens{kind=%q} = %v, want %v", kind, got, want)
+		}
+	}
+	if got := testutil.CollectAndCount(m.tokens); got != len(tokens) {
+		t.Errorf("token series = %d, want %d (zero kinds are not exported)", got, len(tokens))
+	}
+	if got := testutil.ToFloat64(m.cost.WithLabelValues("gemini", "a1", "flash", "false")); got != 0.002 {
+		t.Errorf("cost = %v, want 0.002", got)
+	}
+	if got := testutil.ToFloat64(m.results.WithLabelValues("gemini", "a1", "flash", "false", "true", "none")); got != 1 {
+		t.Errorf("successful results = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.results.WithLabelValues("gemini", "a1", "flash", "false", "false", "rate_limited")); got != 1 {
+		t.Errorf("rate limited results = %v, want 1", got)
+	}
+	// The failed event has no duration, so only the success is observed.
+	if got := testutil.CollectAndCount(m.duration); got != 1 {
+		t.Errorf("duration series = %d, want 1", got)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, "none"},
+		{ir.ErrHedgeLost, "hedge_lost"},
+		{context.Canceled, "canceled"},
+		{context.DeadlineExceeded, "timeout"},
+		{ir.ErrTPMExceeded, "rate_limited"},
+		{ir.ErrConcurrencyExceeded, "rate_limited"},
+		{&ir.RateLimitError{RetryAfter: time.Second}, "rate_limited"},
+		{ir.ErrQuotaExceeded, "quota"},
+		{fmt.Errorf("gemini: %w", ir.ErrAuthFailed), "auth"},
+		{ir.ErrInvalidRequest, "invalid_request"},
+		{ir.ErrContextLengthExceeded, "context_length"},
+		{ir.ErrModelNotFound, "model_not_found"},
+		{ir.ErrResponseFormatMismatch, "response_format"},
+		{ir.ErrProviderUnavailable, "unavailable"},
+		{errors.New("boom"), "other"},
+	}
+	for _, tt := range tests {
+		if got := ErrorClass(tt.err); got != tt.want {
+			t.Errorf("ErrorClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestNewDuplicateRegistration(t *testing.T) {
+	reg := prom.NewRegistry()
+	if _, err := New(WithRegisterer(reg)); err != nil {
+		t.Fatalf("first New: %v", err)
+	}
+	if _, err := New(WithRegisterer(reg)); err == nil {
+		t.Fatal("second New on the same registry should fail")
+	}
+	if _, err := New(WithRegisterer(reg), WithNamespace("other")); err != nil {
+		t.Fatalf("New with a distinct namespace: %v", err)
+	}
+}
+
+func TestObserveRouter(t *testing.T) {
+	cfg := ir.Config{
+		DefaultModel: "test-model",
+		AllowPaid:    true,
+		Models: []ir.ModelMapping{{
+			Alias:  "test-model",
+			Models: []ir.ModelRef{{Provider: "mock", Model: "test-model"}},
+		}},
+		Accounts: []ir.AccountConfig{
+			{Provider: "mock", ID: "free-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "mock", ID: "paid-1", PaidEnabled: true, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+	m, reg := newTestMeter(t)
+	health := ir.NewHealthTracker()
+	r, err := ir.NewRouter(cfg, []ir.Provider{mock.New(mock.WithModels("test-model"))},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithHealthTracker(health),
+		ir.WithMeter(m),
+	)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	if err := m.ObserveRouter(r); err != nil {
+		t.Fatalf("ObserveRouter: %v", err)
+	}
+
+	if _, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	}); err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	for range 3 {
+		health.RecordFailure("paid-1")
+	}
+
+	expected := `
+# HELP inferrouter_quota_remaining Free quota left today, in the account's quota unit (nanodollars for dollars).
+# TYPE inferrouter_quota_remaining gauge
+inferrouter_quota_remaining{account="free-1",provider="mock",unit="tokens"} 970
+# HELP inferrouter_account_health Account circuit breaker state: 1 for the current state, 0 otherwise.
+# TYPE inferrouter_account_health gauge
+inferrouter_account_health{account="free-1",provider="mock",state="half-open"} 0
+inferrouter_account_health{account="free-1",provider="mock",state="healthy"} 1
+inferrouter_account_health{account="free-1",provider="mock",state="unhealthy"} 0
+inferrouter_account_health{account="paid-1",provider="mock",state="half-open"} 0
+inferrouter_account_health{account="paid-1",provider="mock",state="healthy"} 0
+inferrouter_account_health{account="paid-1",provider="mock",state="unhealthy"} 1
+# HELP inferrouter_inflight_requests Requests currently executing on the account.
+# TYPE inferrouter_inflight_requests gauge
+inferrouter_inflight_requests{account="free-1",provider="mock"} 0
+inferrouter_inflight_requests{account="paid-1",provider="mock"} 0
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"inferrouter_quota_remaining", "inferrouter_account_health", "inferrouter_inflight_requests")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// The router's own events reach the meter too.
+	if got := testutil.ToFloat64(m.results.WithLabelValues("mock", "free-1", "test-model", "true", "true", "none")); got != 1 {
+		t.Errorf("results = %v, want 1", got)
+	}
+}
@@ -0,0 +1,46 @@
+package meter
+
+import (
+	"math/rand/v2"
+
+	"github.com/ineyio/inferrouter"
+)
+
+// SampledMeter passes on a fraction of successful results, and optionally of
+// route events, and every failed result. It is meant for the success path of
+// a LogMeter, which at volume is mostly noise; a counter-based exporter would
+// undercount behind it.
+type SampledMeter struct {
+	next inferrouter.Meter
+	rate float64
+
+	// SampleRoutes applies the rate to route events too. Off by default:
+	// route events then pass through unsampled.
+	SampleRoutes bool
+}
+
+var _ inferrouter.Meter = (*SampledMeter)(nil)
+
+// NewSampledMeter creates a SampledMeter passing on rate, in [0, 1], of
+// successful results to next. Rates outside the range are clamped.
+func NewSampledMeter(next inferrouter.Meter, rate float64) *SampledMeter {
+	return &SampledMeter{next: next, rate: min(max(rate, 0), 1)}
+}
+
+func (m *SampledMeter) OnRoute(e inferrouter.RouteEvent) {
+	if m.SampleRoutes && !m.sample() {
+		return
+	}
+	m.next.OnRoute(e)
+}
+
+func (m *SampledMeter) OnResult(e inferrouter.ResultEvent) {
+	if e.Success && !m.sample() {
+		return
+	}
+	m.next.OnResult(e)
+}
+
+func (m *SampledMeter) sample() bool {
+	return m.rate >= 1 || rand.Float64() < m.rate
+}
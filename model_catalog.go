@@ -0,0 +1,167 @@
+package inferrouter
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ModelInfo describes what a model accepts and produces. A zero field means
+// unknown, and an unknown never excludes a candidate: the router only skips a
+// step the catalog says cannot serve the request.
+//
+// Each candidate's ModelInfo is assembled from the built-in catalog (see
+// LookupModelInfo), then ModelRef.Info, then AccountConfig.ModelInfo for the
+// model; each layer overrides the fields it sets.
+type ModelInfo struct {
+	// ContextWindow is the total of prompt and output tokens the model
+	// accepts in one request.
+	ContextWindow int64 `yaml:"context_window"`
+
+	// MaxOutputTokens is the most the model generates in one response.
+	MaxOutputTokens int `yaml:"max_output_tokens"`
+
+	// Input lists the part types the model accepts, PartText included.
+	Input []PartType `yaml:"input"`
+
+	// Tools, JSON and Streaming say whether the model supports tool calling,
+	// structured output (ChatRequest.ResponseFormat) and streaming. Nil is
+	// unknown.
+	Tools     *bool `yaml:"tools"`
+	JSON      *bool `yaml:"json"`
+	Streaming *bool `yaml:"streaming"`
+
+	// Embedding marks an embedding model. openaicompat.FromAccounts enables
+	// Embed for the models an account marks this way.
+	Embedding bool `yaml:"embedding"`
+}
+
+// merge returns m with the fields over sets replaced.
+func (m ModelInfo) merge(over ModelInfo) ModelInfo {
+	if over.ContextWindow > 0 {
+		m.ContextWindow = over.ContextWindow
+	}
+	if over.MaxOutputTokens > 0 {
+		m.MaxOutputTokens = over.MaxOutputTokens
+	}
+	if len(over.Input) > 0 {
+		m.Input = over.Input
+	}
+	if over.Tools != nil {
+		m.Tools = over.Tools
+	}
+	if over.JSON != nil {
+		m.JSON = over.JSON
+	}
+	if over.Streaming != nil {
+		m.Streaming = over.Streaming
+	}
+	if over.Embedding {
+		m.Embedding = true
+	}
+	return m
+}
+
+// serves reports whether nothing the catalog knows rules the model out for a
+// request with the given needs. The context window is checked separately, by
+// fits.
+func (m ModelInfo) serves(needs routeNeeds) bool {
+	return m.accepts(needs.media) &&
+		!(needs.tools && isFalse(m.Tools)) &&
+		!(needs.json && isFalse(m.JSON)) &&
+		!(needs.stream && isFalse(m.Streaming))
+}
+
+// accepts reports whether nothing the catalog knows rules out the media part
+// types.
+func (m ModelInfo) accepts(media []PartType) bool {
+	if len(m.Input) == 0 {
+		return true
+	}
+	for _, t := range media {
+		if !slices.Contains(m.Input, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// fits reports whether a prompt of estimatedTokens and up to maxTokens of
+// output fit the model. maxTokens is zero when the caller set no limit.
+func (m ModelInfo) fits(estimatedTokens int64, maxTokens int) bool {
+	if m.ContextWindow > 0 && estimatedTokens+int64(maxTokens) > m.ContextWindow {
+		return false
+	}
+	return m.MaxOutputTokens == 0 || maxTokens <= m.MaxOutputTokens
+}
+
+func isFalse(b *bool) bool { return b != nil && !*b }
+
+// LookupModelInfo returns the built-in catalog entry for a model. A pinned
+// snapshot of a listed model ("gemini-2.0-flash-001", "gpt-4o-2024-08-06",
+// "claude-3-5-sonnet-20241022") matches its entry; other variants
+// ("gpt-4o-audio-preview") are different models and match nothing. Case and
+// a leading "models/" are ignored.
+func LookupModelInfo(model string) (ModelInfo, bool) {
+	model = strings.ToLower(strings.TrimPrefix(model, "models/"))
+	info, ok := builtinModels[model]
+	if !ok {
+		for name, entry := range builtinModels {
+			if strings.HasPrefix(model, name) && versionSuffix.MatchString(model[len(name):]) {
+				info, ok = entry, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return ModelInfo{}, false
+	}
+	info.Input = slices.Clone(info.Input)
+	return info, true
+}
+
+// versionSuffix matches what vendors append to pin a snapshot of the same
+// model: "-001", "-20241022", "-2024-08-06", "-latest", "-preview-06-17".
+var versionSuffix = regexp.MustCompile(`^-(\d{3}|\d{8}|\d{4}-\d{2}-\d{2}|latest|preview-\d{2}-\d{2})$`)
+
+// modelInfo is the catalog entry for one (account, ladder step) candidate.
+func modelInfo(acc AccountConfig, ref ModelRef) ModelInfo {
+	info, _ := LookupModelInfo(ref.Model)
+	return info.merge(ref.Info).merge(acc.ModelInfo[ref.Model])
+}
+
+var (
+	textOnly    = []PartType{PartText}
+	textImage   = []PartType{PartText, PartImage}
+	allModality = []PartType{PartText, PartImage, PartAudio, PartVideo}
+)
+
+// builtinModels holds the published limits of well-known models, keyed in
+// lower case. Entries are deliberately few; anything else is configured
+// through ModelRef.Info or AccountConfig.ModelInfo.
+var builtinModels = map[string]ModelInfo{
+	"gpt-4o":       {ContextWindow: 128_000, MaxOutputTokens: 16_384, Input: textImage, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+	"gpt-4o-mini":  {ContextWindow: 128_000, MaxOutputTokens: 16_384, Input: textImage, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+	"gpt-4.1":      {ContextWindow: 1_047_576, MaxOutputTokens: 32_768, Input: textImage, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+	"gpt-4.1-mini": {ContextWindow: 1_047_576, MaxOutputTokens: 32_768, Input: textImage, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+	"gpt-4.1-nano": {ContextWindow: 1_047_576, MaxOutputTokens: 32_768, Input: textImage, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+	"o3-mini":      {ContextWindow: 200_000, MaxOutputTokens: 100_000, Input: textOnly, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+
+	"gemini-1.5-flash":      {ContextWindow: 1_048_576, MaxOutputTokens: 8_192, Input: allModality, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+	"gemini-1.5-pro":        {ContextWindow: 2_097_152, MaxOutputTokens: 8_192, Input: allModality, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+	"gemini-2.0-flash":      {ContextWindow: 1_048_576, MaxOutputTokens: 8_192, Input: allModality, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+	"gemini-2.0-flash-lite": {ContextWindow: 1_048_576, MaxOutputTokens: 8_192, Input: allModality, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+	"gemini-2.5-flash":      {ContextWindow: 1_048_576, MaxOutputTokens: 65_536, Input: allModality, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+	"gemini-2.5-flash-lite": {ContextWindow: 1_048_576, MaxOutputTokens: 65_536, Input: allModality, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+	"gemini-2.5-pro":        {ContextWindow: 1_048_576, MaxOutputTokens: 65_536, Input: allModality, Tools: BoolPtr(true), JSON: BoolPtr(true), Streaming: BoolPtr(true)},
+
+	"claude-3-haiku":    {ContextWindow: 200_000, MaxOutputTokens: 4_096, Input: textImage, Tools: BoolPtr(true), Streaming: BoolPtr(true)},
+	"claude-3-5-haiku":  {ContextWindow: 200_000, MaxOutputTokens: 8_192, Input: textImage, Tools: BoolPtr(true), Streaming: BoolPtr(true)},
+	"claude-3-5-sonnet": {ContextWindow: 200_000, MaxOutputTokens: 8_192, Input: textImage, Tools: BoolPtr(true), Streaming: BoolPtr(true)},
+	"claude-3-7-sonnet": {ContextWindow: 200_000, MaxOutputTokens: 64_000, Input: textImage, Tools: BoolPtr(true), Streaming: BoolPtr(true)},
+	"claude-sonnet-4":   {ContextWindow: 200_000, MaxOutputTokens: 64_000, Input: textImage, Tools: BoolPtr(true), Streaming: BoolPtr(true)},
+	"claude-opus-4":     {ContextWindow: 200_000, MaxOutputTokens: 32_000, Input: textImage, Tools: BoolPtr(true), Streaming: BoolPtr(true)},
+
+	"qwen/qwen3-235b-a22b-instruct-2507-fp8": {ContextWindow: 262_144, Input: textOnly, Streaming: BoolPtr(true)},
+	"qwen/qwq-32b":                           {ContextWindow: 131_072, Input: textOnly, Streaming: BoolPtr(true)},
+}
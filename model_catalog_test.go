@@ -0,0 +1,136 @@
+package inferrouter
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLookupModelInfo(t *testing.T) {
+	cases := []struct {
+		model  string
+		window int64
+		out    int
+	}{
+		{"gpt-4o", 128_000, 16_384},
+		{"gpt-4o-mini", 128_000, 16_384},
+		{"gemini-2.0-flash-001", 1_048_576, 8_192},
+		{"models/gemini-2.5-pro", 1_048_576, 65_536},
+		{"gemini-2.5-flash-lite-preview-06-17", 1_048_576, 65_536},
+		{"gpt-4o-2024-08-06", 128_000, 16_384},
+		{"gpt-4o-mini-2024-07-18", 128_000, 16_384},
+		{"claude-3-5-sonnet-20241022", 200_000, 8_192},
+		{"claude-3-5-sonnet-latest", 200_000, 8_192},
+	}
+	for _, tc := range cases {
+		info, ok := LookupModelInfo(tc.model)
+		if !ok {
+			t.Errorf("%s: not in catalog", tc.model)
+			continue
+		}
+		if info.ContextWindow != tc.window || info.MaxOutputTokens != tc.out {
+			t.Errorf("%s: window %d, output %d; want %d, %d", tc.model, info.ContextWindow, info.MaxOutputTokens, tc.window, tc.out)
+		}
+	}
+
+	// Variants are different models, not snapshots of the base one.
+	for _, model := range []string{"gpt-4", "gpt-4omni", "my-finetune", "gpt-4o-audio-preview",
+		"gpt-4o-realtime-preview-2024-12-17", "gpt-4o-search-preview", "gemini-2.0-flash-exp-image-generation"} {
+		if _, ok := LookupModelInfo(model); ok {
+			t.Errorf("%s: found, want no entry", model)
+		}
+	}
+}
+
+func TestModelInfoLayering(t *testing.T) {
+	acc := AccountConfig{ModelInfo: map[string]ModelInfo{
+		"gpt-4o": {ContextWindow: 32_000},
+	}}
+	ref := ModelRef{Model: "gpt-4o", Info: ModelInfo{ContextWindow: 64_000, Tools: BoolPtr(false)}}
+
+	info := modelInfo(acc, ref)
+	if info.ContextWindow != 32_000 {
+		t.Errorf("window = %d, want the account's 32000", info.ContextWindow)
+	}
+	if !isFalse(info.Tools) {
+		t.Error("tools = unset, want the ref's false")
+	}
+	if info.MaxOutputTokens != 16_384 {
+		t.Errorf("max output = %d, want the built-in 16384", info.MaxOutputTokens)
+	}
+}
+
+func TestModelInfoServes(t *testing.T) {
+	text := ModelInfo{Input: []PartType{PartText}, Tools: BoolPtr(false)}
+	cases := []struct {
+		name  string
+		info  ModelInfo
+		needs routeNeeds
+		want  bool
+	}{
+		{"unknown serves anything", ModelInfo{}, routeNeeds{media: []PartType{PartAudio}, tools: true, json: true, stream: true}, true},
+		{"plain text", text, routeNeeds{}, true},
+		{"image on text model", text, routeNeeds{media: []PartType{PartImage}}, false},
+		{"tools declined", text, routeNeeds{tools: true}, false},
+		{"json unknown", text, routeNeeds{json: true}, true},
+		{"no streaming", ModelInfo{Streaming: BoolPtr(false)}, routeNeeds{stream: true}, false},
+	}
+	for _, tc := range cases {
+		if got := tc.info.serves(tc.needs); got != tc.want {
+			t.Errorf("%s: serves = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFitCandidates(t *testing.T) {
+	p := &testProvider{name: "p"}
+	in := []Candidate{
+		{Provider: p, AccountID: "small", Info: ModelInfo{ContextWindow: 1000, MaxOutputTokens: 500}},
+		{Provider: p, AccountID: "large", Info: ModelInfo{ContextWindow: 100_000, MaxOutputTokens: 8000}},
+		{Provider: p, AccountID: "unknown"},
+	}
+
+	out, err := fitCandidates(in, 900, 200)
+	if err != nil || len(out) != 2 || out[0].AccountID != "large" {
+		t.Errorf("got %v, %v; want large and unknown", ids(out), err)
+	}
+
+	out, err = fitCandidates(in, 10, 1000)
+	if err != nil || len(out) != 2 {
+		t.Errorf("max tokens over small's output limit: got %v, %v", ids(out), err)
+	}
+
+	_, err = fitCandidates(in[:2], 200_000, 0)
+	if !errors.Is(err, ErrContextWindowExceeded) {
+		t.Errorf("err = %v, want ErrContextWindowExceeded", err)
+	}
+}
+
+func TestPrepareRouteSkipsSmallContextWindow(t *testing.T) {
+	cfg := Config{
+		AllowPaid:    true,
+		DefaultModel: "ladder",
+		Models: []ModelMapping{{Alias: "ladder", Models: []ModelRef{
+			{Provider: "p", Model: "small", Info: ModelInfo{ContextWindow: 8}},
+			{Provider: "p", Model: "long", Info: ModelInfo{ContextWindow: 1_000_000}},
+		}}},
+		Accounts: []AccountConfig{{Provider: "p", ID: "acc-1", QuotaUnit: QuotaTokens}},
+	}
+	r, err := NewRouter(cfg, []Provider{&testProvider{name: "p"}}, WithQuotaStore(&noopQuotaStore{}))
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	cands, err := r.prepareRoute(context.Background(), RouteContext{EstimatedTokens: 100}, routeNeeds{})
+	if err != nil {
+		t.Fatalf("prepareRoute: %v", err)
+	}
+	if len(cands) != 1 || cands[0].Model != "long" {
+		t.Errorf("got %v, want only the long-context step", ids(cands))
+	}
+
+	_, err = r.prepareRoute(context.Background(), RouteContext{EstimatedTokens: 2_000_000}, routeNeeds{})
+	if !errors.Is(err, ErrContextWindowExceeded) {
+		t.Errorf("err = %v, want ErrContextWindowExceeded", err)
+	}
+}
@@ -3,6 +3,7 @@ package inferrouter_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 
@@ -269,6 +270,40 @@ func TestRouter_MultimodalNoCapableStep(t *testing.T) {
 	assert.Empty(t, log.snapshot(), "no step may be attempted")
 }
 
+// A step that answers "context length exceeded" is skipped, not fatal: the
+// next step may have a larger window. The short step's health is untouched.
+func TestRouter_ContextLengthMovesDownLadder(t *testing.T) {
+	log := &attemptLog{}
+	short := mock.New(
+		mock.WithName("short"),
+		mock.WithModels("ladder-model"),
+		mock.WithResponseFunc(func(ir.ProviderRequest) (ir.ProviderResponse, error) {
+			log.record("short")
+			return ir.ProviderResponse{}, fmt.Errorf("%w: maximum context length is 8192 tokens", ir.ErrContextLengthExceeded)
+		}),
+	)
+
+	cfg := ladderConfig("short", "long")
+	health := ir.DefaultHealthConfig()
+	health.FailureThreshold = 1
+	r, err := ir.NewRouter(cfg, []ir.Provider{short, servingStep(log, "long")},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithHealthConfig(health))
+	require.NoError(t, err)
+
+	resp, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Model:    "ladder",
+		Messages: []ir.Message{{Role: "user", Content: "a long prompt"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"short", "long"}, log.snapshot())
+	assert.Equal(t, "long", resp.Routing.Provider)
+
+	for _, s := range r.AccountStatuses(context.Background()) {
+		assert.Equal(t, ir.HealthHealthy, s.Health, "%s health", s.AccountID)
+	}
+}
+
 // declareLadder gives a pre-strict-resolution test config the alias its
 // DefaultModel now needs: one step per distinct provider, in account order.
 // Resolution is strict (R2), so every request must name a declared ladder;
@@ -6,6 +6,61 @@ type Policy interface {
 	Select(candidates []Candidate) []Candidate
 }
 
+// RequestPolicy is a Policy that also looks at the request: it can send long
+// prompts to large-context models, media to cheap vision accounts, or a
+// high-priority tenant straight to paid keys. The router calls SelectRequest
+// instead of Select when the configured policy implements it.
+type RequestPolicy interface {
+	Policy
+
+	// SelectRequest orders candidates for the request rc describes, highest
+	// priority first. Candidates it leaves out are not attempted.
+	SelectRequest(rc RouteContext, candidates []Candidate) []Candidate
+}
+
+// RouteContext is what a RequestPolicy knows about the request.
+type RouteContext struct {
+	// Model is the model or alias the caller asked for; empty means the
+	// config's default.
+	Model string
+
+	// EstimatedTokens is the prompt size as EstimateTokens sees it.
+	EstimatedTokens int64
+
+	// MaxTokens is the caller's output limit; zero when unset.
+	MaxTokens int
+
+	HasMedia bool
+	HasTools bool
+	Stream   bool
+
+	// Labels and Priority are passed through from ChatRequest.
+	Labels   map[string]string
+	Priority int
+
+	// Route is the request's RouteOptions, or nil. The router has already
+	// applied it to the candidates a policy sees.
+	Route *RouteOptions
+}
+
+// newRouteContext describes req for a RequestPolicy.
+func newRouteContext(req ChatRequest, needs routeNeeds) RouteContext {
+	rc := RouteContext{
+		Model:           req.Model,
+		EstimatedTokens: EstimateTokens(req.Messages),
+		HasMedia:        needs.multimodal,
+		HasTools:        needs.tools,
+		Stream:          needs.stream,
+		Labels:          req.Labels,
+		Priority:        req.Priority,
+		Route:           req.Route,
+	}
+	if req.MaxTokens != nil {
+		rc.MaxTokens = *req.MaxTokens
+	}
+	return rc
+}
+
 // Candidate represents a possible route for a request.
 type Candidate struct {
 	Provider  Provider
@@ -13,16 +68,30 @@ type Candidate struct {
 	Auth      Auth
 	Model     string
 	Free      bool
-	Remaining int64     // remaining quota (tokens or requests)
+	Remaining int64     // remaining quota, in QuotaUnit (nanodollars for QuotaDollars)
 	QuotaUnit QuotaUnit // unit of the quota
 	Health    HealthState
 
+	// ProviderHealth is the provider-wide circuit state, shared by every
+	// account of the provider. Unhealthy candidates never reach a policy;
+	// half-open means the provider is being probed after an outage.
+	ProviderHealth HealthState
+
 	// Inflight is the number of requests currently executing against this
 	// account. Populated from the router's InflightTracker; used by
 	// load-aware policies (policy.LeastBusyPolicy) to spread concurrent
 	// requests across a pool of slow gateways.
 	Inflight int64
 
+	// Latency is the observed speed of this model on this account (or of the
+	// account as a whole until the model has been measured there). Populated
+	// from the router's LatencyTracker; used by policy.LatencyPolicy.
+	Latency LatencyStats
+
+	// Info is the model catalog entry for this step on this account; zero
+	// fields are unknown.
+	Info ModelInfo
+
 	// Deprecated: use CostPerInputToken/CostPerOutputToken.
 	CostPerToken float64
 
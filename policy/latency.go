@@ -0,0 +1,128 @@
+package policy
+
+import (
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/ineyio/inferrouter"
+)
+
+// LatencyPolicy prefers the accounts that have been answering fastest, by
+// the EWMA latency the router records for every served call, or the time to
+// first token of served streams (Candidate.Latency).
+//
+// Ordering: free before paid; within each group healthy before half-open,
+// then unmeasured accounts first (so a new account gets its first samples),
+// then lowest latency. With UseTTFT the time to first token is compared
+// instead, as long as every measured account has streamed; otherwise all of
+// them are ranked by full latency, since the two do not compare.
+//
+// Latency averages only move when an account is used, so an account that was
+// slow once could sit at the back forever after it recovered. Exploration
+// counters that: on that fraction of requests the least recently measured
+// account of the leading group is tried first.
+type LatencyPolicy struct {
+	// Exploration is the fraction of requests, in [0, 1], that re-measure a
+	// stale account instead of taking the fastest one. 0.05 is a reasonable
+	// start; zero disables exploration.
+	Exploration float64
+
+	// UseTTFT ranks by time to first token when every measured candidate
+	// has a TTFT average. Suits streaming-heavy traffic, where the first
+	// token is what users wait for.
+	UseTTFT bool
+
+	// rnd replaces rand.Float64 in tests.
+	rnd func() float64
+}
+
+var _ inferrouter.Policy = (*LatencyPolicy)(nil)
+
+// Select orders candidates: free first, then healthy, then fastest.
+func (p *LatencyPolicy) Select(candidates []inferrouter.Candidate) []inferrouter.Candidate {
+	result := make([]inferrouter.Candidate, len(candidates))
+	copy(result, candidates)
+	ttft := p.UseTTFT && allHaveTTFT(result)
+
+	sort.SliceStable(result, func(i, j int) bool {
+		ci, cj := result[i], result[j]
+
+		// Free before paid.
+		if ci.Free != cj.Free {
+			return ci.Free
+		}
+
+		// Healthy before half-open.
+		if (ci.Health == inferrouter.HealthHealthy) != (cj.Health == inferrouter.HealthHealthy) {
+			return ci.Health == inferrouter.HealthHealthy
+		}
+
+		// Unmeasured first: there is no other way to learn their speed.
+		li, ni := observed(ci, ttft)
+		lj, nj := observed(cj, ttft)
+		if (ni > 0) != (nj > 0) {
+			return ni == 0
+		}
+
+		return li < lj
+	})
+
+	if p.explore() {
+		exploreStale(result)
+	}
+	return result
+}
+
+// observed returns the average a candidate is ranked by, TTFT or full
+// latency, and the number of samples behind it.
+func observed(c inferrouter.Candidate, ttft bool) (time.Duration, int64) {
+	if ttft {
+		return c.Latency.TTFT, c.Latency.TTFTSamples
+	}
+	return c.Latency.Latency, c.Latency.Samples
+}
+
+// allHaveTTFT reports whether every measured candidate has a TTFT average.
+// One that lacks it would be ranked by full latency against the others'
+// TTFT and always look slower.
+func allHaveTTFT(candidates []inferrouter.Candidate) bool {
+	for _, c := range candidates {
+		measured := c.Latency.Samples > 0 || c.Latency.TTFTSamples > 0
+		if measured && c.Latency.TTFTSamples == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *LatencyPolicy) explore() bool {
+	if p.Exploration <= 0 {
+		return false
+	}
+	rnd := p.rnd
+	if rnd == nil {
+		rnd = rand.Float64
+	}
+	return rnd() < p.Exploration
+}
+
+// exploreStale moves the least recently measured candidate of the leading
+// free/paid group to the front. Exploring never crosses from free to paid.
+func exploreStale(result []inferrouter.Candidate) {
+	if len(result) < 2 {
+		return
+	}
+	stalest := 0
+	for i := 1; i < len(result) && result[i].Free == result[0].Free; i++ {
+		if result[i].Latency.Updated.Before(result[stalest].Latency.Updated) {
+			stalest = i
+		}
+	}
+	if stalest == 0 {
+		return
+	}
+	c := result[stalest]
+	copy(result[1:stalest+1], result[:stalest])
+	result[0] = c
+}
@@ -3,6 +3,7 @@ package policy
 import (
 	"math"
 	"testing"
+	"time"
 
 	ir "github.com/ineyio/inferrouter"
 )
@@ -192,4 +193,83 @@ func TestEmptyInput(t *testing.T) {
 	if got := (&LeastBusyPolicy{}).Select(nil); len(got) != 0 {
 		t.Errorf("LeastBusy on nil = %v", got)
 	}
+	if got := (&LatencyPolicy{Exploration: 1}).Select(nil); len(got) != 0 {
+		t.Errorf("Latency on nil = %v", got)
+	}
+}
+
+func measured(latency time.Duration, updated time.Time) ir.LatencyStats {
+	return ir.LatencyStats{Latency: latency, Samples: 10, Updated: updated}
+}
+
+func TestLatencyPrefersFastFreeBeforePaid(t *testing.T) {
+	now := time.Now()
+	p := &LatencyPolicy{}
+	in := []ir.Candidate{
+		{AccountID: "paid-fast", Free: false, Latency: measured(100*time.Millisecond, now)},
+		{AccountID: "free-slow", Free: true, Latency: measured(5*time.Second, now)},
+		{AccountID: "free-fast", Free: true, Latency: measured(time.Second, now)},
+		{AccountID: "free-halfopen", Free: true, Health: ir.HealthHalfOpen, Latency: measured(10*time.Millisecond, now)},
+		{AccountID: "free-new", Free: true},
+	}
+	got := ids(p.Select(in))
+	want := []string{"free-new", "free-fast", "free-slow", "free-halfopen", "paid-fast"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("pos %d: got %q, want %q (full=%v)", i, got[i], want[i], got)
+		}
+	}
+}
+
+func TestLatencyUseTTFT(t *testing.T) {
+	now := time.Now()
+	p := &LatencyPolicy{UseTTFT: true}
+	in := []ir.Candidate{
+		{AccountID: "quick-total", Free: true, Latency: ir.LatencyStats{
+			Latency: time.Second, TTFT: 900 * time.Millisecond, Samples: 5, TTFTSamples: 5, Updated: now}},
+		{AccountID: "quick-first-token", Free: true, Latency: ir.LatencyStats{
+			Latency: 3 * time.Second, TTFT: 200 * time.Millisecond, Samples: 5, TTFTSamples: 5, Updated: now}},
+	}
+	if got := ids(p.Select(in)); got[0] != "quick-first-token" {
+		t.Errorf("got %v, want the lowest TTFT first", got)
+	}
+}
+
+// One candidate without TTFT samples puts everyone back on full latency;
+// its 2s total must not be compared with the others' sub-second TTFT.
+func TestLatencyUseTTFTFallsBackWhenOneLacksIt(t *testing.T) {
+	now := time.Now()
+	p := &LatencyPolicy{UseTTFT: true}
+	in := []ir.Candidate{
+		{AccountID: "streamed", Free: true, Latency: ir.LatencyStats{
+			Latency: 4 * time.Second, TTFT: 300 * time.Millisecond, Samples: 5, TTFTSamples: 5, Updated: now}},
+		{AccountID: "sync-only", Free: true, Latency: measured(2*time.Second, now)},
+	}
+	if got := ids(p.Select(in)); got[0] != "sync-only" {
+		t.Errorf("got %v, want both ranked by full latency", got)
+	}
+}
+
+func TestLatencyExplorationRemeasuresStalest(t *testing.T) {
+	now := time.Now()
+	in := []ir.Candidate{
+		{AccountID: "fast", Free: true, Latency: measured(time.Second, now)},
+		{AccountID: "slow-stale", Free: true, Latency: measured(9*time.Second, now.Add(-time.Hour))},
+		{AccountID: "mid", Free: true, Latency: measured(2*time.Second, now.Add(-time.Minute))},
+		{AccountID: "paid-stalest", Free: false, Latency: measured(time.Second, now.Add(-24*time.Hour))},
+	}
+
+	exploring := &LatencyPolicy{Exploration: 0.1, rnd: func() float64 { return 0.05 }}
+	got := ids(exploring.Select(in))
+	want := []string{"slow-stale", "fast", "mid", "paid-stalest"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("exploring pos %d: got %q, want %q (full=%v)", i, got[i], want[i], got)
+		}
+	}
+
+	exploiting := &LatencyPolicy{Exploration: 0.1, rnd: func() float64 { return 0.5 }}
+	if got := ids(exploiting.Select(in)); got[0] != "fast" {
+		t.Errorf("exploiting: got %v, want fast first", got)
+	}
 }
@@ -38,6 +38,17 @@ type ProviderRequest struct {
 	Stop        []string
 	Stream      bool
 
+	Seed             *int
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+	N                *int
+	SafetySettings   []SafetySetting
+
+	Tools      []Tool
+	ToolChoice *ToolChoice
+
+	ResponseFormat *ResponseFormat
+
 	// HasMedia is precomputed by the router so providers don't need to
 	// rewalk Messages/Parts (important on the streaming path where buildUsage
 	// fires per chunk).
@@ -48,9 +59,68 @@ type ProviderRequest struct {
 type ProviderResponse struct {
 	ID           string
 	Content      string
+	ToolCalls    []ToolCall
 	FinishReason string
 	Usage        Usage
 	Model        string
+
+	// Choices holds every completion when the request asked for more than
+	// one (ProviderRequest.N); nil otherwise. Content, ToolCalls and
+	// FinishReason always describe the first.
+	Choices []Choice
+
+	// RateLimits is the budget the provider reported in its response
+	// headers, nil if it sent none. The router feeds it to
+	// RateLimiter.SyncFromProvider.
+	RateLimits *RateLimitInfo
+}
+
+// ToolProvider is an OPTIONAL capability interface. Providers that can
+// serialize Tools, ToolChoice, assistant ToolCalls and "tool" role results
+// implement it and return true; the router discovers it via type assertion
+// and drops every other provider from requests that use tools. Absence means
+// "cannot do tools" — sending such a request to a text-only backend would
+// silently strip the tool definitions.
+type ToolProvider interface {
+	SupportsTools() bool
+}
+
+// supportsTools reports whether p advertises tool calling.
+func supportsTools(p Provider) bool {
+	tp, ok := p.(ToolProvider)
+	return ok && tp.SupportsTools()
+}
+
+// ChoicesProvider is an OPTIONAL capability interface. Providers that return
+// every completion for ProviderRequest.N above 1 (in ProviderResponse.Choices)
+// implement it and return true; the router drops every other provider from
+// such requests. Absence means "generates one completion" — the request
+// would otherwise succeed with fewer choices than it asked for.
+type ChoicesProvider interface {
+	SupportsChoices() bool
+}
+
+// supportsChoices reports whether p returns several completions for N.
+func supportsChoices(p Provider) bool {
+	cp, ok := p.(ChoicesProvider)
+	return ok && cp.SupportsChoices()
+}
+
+// MultimodalModelProvider is an OPTIONAL capability interface for providers
+// whose media support depends on the model, such as an OpenAI-compatible
+// endpoint that serves both vision and text-only models. The router discovers
+// it via type assertion; when present it decides per candidate in place of
+// SupportsMultimodal.
+type MultimodalModelProvider interface {
+	SupportsMultimodalModel(model string) bool
+}
+
+// supportsMultimodal reports whether p accepts media parts for model.
+func supportsMultimodal(p Provider, model string) bool {
+	if mp, ok := p.(MultimodalModelProvider); ok {
+		return mp.SupportsMultimodalModel(model)
+	}
+	return p.SupportsMultimodal()
 }
 
 // ProviderStream is the interface for streaming responses.
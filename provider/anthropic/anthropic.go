@@ -0,0 +1,695 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ineyio/inferrouter"
+)
+
+const (
+	defaultBaseURL = "https://api.anthropic.com/v1"
+
+	// apiVersion is the anthropic-version header every request must carry.
+	apiVersion = "2023-06-01"
+
+	// defaultMaxTokens fills max_tokens, which the Messages API requires,
+	// when the request leaves it unset.
+	defaultMaxTokens = 4096
+)
+
+// Provider is the Anthropic Messages API adapter.
+type Provider struct {
+	baseURL    string
+	httpClient *http.Client
+	models     []string
+	maxTokens  int
+}
+
+var (
+	_ inferrouter.Provider     = (*Provider)(nil)
+	_ inferrouter.ToolProvider = (*Provider)(nil)
+)
+
+// Option configures the provider.
+type Option func(*Provider)
+
+// WithBaseURL sets a custom base URL.
+func WithBaseURL(url string) Option {
+	return func(p *Provider) { p.baseURL = strings.TrimRight(url, "/") }
+}
+
+// WithHTTPClient sets a custom HTTP client.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.httpClient = c }
+}
+
+// WithModels sets the list of supported models.
+func WithModels(models ...string) Option {
+	return func(p *Provider) { p.models = models }
+}
+
+// WithDefaultMaxTokens sets the max_tokens sent when a request has no
+// MaxTokens. The Messages API rejects requests without one; the default is
+// 4096.
+func WithDefaultMaxTokens(n int) Option {
+	return func(p *Provider) { p.maxTokens = n }
+}
+
+// New creates a new Anthropic provider.
+func New(opts ...Option) *Provider {
+	p := &Provider{
+		baseURL:    defaultBaseURL,
+		httpClient: http.DefaultClient,
+		maxTokens:  defaultMaxTokens,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Provider) Name() string { return "anthropic" }
+
+func (p *Provider) SupportsModel(model string) bool {
+	if len(p.models) == 0 {
+		return true
+	}
+	for _, m := range p.models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsMultimodal reports that image parts are serialized. Claude takes
+// no audio or video; a request carrying them fails with ErrInvalidRequest,
+// and the model catalog keeps such requests away from known Claude models.
+func (p *Provider) SupportsMultimodal() bool { return true }
+
+// SupportsTools reports that tool use is serialized (tools / tool_use /
+// tool_result).
+func (p *Provider) SupportsTools() bool { return true }
+
+// Messages API types.
+type apiRequest struct {
+	Model         string         `json:"model"`
+	System        string         `json:"system,omitempty"`
+	Messages      []apiMessage   `json:"messages"`
+	MaxTokens     int            `json:"max_tokens"`
+	Temperature   *float64       `json:"temperature,omitempty"`
+	TopP          *float64       `json:"top_p,omitempty"`
+	StopSequences []string       `json:"stop_sequences,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	Tools         []apiTool      `json:"tools,omitempty"`
+	ToolChoice    *apiToolChoice `json:"tool_choice,omitempty"`
+}
+
+type apiMessage struct {
+	Role    string     `json:"role"`
+	Content []apiBlock `json:"content"`
+}
+
+// apiBlock is a content block; Type selects which fields are set.
+type apiBlock struct {
+	Type string `json:"type"`
+
+	Text string `json:"text,omitempty"` // text
+
+	Source *apiSource `json:"source,omitempty"` // image
+
+	ID    string          `json:"id,omitempty"` // tool_use
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	ToolUseID string `json:"tool_use_id,omitempty"` // tool_result
+	Content   string `json:"content,omitempty"`
+}
+
+type apiSource struct {
+	Type      string `json:"type"` // always "base64"
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type apiTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type apiToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type apiUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+}
+
+type apiResponse struct {
+	ID         string     `json:"id"`
+	Model      string     `json:"model"`
+	Content    []apiBlock `json:"content"`
+	StopReason string     `json:"stop_reason"`
+	Usage      apiUsage   `json:"usage"`
+}
+
+func (p *Provider) ChatCompletion(ctx context.Context, req inferrouter.ProviderRequest) (inferrouter.ProviderResponse, error) {
+	body, err := p.buildRequest(req, false)
+	if err != nil {
+		return inferrouter.ProviderResponse{}, err
+	}
+
+	httpResp, err := p.doRequest(ctx, req.Auth, body)
+	if err != nil {
+		return inferrouter.ProviderResponse{}, err
+	}
+	defer httpResp.Body.Close()
+
+	if err := mapHTTPError(httpResp); err != nil {
+		return inferrouter.ProviderResponse{}, err
+	}
+
+	var resp apiResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return inferrouter.ProviderResponse{}, fmt.Errorf("inferrouter: decode anthropic response: %w", err)
+	}
+
+	var content strings.Builder
+	var toolCalls []inferrouter.ToolCall
+	for _, b := range resp.Content {
+		switch b.Type {
+		case "text":
+			content.WriteString(b.Text)
+		case "tool_use":
+			args := string(b.Input)
+			if args == "" {
+				args = "{}"
+			}
+			toolCalls = append(toolCalls, inferrouter.ToolCall{
+				ID:       b.ID,
+				Type:     "function",
+				Function: inferrouter.FunctionCall{Name: b.Name, Arguments: args},
+			})
+		}
+	}
+
+	return inferrouter.ProviderResponse{
+		ID:           resp.ID,
+		Content:      content.String(),
+		ToolCalls:    toolCalls,
+		FinishReason: finishReason(resp.StopReason),
+		Model:        resp.Model,
+		Usage:        buildUsage(resp.Usage, req),
+		RateLimits:   rateLimits(httpResp.Header),
+	}, nil
+}
+
+func (p *Provider) ChatCompletionStream(ctx context.Context, req inferrouter.ProviderRequest) (inferrouter.ProviderStream, error) {
+	body, err := p.buildRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := p.doRequest(ctx, req.Auth, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mapHTTPError(httpResp); err != nil {
+		httpResp.Body.Close()
+		return nil, err
+	}
+
+	return &sseStream{
+		reader:     bufio.NewReader(httpResp.Body),
+		body:       httpResp.Body,
+		req:        req,
+		model:      req.Model,
+		toolIndex:  make(map[int]int),
+		rateLimits: rateLimits(httpResp.Header),
+	}, nil
+}
+
+// finishReason maps stop_reason to the OpenAI vocabulary callers branch on.
+func finishReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence", "pause_turn":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	default:
+		return reason
+	}
+}
+
+// buildUsage maps Anthropic usage to inferrouter.Usage. Anthropic counts
+// cache reads and cache writes apart from input_tokens; they are added back
+// into PromptTokens, so it is the whole prompt as with other providers, and
+// their sum is CachedTokens. Cost is computed on PromptTokens at the input
+// rate: Anthropic bills cache reads lower and cache writes higher than that.
+func buildUsage(u apiUsage, req inferrouter.ProviderRequest) inferrouter.Usage {
+	cached := u.CacheReadInputTokens + u.CacheCreationInputTokens
+	prompt := u.InputTokens + cached
+	usage := inferrouter.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      prompt + u.OutputTokens,
+		CachedTokens:     cached,
+	}
+	if !req.HasMedia {
+		usage.InputBreakdown = &inferrouter.InputTokenBreakdown{Text: prompt}
+	}
+	return usage
+}
+
+func (p *Provider) buildRequest(req inferrouter.ProviderRequest, stream bool) (apiRequest, error) {
+	ar := apiRequest{
+		Model:         req.Model,
+		MaxTokens:     p.maxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+		Stream:        stream,
+		Tools:         buildTools(req.Tools),
+		ToolChoice:    buildToolChoice(req.ToolChoice),
+	}
+	if req.MaxTokens != nil {
+		ar.MaxTokens = *req.MaxTokens
+	}
+
+	var system []string
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system":
+			// The Messages API takes the system prompt as a top-level field,
+			// not as a turn.
+			system = append(system, messageText(m))
+		case "tool":
+			// Every result answering one assistant turn travels in the next
+			// user message, as tool_result blocks.
+			block := apiBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content}
+			if n := len(ar.Messages); n > 0 && isToolResultMessage(ar.Messages[n-1]) {
+				ar.Messages[n-1].Content = append(ar.Messages[n-1].Content, block)
+				continue
+			}
+			ar.Messages = append(ar.Messages, apiMessage{Role: "user", Content: []apiBlock{block}})
+		default:
+			blocks, err := buildBlocks(m)
+			if err != nil {
+				return apiRequest{}, err
+			}
+			ar.Messages = append(ar.Messages, apiMessage{Role: m.Role, Content: blocks})
+		}
+	}
+	ar.System = strings.Join(system, "\n\n")
+
+	return ar, nil
+}
+
+// messageText is the text of a message, whether it is set as Content or as
+// text parts.
+func messageText(m inferrouter.Message) string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	var texts []string
+	for _, p := range m.Parts {
+		if p.Type == inferrouter.PartText {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func isToolResultMessage(m apiMessage) bool {
+	return m.Role == "user" && len(m.Content) > 0 && m.Content[0].Type == "tool_result"
+}
+
+// buildBlocks maps a user or assistant message to content blocks. Assistant
+// tool calls follow the text as tool_use blocks.
+func buildBlocks(m inferrouter.Message) ([]apiBlock, error) {
+	var blocks []apiBlock
+	if len(m.Parts) == 0 {
+		if m.Content != "" {
+			blocks = append(blocks, apiBlock{Type: "text", Text: m.Content})
+		}
+	}
+	for _, p := range m.Parts {
+		switch p.Type {
+		case inferrouter.PartText:
+			blocks = append(blocks, apiBlock{Type: "text", Text: p.Text})
+		case inferrouter.PartImage:
+			blocks = append(blocks, apiBlock{Type: "image", Source: &apiSource{
+				Type:      "base64",
+				MediaType: p.MIMEType,
+				Data:      base64.StdEncoding.EncodeToString(p.Data),
+			}})
+		default:
+			return nil, fmt.Errorf("%w: anthropic does not accept %s parts", inferrouter.ErrInvalidRequest, p.Type)
+		}
+	}
+	for _, tc := range m.ToolCalls {
+		input := json.RawMessage(tc.Function.Arguments)
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		blocks = append(blocks, apiBlock{Type: "tool_use", ID: tc.ID, Name: tc.Function.Name, Input: input})
+	}
+	return blocks, nil
+}
+
+// buildTools maps tool definitions; Anthropic requires an input schema, so
+// a function without parameters gets an empty object schema.
+func buildTools(tools []inferrouter.Tool) []apiTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make([]apiTool, len(tools))
+	for i, t := range tools {
+		schema := t.Function.Parameters
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object"}`)
+		}
+		out[i] = apiTool{Name: t.Function.Name, Description: t.Function.Description, InputSchema: schema}
+	}
+	return out
+}
+
+// buildToolChoice maps ToolChoice: required is "any", a forced function is
+// "tool" with its name.
+func buildToolChoice(tc *inferrouter.ToolChoice) *apiToolChoice {
+	if tc == nil {
+		return nil
+	}
+	if tc.Function != "" {
+		return &apiToolChoice{Type: "tool", Name: tc.Function}
+	}
+	switch tc.Mode {
+	case inferrouter.ToolChoiceAuto:
+		return &apiToolChoice{Type: "auto"}
+	case inferrouter.ToolChoiceNone:
+		return &apiToolChoice{Type: "none"}
+	case inferrouter.ToolChoiceRequired:
+		return &apiToolChoice{Type: "any"}
+	default:
+		return nil
+	}
+}
+
+func (p *Provider) doRequest(ctx context.Context, auth inferrouter.Auth, body apiRequest) (*http.Response, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("inferrouter: marshal anthropic request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/messages", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("inferrouter: create anthropic request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Api-Key", auth.APIKey)
+	httpReq.Header.Set("Anthropic-Version", apiVersion)
+	inferrouter.InjectTraceHeaders(ctx, httpReq.Header)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, inferrouter.ErrProviderUnavailable
+	}
+
+	return resp, nil
+}
+
+func mapHTTPError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	// Best-effort body read for diagnostics.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+
+	detail := ""
+	if err == nil && len(body) > 0 {
+		detail = string(body)
+	} else {
+		detail = http.StatusText(resp.StatusCode)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		wait, _ := rateLimits(resp.Header).Backoff()
+		return &inferrouter.RateLimitError{RetryAfter: wait, Detail: detail}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %s", inferrouter.ErrAuthFailed, detail)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", inferrouter.ErrModelNotFound, detail)
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		if inferrouter.IsContextLengthMessage(detail) {
+			return fmt.Errorf("%w: %s", inferrouter.ErrContextLengthExceeded, detail)
+		}
+		return fmt.Errorf("%w: %s", inferrouter.ErrInvalidRequest, detail)
+	default:
+		// 529 is Anthropic's "overloaded".
+		return fmt.Errorf("%w: HTTP %d: %s", inferrouter.ErrProviderUnavailable, resp.StatusCode, detail)
+	}
+}
+
+// rateLimits reads Anthropic's anthropic-ratelimit-* headers, whose resets
+// are RFC 3339 timestamps, on top of the standard Retry-After. Nil when the
+// response carried none of them.
+func rateLimits(h http.Header) *inferrouter.RateLimitInfo {
+	info := inferrouter.ParseRateLimitHeaders(h)
+	found := info != nil
+	if info == nil {
+		info = &inferrouter.RateLimitInfo{}
+	}
+	now := time.Now()
+	for _, kind := range []string{"requests", "tokens"} {
+		v := h.Get("Anthropic-Ratelimit-" + kind + "-Remaining")
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			continue
+		}
+		var reset time.Duration
+		if t, err := time.Parse(time.RFC3339, h.Get("Anthropic-Ratelimit-"+kind+"-Reset")); err == nil && t.After(now) {
+			reset = t.Sub(now)
+		}
+		found = true
+		if kind == "requests" {
+			info.RemainingRequests, info.ResetRequests = &n, reset
+		} else {
+			info.RemainingTokens, info.ResetTokens = &n, reset
+		}
+	}
+	if !found {
+		return nil
+	}
+	return info
+}
+
+// streamEvent is one SSE data payload; Type selects which fields are set.
+type streamEvent struct {
+	Type string `json:"type"`
+
+	Message *apiResponse `json:"message,omitempty"` // message_start
+
+	Index        int       `json:"index"`                   // content_block_*
+	ContentBlock *apiBlock `json:"content_block,omitempty"` // content_block_start
+
+	Delta *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`         // text_delta
+		PartialJSON string `json:"partial_json"` // input_json_delta
+		StopReason  string `json:"stop_reason"`  // message_delta
+	} `json:"delta,omitempty"`
+
+	Usage *apiUsage `json:"usage,omitempty"` // message_delta
+
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type sseStream struct {
+	reader    *bufio.Reader
+	body      io.ReadCloser
+	req       inferrouter.ProviderRequest
+	id        string
+	model     string
+	parseErrs int  // consecutive parse errors
+	stopped   bool // message_stop was seen
+
+	// usage accumulates message_start's input counts and message_delta's
+	// output count; every usage chunk carries the running total.
+	usage apiUsage
+
+	// toolIndex maps a content block index to its ToolCallDelta.Index.
+	toolIndex map[int]int
+
+	rateLimits *inferrouter.RateLimitInfo
+}
+
+var _ inferrouter.RateLimitReporter = (*sseStream)(nil)
+
+// RateLimits returns the rate limit headers the stream was opened with.
+func (s *sseStream) RateLimits() *inferrouter.RateLimitInfo { return s.rateLimits }
+
+func (s *sseStream) Next() (inferrouter.StreamChunk, error) {
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			// Only message_stop ends a reply; a body cut off before it is a
+			// dead stream the router may fail over.
+			if s.stopped {
+				return inferrouter.StreamChunk{}, io.EOF
+			}
+			return inferrouter.StreamChunk{}, fmt.Errorf("%w: stream ended before message_stop: %v", inferrouter.ErrProviderUnavailable, err)
+		}
+
+		// "event:" lines repeat the type carried in the data payload.
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+		var ev streamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			s.parseErrs++
+			if s.parseErrs >= 3 {
+				return inferrouter.StreamChunk{}, fmt.Errorf("inferrouter: %d consecutive malformed SSE chunks: %w", s.parseErrs, err)
+			}
+			continue
+		}
+		s.parseErrs = 0
+
+		chunk, ok, err := s.handle(ev)
+		if err != nil {
+			return inferrouter.StreamChunk{}, err
+		}
+		if ok {
+			return chunk, nil
+		}
+	}
+}
+
+// handle turns one event into a chunk. ok is false for events that carry
+// nothing for the caller (ping, content_block_stop, thinking deltas).
+func (s *sseStream) handle(ev streamEvent) (chunk inferrouter.StreamChunk, ok bool, err error) {
+	chunk = inferrouter.StreamChunk{ID: s.id, Model: s.model}
+
+	switch ev.Type {
+	case "message_start":
+		if ev.Message == nil {
+			return chunk, false, nil
+		}
+		s.id = ev.Message.ID
+		if ev.Message.Model != "" {
+			s.model = ev.Message.Model
+		}
+		s.usage = ev.Message.Usage
+		chunk.ID, chunk.Model = s.id, s.model
+		chunk.Choices = []inferrouter.StreamDelta{{Delta: inferrouter.Delta{Role: "assistant"}}}
+		u := buildUsage(s.usage, s.req)
+		chunk.Usage = &u
+		return chunk, true, nil
+
+	case "content_block_start":
+		if ev.ContentBlock == nil || ev.ContentBlock.Type != "tool_use" {
+			return chunk, false, nil
+		}
+		idx := len(s.toolIndex)
+		s.toolIndex[ev.Index] = idx
+		chunk.Choices = []inferrouter.StreamDelta{{Delta: inferrouter.Delta{ToolCalls: []inferrouter.ToolCallDelta{{
+			Index:    idx,
+			ID:       ev.ContentBlock.ID,
+			Type:     "function",
+			Function: inferrouter.FunctionCall{Name: ev.ContentBlock.Name},
+		}}}}}
+		return chunk, true, nil
+
+	case "content_block_delta":
+		if ev.Delta == nil {
+			return chunk, false, nil
+		}
+		switch ev.Delta.Type {
+		case "text_delta":
+			chunk.Choices = []inferrouter.StreamDelta{{Delta: inferrouter.Delta{Content: ev.Delta.Text}}}
+			return chunk, true, nil
+		case "input_json_delta":
+			idx, known := s.toolIndex[ev.Index]
+			if !known {
+				return chunk, false, nil
+			}
+			chunk.Choices = []inferrouter.StreamDelta{{Delta: inferrouter.Delta{ToolCalls: []inferrouter.ToolCallDelta{{
+				Index:    idx,
+				Function: inferrouter.FunctionCall{Arguments: ev.Delta.PartialJSON},
+			}}}}}
+			return chunk, true, nil
+		}
+		return chunk, false, nil
+
+	case "message_delta":
+		if ev.Usage != nil {
+			s.usage.OutputTokens = ev.Usage.OutputTokens
+		}
+		if ev.Delta != nil && ev.Delta.StopReason != "" {
+			chunk.Choices = []inferrouter.StreamDelta{{FinishReason: finishReason(ev.Delta.StopReason)}}
+		}
+		u := buildUsage(s.usage, s.req)
+		chunk.Usage = &u
+		return chunk, true, nil
+
+	case "message_stop":
+		s.stopped = true
+		return chunk, false, io.EOF
+
+	case "error":
+		return chunk, false, streamError(ev)
+	}
+	return chunk, false, nil
+}
+
+// streamError maps an error event sent after the stream opened.
+func streamError(ev streamEvent) error {
+	if ev.Error == nil {
+		return fmt.Errorf("%w: anthropic stream error", inferrouter.ErrProviderUnavailable)
+	}
+	detail := ev.Error.Type + ": " + ev.Error.Message
+	switch ev.Error.Type {
+	case "rate_limit_error":
+		return &inferrouter.RateLimitError{Detail: detail}
+	case "invalid_request_error":
+		return fmt.Errorf("%w: %s", inferrouter.ErrInvalidRequest, detail)
+	default:
+		return fmt.Errorf("%w: %s", inferrouter.ErrProviderUnavailable, detail)
+	}
+}
+
+func (s *sseStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,423 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ir "github.com/ineyio/inferrouter"
+)
+
+func TestName(t *testing.T) {
+	if New().Name() != "anthropic" {
+		t.Fatal("Name should be anthropic")
+	}
+}
+
+func TestSupportsModel(t *testing.T) {
+	p := New()
+	if !p.SupportsModel("claude-sonnet-4-5") {
+		t.Fatal("unfiltered should accept anything")
+	}
+	p = New(WithModels("claude-sonnet-4-5"))
+	if p.SupportsModel("claude-opus-4-1") {
+		t.Fatal("should reject non-configured model")
+	}
+}
+
+func TestBuildRequestSystemAndImages(t *testing.T) {
+	p := New()
+	req, err := p.buildRequest(ir.ProviderRequest{
+		Model: "claude-sonnet-4-5",
+		Messages: []ir.Message{
+			{Role: "system", Content: "Be brief."},
+			{Role: "system", Parts: []ir.Part{{Type: ir.PartText, Text: "Answer in French."}}},
+			{Role: "user", Parts: []ir.Part{
+				{Type: ir.PartText, Text: "what is this"},
+				{Type: ir.PartImage, MIMEType: "image/png", Data: []byte{1, 2, 3}},
+			}},
+		},
+		Stop: []string{"END"},
+	}, false)
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	if req.System != "Be brief.\n\nAnswer in French." {
+		t.Errorf("system = %q", req.System)
+	}
+	if len(req.Messages) != 1 || req.Messages[0].Role != "user" {
+		t.Fatalf("messages = %+v, want the user turn only", req.Messages)
+	}
+	img := req.Messages[0].Content[1]
+	if img.Type != "image" || img.Source == nil || img.Source.MediaType != "image/png" || img.Source.Data != "AQID" {
+		t.Errorf("image block = %+v", img)
+	}
+	if req.MaxTokens != defaultMaxTokens {
+		t.Errorf("max_tokens = %d, want the default %d", req.MaxTokens, defaultMaxTokens)
+	}
+	if len(req.StopSequences) != 1 || req.StopSequences[0] != "END" {
+		t.Errorf("stop_sequences = %v", req.StopSequences)
+	}
+}
+
+func TestBuildRequestRejectsAudio(t *testing.T) {
+	_, err := New().buildRequest(ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Parts: []ir.Part{{Type: ir.PartAudio, MIMEType: "audio/wav", Data: []byte{1}}}}},
+	}, false)
+	if !errors.Is(err, ir.ErrInvalidRequest) {
+		t.Errorf("err = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestBuildRequestTools(t *testing.T) {
+	p := New(WithDefaultMaxTokens(1000))
+	maxTokens := 64
+	req, err := p.buildRequest(ir.ProviderRequest{
+		Messages: []ir.Message{
+			{Role: "user", Content: "weather in Paris and Rome?"},
+			{Role: "assistant", ToolCalls: []ir.ToolCall{
+				{ID: "toolu_1", Type: "function", Function: ir.FunctionCall{Name: "weather", Arguments: `{"city":"Paris"}`}},
+				{ID: "toolu_2", Type: "function", Function: ir.FunctionCall{Name: "weather", Arguments: `{"city":"Rome"}`}},
+			}},
+			{Role: "tool", ToolCallID: "toolu_1", Content: "sunny"},
+			{Role: "tool", ToolCallID: "toolu_2", Content: "rain"},
+		},
+		MaxTokens:  &maxTokens,
+		Tools:      []ir.Tool{{Type: "function", Function: ir.ToolFunction{Name: "weather"}}},
+		ToolChoice: &ir.ToolChoice{Mode: ir.ToolChoiceRequired},
+	}, false)
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	if req.MaxTokens != 64 {
+		t.Errorf("max_tokens = %d, want the request's 64", req.MaxTokens)
+	}
+	if len(req.Messages) != 3 {
+		t.Fatalf("messages = %d, want user, assistant, one user with both results", len(req.Messages))
+	}
+	use := req.Messages[1].Content
+	if len(use) != 2 || use[0].Type != "tool_use" || use[0].ID != "toolu_1" || string(use[0].Input) != `{"city":"Paris"}` {
+		t.Errorf("tool_use blocks = %+v", use)
+	}
+	results := req.Messages[2]
+	if results.Role != "user" || len(results.Content) != 2 || results.Content[1].ToolUseID != "toolu_2" || results.Content[1].Content != "rain" {
+		t.Errorf("tool_result message = %+v", results)
+	}
+	if len(req.Tools) != 1 || string(req.Tools[0].InputSchema) != `{"type":"object"}` {
+		t.Errorf("tools = %+v, want an empty object schema filled in", req.Tools)
+	}
+	if req.ToolChoice == nil || req.ToolChoice.Type != "any" {
+		t.Errorf("tool_choice = %+v, want any", req.ToolChoice)
+	}
+}
+
+func TestChatCompletionHappyPath(t *testing.T) {
+	var gotBody apiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		if r.Header.Get("X-Api-Key") != "k" || r.Header.Get("Anthropic-Version") != apiVersion {
+			t.Errorf("headers = %v", r.Header)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{
+			"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-4-5",
+			"content": [
+				{"type": "text", "text": "Let me check."},
+				{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Paris"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 10, "output_tokens": 20, "cache_creation_input_tokens": 100, "cache_read_input_tokens": 1000}
+		}`)
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL))
+	resp, err := p.ChatCompletion(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "claude-sonnet-4-5",
+		Messages: []ir.Message{{Role: "user", Content: "weather?"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if gotBody.Model != "claude-sonnet-4-5" || gotBody.Stream {
+		t.Errorf("request = %+v", gotBody)
+	}
+	if resp.ID != "msg_1" || resp.Content != "Let me check." || resp.FinishReason != "tool_calls" {
+		t.Errorf("resp = %+v", resp)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "toolu_1" || resp.ToolCalls[0].Function.Arguments != `{"city": "Paris"}` {
+		t.Errorf("tool calls = %+v", resp.ToolCalls)
+	}
+	want := ir.Usage{PromptTokens: 1110, CompletionTokens: 20, TotalTokens: 1130, CachedTokens: 1100,
+		InputBreakdown: &ir.InputTokenBreakdown{Text: 1110}}
+	if resp.Usage.PromptTokens != want.PromptTokens || resp.Usage.CompletionTokens != want.CompletionTokens ||
+		resp.Usage.TotalTokens != want.TotalTokens || resp.Usage.CachedTokens != want.CachedTokens ||
+		resp.Usage.InputBreakdown == nil || *resp.Usage.InputBreakdown != *want.InputBreakdown {
+		t.Errorf("usage = %+v, want %+v", resp.Usage, want)
+	}
+}
+
+func TestChatCompletionErrorMapping(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"429", http.StatusTooManyRequests, "", ir.ErrRateLimited},
+		{"401", http.StatusUnauthorized, "", ir.ErrAuthFailed},
+		{"403", http.StatusForbidden, "", ir.ErrAuthFailed},
+		{"404", http.StatusNotFound, "", ir.ErrModelNotFound},
+		{"400", http.StatusBadRequest, "", ir.ErrInvalidRequest},
+		{"400 context", http.StatusBadRequest,
+			`{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`,
+			ir.ErrContextLengthExceeded},
+		{"500", http.StatusInternalServerError, "", ir.ErrProviderUnavailable},
+		{"529", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ir.ErrProviderUnavailable},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := tc.body
+			if body == "" {
+				body = `{"type":"error","error":{"type":"api_error","message":"nope"}}`
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, body, tc.status)
+			}))
+			defer srv.Close()
+
+			p := New(WithBaseURL(srv.URL))
+			_, err := p.ChatCompletion(context.Background(), ir.ProviderRequest{
+				Auth:     ir.Auth{APIKey: "k"},
+				Model:    "m",
+				Messages: []ir.Message{{Role: "user", Content: "hi"}},
+			})
+			if !errors.Is(err, tc.want) {
+				t.Errorf("err = %v, want Is=%v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestChatCompletionRateLimitHeaders(t *testing.T) {
+	reset := time.Now().Add(30 * time.Second).UTC().Format(time.RFC3339)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Anthropic-Ratelimit-Requests-Remaining", "0")
+		w.Header().Set("Anthropic-Ratelimit-Requests-Reset", reset)
+		w.Header().Set("Anthropic-Ratelimit-Tokens-Remaining", "5000")
+		_, _ = io.WriteString(w, `{"id":"msg_1","model":"m","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`)
+	}))
+	defer srv.Close()
+
+	resp, err := New(WithBaseURL(srv.URL)).ChatCompletion(context.Background(), ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	rl := resp.RateLimits
+	if rl == nil || rl.RemainingRequests == nil || *rl.RemainingRequests != 0 || rl.RemainingTokens == nil || *rl.RemainingTokens != 5000 {
+		t.Fatalf("rate limits = %+v", rl)
+	}
+	if wait, stop := rl.Backoff(); !stop || wait <= 0 || wait > 30*time.Second {
+		t.Errorf("Backoff = %v, %v; want up to 30s", wait, stop)
+	}
+	if resp.FinishReason != "stop" {
+		t.Errorf("finish reason = %q, want stop", resp.FinishReason)
+	}
+}
+
+const streamBody = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"usage":{"input_tokens":25,"cache_read_input_tokens":5,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type": "ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" there"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":15}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+func TestChatCompletionStreamHappyPath(t *testing.T) {
+	var gotBody apiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, streamBody)
+	}))
+	defer srv.Close()
+
+	stream, err := New(WithBaseURL(srv.URL)).ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Model:    "claude-sonnet-4-5",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	defer stream.Close()
+	if !gotBody.Stream {
+		t.Error("request did not ask for a stream")
+	}
+
+	var (
+		text   strings.Builder
+		args   strings.Builder
+		name   string
+		finish string
+		usage  *ir.Usage
+	)
+	for {
+		chunk, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if chunk.ID != "msg_1" {
+			t.Errorf("chunk id = %q", chunk.ID)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, c := range chunk.Choices {
+			text.WriteString(c.Delta.Content)
+			for _, tc := range c.Delta.ToolCalls {
+				if tc.Index != 0 {
+					t.Errorf("tool call index = %d, want 0", tc.Index)
+				}
+				if tc.Function.Name != "" {
+					name = tc.Function.Name
+				}
+				args.WriteString(tc.Function.Arguments)
+			}
+			if c.FinishReason != "" {
+				finish = c.FinishReason
+			}
+		}
+	}
+
+	if text.String() != "Hello there" {
+		t.Errorf("text = %q", text.String())
+	}
+	if name != "weather" || args.String() != `{"city":"Paris"}` {
+		t.Errorf("tool call = %s(%s)", name, args.String())
+	}
+	if finish != "tool_calls" {
+		t.Errorf("finish = %q, want tool_calls", finish)
+	}
+	if usage == nil || usage.PromptTokens != 30 || usage.CompletionTokens != 15 || usage.TotalTokens != 45 || usage.CachedTokens != 5 {
+		t.Errorf("usage = %+v, want 30 prompt (5 cached) + 15", usage)
+	}
+}
+
+func TestChatCompletionStreamErrorEvent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","model":"m","usage":{"input_tokens":3,"output_tokens":0}}}
+
+event: error
+data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}
+
+`)
+	}))
+	defer srv.Close()
+
+	stream, err := New(WithBaseURL(srv.URL)).ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("first Next: %v", err)
+	}
+	if _, err := stream.Next(); !errors.Is(err, ir.ErrProviderUnavailable) {
+		t.Errorf("err = %v, want ErrProviderUnavailable", err)
+	}
+}
+
+// A body cut off before message_stop is a truncated reply, not a clean end.
+func TestChatCompletionStreamTruncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","model":"m","usage":{"input_tokens":3,"output_tokens":0}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}
+
+`)
+	}))
+	defer srv.Close()
+
+	stream, err := New(WithBaseURL(srv.URL)).ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	defer stream.Close()
+
+	for range 2 {
+		if _, err := stream.Next(); err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+	}
+	_, err = stream.Next()
+	if errors.Is(err, io.EOF) || !errors.Is(err, ir.ErrProviderUnavailable) {
+		t.Errorf("err = %v, want ErrProviderUnavailable", err)
+	}
+}
+
+func TestChatCompletionStreamErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := New(WithBaseURL(srv.URL)).ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if !errors.Is(err, ir.ErrAuthFailed) {
+		t.Errorf("err = %v, want ErrAuthFailed", err)
+	}
+}
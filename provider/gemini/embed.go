@@ -168,6 +168,7 @@ func (p *Provider) doEmbedRequest(ctx context.Context, url string, body geminiEm
 		return nil, fmt.Errorf("inferrouter: create gemini embed request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	inferrouter.InjectTraceHeaders(ctx, httpReq.Header)
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
@@ -11,6 +11,9 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/ineyio/inferrouter"
 )
@@ -25,7 +28,11 @@ type Provider struct {
 	logger     *slog.Logger
 }
 
-var _ inferrouter.Provider = (*Provider)(nil)
+var (
+	_ inferrouter.Provider        = (*Provider)(nil)
+	_ inferrouter.ToolProvider    = (*Provider)(nil)
+	_ inferrouter.ChoicesProvider = (*Provider)(nil)
+)
 
 // Option configures the provider.
 type Option func(*Provider)
@@ -83,20 +90,67 @@ func (p *Provider) SupportsModel(model string) bool {
 // SupportsMultimodal reports that Gemini accepts media parts (image/audio/video).
 func (p *Provider) SupportsMultimodal() bool { return true }
 
+// SupportsTools reports that Gemini function calling is serialized
+// (functionDeclarations / functionCall / functionResponse).
+func (p *Provider) SupportsTools() bool { return true }
+
+// SupportsChoices reports that N is sent as candidateCount and every
+// candidate is mapped.
+func (p *Provider) SupportsChoices() bool { return true }
+
 // Gemini API types.
 type geminiRequest struct {
-	Contents         []geminiContent         `json:"contents"`
-	GenerationConfig *geminiGenerationConfig `json:"generationConfig,omitempty"`
+	SystemInstruction *geminiContent              `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent             `json:"contents"`
+	Tools             []geminiTool                `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig           `json:"toolConfig,omitempty"`
+	SafetySettings    []inferrouter.SafetySetting `json:"safetySettings,omitempty"`
+	GenerationConfig  *geminiGenerationConfig     `json:"generationConfig,omitempty"`
 }
 
+// geminiContent is one conversation turn. Role is empty only for
+// systemInstruction, which takes no role.
 type geminiContent struct {
-	Role  string       `json:"role"`
+	Role  string       `json:"role,omitempty"`
 	Parts []geminiPart `json:"parts"`
 }
 
 type geminiPart struct {
-	Text       string            `json:"text,omitempty"`
-	InlineData *geminiInlineData `json:"inline_data,omitempty"`
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *geminiInlineData       `json:"inline_data,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig geminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
 }
 
 type geminiInlineData struct {
@@ -109,6 +163,14 @@ type geminiGenerationConfig struct {
 	MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
 	TopP            *float64 `json:"topP,omitempty"`
 	StopSequences   []string `json:"stopSequences,omitempty"`
+
+	CandidateCount   *int     `json:"candidateCount,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+
+	ResponseMIMEType   string          `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
 }
 
 type geminiTokenDetail struct {
@@ -125,14 +187,16 @@ type geminiUsageMetadata struct {
 }
 
 type geminiResponse struct {
-	Candidates []struct {
-		Content      geminiContent `json:"content"`
-		FinishReason string        `json:"finishReason"`
-	} `json:"candidates"`
+	Candidates    []geminiCandidate   `json:"candidates"`
 	UsageMetadata geminiUsageMetadata `json:"usageMetadata"`
 	ModelVersion  string              `json:"modelVersion"`
 }
 
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
 func (p *Provider) ChatCompletion(ctx context.Context, req inferrouter.ProviderRequest) (inferrouter.ProviderResponse, error) {
 	body := p.buildRequest(req)
 	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", p.baseURL, req.Model, req.Auth.APIKey)
@@ -156,18 +220,71 @@ func (p *Provider) ChatCompletion(ctx context.Context, req inferrouter.ProviderR
 		return inferrouter.ProviderResponse{}, fmt.Errorf("inferrouter: empty candidates in gemini response")
 	}
 
-	content := ""
-	if len(resp.Candidates[0].Content.Parts) > 0 {
-		content = resp.Candidates[0].Content.Parts[0].Text
-	}
-
-	return inferrouter.ProviderResponse{
+	first := candidateChoice(resp.Candidates[0])
+	out := inferrouter.ProviderResponse{
 		ID:           "",
-		Content:      content,
-		FinishReason: strings.ToLower(resp.Candidates[0].FinishReason),
+		Content:      first.Message.Content,
+		ToolCalls:    first.Message.ToolCalls,
+		FinishReason: first.FinishReason,
 		Model:        req.Model,
 		Usage:        p.buildUsage(resp.UsageMetadata, req),
-	}, nil
+		RateLimits:   inferrouter.ParseRateLimitHeaders(httpResp.Header),
+	}
+	if len(resp.Candidates) > 1 {
+		out.Choices = make([]inferrouter.Choice, len(resp.Candidates))
+		for i, cand := range resp.Candidates {
+			out.Choices[i] = candidateChoice(cand)
+			out.Choices[i].Index = i
+		}
+	}
+	return out, nil
+}
+
+// candidateChoice maps one candidate to a Choice with Index 0.
+func candidateChoice(cand geminiCandidate) inferrouter.Choice {
+	content := ""
+	if len(cand.Content.Parts) > 0 {
+		content = cand.Content.Parts[0].Text
+	}
+
+	toolCalls := extractToolCalls(cand.Content.Parts)
+	finishReason := strings.ToLower(cand.FinishReason)
+	if len(toolCalls) > 0 {
+		// Gemini reports STOP for a turn that ends in function calls; the
+		// OpenAI vocabulary callers branch on is "tool_calls".
+		finishReason = "tool_calls"
+	}
+
+	return inferrouter.Choice{
+		Message:      inferrouter.Message{Role: "assistant", Content: content, ToolCalls: toolCalls},
+		FinishReason: finishReason,
+	}
+}
+
+// extractToolCalls collects functionCall parts as ToolCalls. Gemini only
+// recently started returning call ids; when absent one is synthesized so the
+// caller can correlate its "tool" result message the same way as with OpenAI.
+func extractToolCalls(parts []geminiPart) []inferrouter.ToolCall {
+	var calls []inferrouter.ToolCall
+	for _, part := range parts {
+		if part.FunctionCall == nil {
+			continue
+		}
+		id := part.FunctionCall.ID
+		if id == "" {
+			id = "call_" + uuid.NewString()
+		}
+		args := string(part.FunctionCall.Args)
+		if args == "" {
+			args = "{}"
+		}
+		calls = append(calls, inferrouter.ToolCall{
+			ID:       id,
+			Type:     "function",
+			Function: inferrouter.FunctionCall{Name: part.FunctionCall.Name, Arguments: args},
+		})
+	}
+	return calls
 }
 
 // buildUsage maps Gemini usageMetadata to inferrouter.Usage.
@@ -229,6 +346,9 @@ func (p *Provider) buildBreakdown(details []geminiTokenDetail) *inferrouter.Inpu
 }
 
 func (p *Provider) ChatCompletionStream(ctx context.Context, req inferrouter.ProviderRequest) (inferrouter.ProviderStream, error) {
+	// Chunks of several candidates would interleave on one stream, and
+	// the reader follows the first; streams always ask for one.
+	req.N = nil
 	body := p.buildRequest(req)
 	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse&key=%s", p.baseURL, req.Model, req.Auth.APIKey)
 
@@ -243,17 +363,45 @@ func (p *Provider) ChatCompletionStream(ctx context.Context, req inferrouter.Pro
 	}
 
 	return &geminiStream{
-		reader: bufio.NewReader(httpResp.Body),
-		body:   httpResp.Body,
-		model:  req.Model,
-		req:    req,
-		prov:   p,
+		reader:     bufio.NewReader(httpResp.Body),
+		body:       httpResp.Body,
+		model:      req.Model,
+		req:        req,
+		prov:       p,
+		rateLimits: inferrouter.ParseRateLimitHeaders(httpResp.Header),
 	}, nil
 }
 
 func (p *Provider) buildRequest(req inferrouter.ProviderRequest) geminiRequest {
-	var contents []geminiContent
+	callNames := toolCallNames(req.Messages)
+
+	var (
+		system   *geminiContent
+		contents []geminiContent
+	)
 	for _, m := range req.Messages {
+		if m.Role == "system" {
+			// Gemini has no system role inside contents; every system
+			// message goes to the one systemInstruction, in order.
+			if system == nil {
+				system = &geminiContent{}
+			}
+			system.Parts = append(system.Parts, buildParts(m)...)
+			continue
+		}
+		if m.Role == "tool" {
+			part := buildFunctionResponsePart(m, callNames)
+			// All results answering one model turn travel in a single
+			// content: Gemini pairs functionResponse parts with the
+			// functionCall parts of the preceding turn.
+			if n := len(contents); n > 0 && contents[n-1].Role == "user" && isFunctionResponseContent(contents[n-1]) {
+				contents[n-1].Parts = append(contents[n-1].Parts, part)
+				continue
+			}
+			contents = append(contents, geminiContent{Role: "user", Parts: []geminiPart{part}})
+			continue
+		}
+
 		role := m.Role
 		if role == "assistant" {
 			role = "model"
@@ -264,23 +412,154 @@ func (p *Provider) buildRequest(req inferrouter.ProviderRequest) geminiRequest {
 		})
 	}
 
-	gr := geminiRequest{Contents: contents}
+	gr := geminiRequest{
+		SystemInstruction: system,
+		Contents:          contents,
+		Tools:             buildTools(req.Tools),
+		ToolConfig:        buildToolConfig(req.ToolChoice),
+		SafetySettings:    req.SafetySettings,
+	}
 
-	if req.Temperature != nil || req.MaxTokens != nil || req.TopP != nil || len(req.Stop) > 0 {
+	mimeType, schema := responseFormat(req.ResponseFormat)
+	if req.Temperature != nil || req.MaxTokens != nil || req.TopP != nil || len(req.Stop) > 0 || mimeType != "" ||
+		req.N != nil || req.Seed != nil || req.PresencePenalty != nil || req.FrequencyPenalty != nil {
 		gr.GenerationConfig = &geminiGenerationConfig{
-			Temperature:     req.Temperature,
-			MaxOutputTokens: req.MaxTokens,
-			TopP:            req.TopP,
-			StopSequences:   req.Stop,
+			Temperature:        req.Temperature,
+			MaxOutputTokens:    req.MaxTokens,
+			TopP:               req.TopP,
+			StopSequences:      req.Stop,
+			CandidateCount:     req.N,
+			Seed:               req.Seed,
+			PresencePenalty:    req.PresencePenalty,
+			FrequencyPenalty:   req.FrequencyPenalty,
+			ResponseMIMEType:   mimeType,
+			ResponseJSONSchema: schema,
 		}
 	}
 
 	return gr
 }
 
+// responseFormat maps ResponseFormat to Gemini's JSON mode. The schema goes
+// to responseJsonSchema, which accepts standard JSON Schema, rather than the
+// OpenAPI-subset responseSchema.
+func responseFormat(rf *inferrouter.ResponseFormat) (string, json.RawMessage) {
+	if rf == nil {
+		return "", nil
+	}
+	switch rf.Type {
+	case inferrouter.ResponseFormatJSON:
+		return "application/json", nil
+	case inferrouter.ResponseFormatJSONSchema:
+		return "application/json", rf.Schema
+	default:
+		return "", nil
+	}
+}
+
+// buildTools maps tool definitions to a single Gemini tool holding all
+// function declarations.
+func buildTools(tools []inferrouter.Tool) []geminiTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	decls := make([]geminiFunctionDeclaration, len(tools))
+	for i, t := range tools {
+		decls[i] = geminiFunctionDeclaration{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  t.Function.Parameters,
+		}
+	}
+	return []geminiTool{{FunctionDeclarations: decls}}
+}
+
+// buildToolConfig maps ToolChoice to functionCallingConfig. A forced
+// function becomes mode ANY restricted to that one name.
+func buildToolConfig(tc *inferrouter.ToolChoice) *geminiToolConfig {
+	if tc == nil {
+		return nil
+	}
+	if tc.Function != "" {
+		return &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{
+			Mode:                 "ANY",
+			AllowedFunctionNames: []string{tc.Function},
+		}}
+	}
+	var mode string
+	switch tc.Mode {
+	case inferrouter.ToolChoiceAuto:
+		mode = "AUTO"
+	case inferrouter.ToolChoiceNone:
+		mode = "NONE"
+	case inferrouter.ToolChoiceRequired:
+		mode = "ANY"
+	default:
+		return nil
+	}
+	return &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{Mode: mode}}
+}
+
+// toolCallNames indexes the function name of every tool call in the history
+// by call id. Gemini keys functionResponse by name, while the OpenAI-shaped
+// "tool" message only has to carry the id.
+func toolCallNames(msgs []inferrouter.Message) map[string]string {
+	names := make(map[string]string)
+	for _, m := range msgs {
+		for _, tc := range m.ToolCalls {
+			names[tc.ID] = tc.Function.Name
+		}
+	}
+	return names
+}
+
+// buildFunctionResponsePart maps a "tool" role message to a functionResponse
+// part. Gemini requires the response to be a JSON object: a result that
+// already is one is passed through, anything else is wrapped as
+// {"content": ...}.
+func buildFunctionResponsePart(m inferrouter.Message, callNames map[string]string) geminiPart {
+	name := m.Name
+	if name == "" {
+		name = callNames[m.ToolCallID]
+	}
+
+	var response json.RawMessage
+	trimmed := strings.TrimSpace(m.Content)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		response = json.RawMessage(trimmed)
+	} else {
+		wrapped, _ := json.Marshal(map[string]string{"content": m.Content})
+		response = wrapped
+	}
+
+	return geminiPart{FunctionResponse: &geminiFunctionResponse{Name: name, Response: response}}
+}
+
+func isFunctionResponseContent(c geminiContent) bool {
+	return len(c.Parts) > 0 && c.Parts[0].FunctionResponse != nil
+}
+
 // buildParts maps inferrouter.Message to Gemini parts. If m.Parts is empty,
-// falls back to m.Content as a single text part (legacy path).
+// falls back to m.Content as a single text part (legacy path). Assistant
+// tool calls follow as functionCall parts.
 func buildParts(m inferrouter.Message) []geminiPart {
+	if len(m.ToolCalls) > 0 {
+		var parts []geminiPart
+		if m.Content != "" {
+			parts = append(parts, geminiPart{Text: m.Content})
+		}
+		for _, tc := range m.ToolCalls {
+			args := json.RawMessage(tc.Function.Arguments)
+			if len(args) == 0 {
+				args = json.RawMessage("{}")
+			}
+			parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{
+				Name: tc.Function.Name,
+				Args: args,
+			}})
+		}
+		return parts
+	}
 	if len(m.Parts) == 0 {
 		return []geminiPart{{Text: m.Content}}
 	}
@@ -313,6 +592,7 @@ func (p *Provider) doRequest(ctx context.Context, url string, body geminiRequest
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
+	inferrouter.InjectTraceHeaders(ctx, httpReq.Header)
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
@@ -327,8 +607,13 @@ func mapHTTPError(resp *http.Response) error {
 		return nil
 	}
 
-	// Best-effort body read for diagnostics.
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	// Best-effort body read for diagnostics. A 429 body is read further:
+	// the RetryInfo detail comes after a QuotaFailure listing.
+	limit := int64(1024)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		limit = 16 << 10
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
 	resp.Body.Close()
 
 	detail := ""
@@ -340,30 +625,77 @@ func mapHTTPError(resp *http.Response) error {
 
 	switch resp.StatusCode {
 	case http.StatusTooManyRequests:
-		return fmt.Errorf("%w: %s", inferrouter.ErrRateLimited, detail)
+		wait, _ := inferrouter.ParseRateLimitHeaders(resp.Header).Backoff()
+		if wait == 0 {
+			wait = retryDelay(body)
+		}
+		return &inferrouter.RateLimitError{RetryAfter: wait, Detail: detail}
 	case http.StatusUnauthorized, http.StatusForbidden:
 		return fmt.Errorf("%w: %s", inferrouter.ErrAuthFailed, detail)
 	case http.StatusBadRequest:
+		if inferrouter.IsContextLengthMessage(detail) {
+			return fmt.Errorf("%w: %s", inferrouter.ErrContextLengthExceeded, detail)
+		}
 		return fmt.Errorf("%w: %s", inferrouter.ErrInvalidRequest, detail)
 	default:
 		return fmt.Errorf("%w: HTTP %d: %s", inferrouter.ErrProviderUnavailable, resp.StatusCode, detail)
 	}
 }
 
+// retryDelay extracts google.rpc.RetryInfo.retryDelay ("27s") from a Gemini
+// error body. Gemini reports its back-off there rather than in Retry-After.
+func retryDelay(body []byte) time.Duration {
+	var e struct {
+		Error struct {
+			Details []struct {
+				Type       string `json:"@type"`
+				RetryDelay string `json:"retryDelay"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &e) != nil {
+		return 0
+	}
+	for _, d := range e.Error.Details {
+		if !strings.HasSuffix(d.Type, "google.rpc.RetryInfo") {
+			continue
+		}
+		if delay, err := time.ParseDuration(d.RetryDelay); err == nil && delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
 type geminiStream struct {
 	reader    *bufio.Reader
 	body      io.ReadCloser
 	model     string
 	req       inferrouter.ProviderRequest
 	prov      *Provider
-	parseErrs int // consecutive parse errors
+	parseErrs int  // consecutive parse errors
+	toolCalls int  // tool calls emitted so far; next ToolCallDelta.Index
+	finished  bool // a finishReason was seen
+
+	rateLimits *inferrouter.RateLimitInfo
 }
 
+var _ inferrouter.RateLimitReporter = (*geminiStream)(nil)
+
+// RateLimits returns the rate limit headers the stream was opened with.
+// Gemini rarely sends any outside a 429; nil then.
+func (s *geminiStream) RateLimits() *inferrouter.RateLimitInfo { return s.rateLimits }
+
 func (s *geminiStream) Next() (inferrouter.StreamChunk, error) {
 	for {
 		line, err := s.reader.ReadString('\n')
 		if err != nil {
-			return inferrouter.StreamChunk{}, io.EOF
+			// Gemini sends no end marker; the body ends after the event that
+			// carries finishReason. Ending before it means the stream died.
+			if err == io.EOF && s.finished {
+				return inferrouter.StreamChunk{}, io.EOF
+			}
+			return inferrouter.StreamChunk{}, fmt.Errorf("%w: stream ended before finishReason: %v", inferrouter.ErrProviderUnavailable, err)
 		}
 
 		line = strings.TrimSpace(line)
@@ -387,15 +719,24 @@ func (s *geminiStream) Next() (inferrouter.StreamChunk, error) {
 			Model: s.model,
 		}
 
+		if len(resp.Candidates) > 0 && resp.Candidates[0].FinishReason != "" {
+			s.finished = true
+		}
 		if len(resp.Candidates) > 0 && len(resp.Candidates[0].Content.Parts) > 0 {
 			chunk.Choices = []inferrouter.StreamDelta{
 				{
 					Index: 0,
-					Delta: inferrouter.Delta{Content: resp.Candidates[0].Content.Parts[0].Text},
+					Delta: inferrouter.Delta{
+						Content:   resp.Candidates[0].Content.Parts[0].Text,
+						ToolCalls: s.toolCallDeltas(resp.Candidates[0].Content.Parts),
+					},
 				},
 			}
 			if resp.Candidates[0].FinishReason != "" {
 				chunk.Choices[0].FinishReason = strings.ToLower(resp.Candidates[0].FinishReason)
+				if s.toolCalls > 0 {
+					chunk.Choices[0].FinishReason = "tool_calls"
+				}
 			}
 		}
 
@@ -408,6 +749,27 @@ func (s *geminiStream) Next() (inferrouter.StreamChunk, error) {
 	}
 }
 
+// toolCallDeltas maps functionCall parts of one SSE event. Gemini streams each
+// call whole, so every delta carries the id, name and complete arguments; the
+// index keeps counting across events as the OpenAI format expects.
+func (s *geminiStream) toolCallDeltas(parts []geminiPart) []inferrouter.ToolCallDelta {
+	calls := extractToolCalls(parts)
+	if len(calls) == 0 {
+		return nil
+	}
+	deltas := make([]inferrouter.ToolCallDelta, len(calls))
+	for i, c := range calls {
+		deltas[i] = inferrouter.ToolCallDelta{
+			Index:    s.toolCalls,
+			ID:       c.ID,
+			Type:     c.Type,
+			Function: c.Function,
+		}
+		s.toolCalls++
+	}
+	return deltas
+}
+
 func (s *geminiStream) Close() error {
 	return s.body.Close()
 }
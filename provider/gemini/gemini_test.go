@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	ir "github.com/ineyio/inferrouter"
 )
@@ -81,6 +82,114 @@ func TestBuildRequestNoGenerationConfig(t *testing.T) {
 	}
 }
 
+func TestBuildRequestSystemInstruction(t *testing.T) {
+	p := New()
+	req := p.buildRequest(ir.ProviderRequest{
+		Messages: []ir.Message{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: "hi"},
+			{Role: "system", Parts: []ir.Part{{Type: ir.PartText, Text: "Answer in French."}}},
+		},
+	})
+	if len(req.Contents) != 1 || req.Contents[0].Role != "user" {
+		t.Fatalf("contents = %+v, want the user turn only", req.Contents)
+	}
+	si := req.SystemInstruction
+	if si == nil || si.Role != "" || len(si.Parts) != 2 || si.Parts[0].Text != "Be brief." || si.Parts[1].Text != "Answer in French." {
+		t.Fatalf("systemInstruction = %+v", si)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"systemInstruction":{"parts":[{"text":"Be brief."}`) {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestBuildRequestGenerationParams(t *testing.T) {
+	p := New()
+	n, seed := 2, 42
+	presence, frequency := 0.5, -0.25
+	req := p.buildRequest(ir.ProviderRequest{
+		Messages:         []ir.Message{{Role: "user", Content: "hi"}},
+		N:                &n,
+		Seed:             &seed,
+		PresencePenalty:  &presence,
+		FrequencyPenalty: &frequency,
+		SafetySettings:   []ir.SafetySetting{{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH"}},
+	})
+	gc := req.GenerationConfig
+	if gc == nil {
+		t.Fatal("generationConfig = nil")
+	}
+	if *gc.CandidateCount != 2 || *gc.Seed != 42 || *gc.PresencePenalty != 0.5 || *gc.FrequencyPenalty != -0.25 {
+		t.Errorf("generationConfig = %+v", gc)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"safetySettings":[{"category":"HARM_CATEGORY_HARASSMENT","threshold":"BLOCK_ONLY_HIGH"}]`,
+		`"candidateCount":2`, `"seed":42`, `"presencePenalty":0.5`, `"frequencyPenalty":-0.25`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("body missing %s: %s", want, body)
+		}
+	}
+}
+
+func TestChatCompletionReturnsEveryCandidate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"candidates":[
+			{"content":{"role":"model","parts":[{"text":"one"}]},"finishReason":"STOP"},
+			{"content":{"role":"model","parts":[{"text":"two"}]},"finishReason":"MAX_TOKENS"}
+		],"usageMetadata":{"promptTokenCount":2,"candidatesTokenCount":6,"totalTokenCount":8}}`)
+	}))
+	defer srv.Close()
+
+	n := 2
+	resp, err := New(WithBaseURL(srv.URL)).ChatCompletion(context.Background(), ir.ProviderRequest{
+		Model:    "m",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+		N:        &n,
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if resp.Content != "one" || len(resp.Choices) != 2 {
+		t.Fatalf("resp = %+v", resp)
+	}
+	if c := resp.Choices[1]; c.Index != 1 || c.Message.Content != "two" || c.FinishReason != "max_tokens" {
+		t.Errorf("choice 1 = %+v", c)
+	}
+}
+
+func TestChatCompletionStreamAsksForOneCandidate(t *testing.T) {
+	var got geminiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "text/event-stream")
+	}))
+	defer srv.Close()
+
+	n := 3
+	stream, err := New(WithBaseURL(srv.URL)).ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+		N:        &n,
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	stream.Close()
+	if got.GenerationConfig != nil && got.GenerationConfig.CandidateCount != nil {
+		t.Errorf("candidateCount = %d, want unset on a stream", *got.GenerationConfig.CandidateCount)
+	}
+}
+
 func TestChatCompletionHappyPath(t *testing.T) {
 	var gotURL string
 	var gotBody geminiRequest
@@ -153,18 +262,26 @@ func TestChatCompletionErrorMapping(t *testing.T) {
 	cases := []struct {
 		name   string
 		status int
+		body   string
 		want   error
 	}{
-		{"429", http.StatusTooManyRequests, ir.ErrRateLimited},
-		{"401", http.StatusUnauthorized, ir.ErrAuthFailed},
-		{"403", http.StatusForbidden, ir.ErrAuthFailed},
-		{"400", http.StatusBadRequest, ir.ErrInvalidRequest},
-		{"500", http.StatusInternalServerError, ir.ErrProviderUnavailable},
+		{"429", http.StatusTooManyRequests, "", ir.ErrRateLimited},
+		{"401", http.StatusUnauthorized, "", ir.ErrAuthFailed},
+		{"403", http.StatusForbidden, "", ir.ErrAuthFailed},
+		{"400", http.StatusBadRequest, "", ir.ErrInvalidRequest},
+		{"500", http.StatusInternalServerError, "", ir.ErrProviderUnavailable},
+		{"400 context", http.StatusBadRequest,
+			`{"error":{"code":400,"message":"The input token count (1200000) exceeds the maximum number of tokens allowed (1048576).","status":"INVALID_ARGUMENT"}}`,
+			ir.ErrContextLengthExceeded},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
+			body := tc.body
+			if body == "" {
+				body = `{"error":"nope"}`
+			}
 			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				http.Error(w, `{"error":"nope"}`, tc.status)
+				http.Error(w, body, tc.status)
 			}))
 			defer srv.Close()
 
@@ -300,6 +417,34 @@ func TestChatCompletionStreamFailsOnPersistentMalformed(t *testing.T) {
 	}
 }
 
+// A body that ends before any finishReason is a dead stream: it must surface
+// as ErrProviderUnavailable so the router can fail over, not as io.EOF.
+func TestChatCompletionStreamEndsBeforeFinish(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"par\"}]}}]}\n\n"))
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL))
+	stream, err := p.ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "m",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("first chunk: %v", err)
+	}
+	_, err = stream.Next()
+	if errors.Is(err, io.EOF) || !errors.Is(err, ir.ErrProviderUnavailable) {
+		t.Errorf("err = %v, want ErrProviderUnavailable", err)
+	}
+}
+
 func TestChatCompletionStreamErrorStatus(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "auth", http.StatusUnauthorized)
@@ -316,3 +461,97 @@ func TestChatCompletionStreamErrorStatus(t *testing.T) {
 		t.Errorf("err = %v, want ErrAuthFailed", err)
 	}
 }
+
+func TestBuildRequestResponseFormat(t *testing.T) {
+	p := New()
+	req := p.buildRequest(ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+		ResponseFormat: &ir.ResponseFormat{
+			Type:   ir.ResponseFormatJSONSchema,
+			Schema: json.RawMessage(`{"type":"object"}`),
+		},
+	})
+	gc := req.GenerationConfig
+	if gc == nil {
+		t.Fatal("generationConfig should be set for a response format")
+	}
+	if gc.ResponseMIMEType != "application/json" || string(gc.ResponseJSONSchema) != `{"type":"object"}` {
+		t.Errorf("generationConfig = %+v", gc)
+	}
+
+	req = p.buildRequest(ir.ProviderRequest{
+		Messages:       []ir.Message{{Role: "user", Content: "hi"}},
+		ResponseFormat: &ir.ResponseFormat{Type: ir.ResponseFormatJSON},
+	})
+	if req.GenerationConfig == nil || req.GenerationConfig.ResponseMIMEType != "application/json" ||
+		req.GenerationConfig.ResponseJSONSchema != nil {
+		t.Errorf("json_object generationConfig = %+v", req.GenerationConfig)
+	}
+}
+
+func TestChatCompletionRateLimitHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ratelimit-remaining-requests", "0")
+		w.Header().Set("x-ratelimit-reset-requests", "45s")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}],"usageMetadata":{}}`))
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL))
+	resp, err := p.ChatCompletion(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "gemini-2.0-flash",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	rl := resp.RateLimits
+	if rl == nil || rl.RemainingRequests == nil || *rl.RemainingRequests != 0 || rl.ResetRequests != 45*time.Second {
+		t.Errorf("RateLimits = %+v, want 0 requests resetting in 45s", rl)
+	}
+}
+
+func TestChatCompletionNoRateLimitHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}],"usageMetadata":{}}`))
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL))
+	resp, err := p.ChatCompletion(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "gemini-2.0-flash",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if resp.RateLimits != nil {
+		t.Errorf("RateLimits = %+v, want nil without headers", resp.RateLimits)
+	}
+}
+
+func TestChatCompletion429RetryInfo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"code":429,"status":"RESOURCE_EXHAUSTED","details":[
+			{"@type":"type.googleapis.com/google.rpc.QuotaFailure","violations":[{"quotaMetric":"generate_content_free_tier_requests"}]},
+			{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"27s"}]}}`))
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL))
+	_, err := p.ChatCompletion(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "gemini-2.0-flash",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	var rle *ir.RateLimitError
+	if !errors.As(err, &rle) {
+		t.Fatalf("err = %v, want *RateLimitError", err)
+	}
+	if rle.RetryAfter != 27*time.Second {
+		t.Errorf("RetryAfter = %v, want 27s from RetryInfo", rle.RetryAfter)
+	}
+}
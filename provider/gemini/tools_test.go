@@ -0,0 +1,162 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ir "github.com/ineyio/inferrouter"
+)
+
+func TestBuildRequestTools(t *testing.T) {
+	p := New()
+	req := p.buildRequest(ir.ProviderRequest{
+		Messages: []ir.Message{
+			{Role: "user", Content: "weather in Paris and Rome?"},
+			{Role: "assistant", ToolCalls: []ir.ToolCall{
+				{ID: "call_a", Type: "function", Function: ir.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+				{ID: "call_b", Type: "function", Function: ir.FunctionCall{Name: "get_weather", Arguments: `{"city":"Rome"}`}},
+			}},
+			{Role: "tool", ToolCallID: "call_a", Content: `{"temp":21}`},
+			{Role: "tool", ToolCallID: "call_b", Content: "sunny"},
+		},
+		Tools: []ir.Tool{{Type: "function", Function: ir.ToolFunction{
+			Name:        "get_weather",
+			Description: "weather",
+			Parameters:  json.RawMessage(`{"type":"object"}`),
+		}}},
+		ToolChoice: &ir.ToolChoice{Function: "get_weather"},
+	})
+
+	if len(req.Tools) != 1 || len(req.Tools[0].FunctionDeclarations) != 1 ||
+		req.Tools[0].FunctionDeclarations[0].Name != "get_weather" {
+		t.Fatalf("tools = %+v", req.Tools)
+	}
+	if req.ToolConfig == nil || req.ToolConfig.FunctionCallingConfig.Mode != "ANY" ||
+		len(req.ToolConfig.FunctionCallingConfig.AllowedFunctionNames) != 1 {
+		t.Errorf("toolConfig = %+v", req.ToolConfig)
+	}
+
+	// user, model(2 calls), user(2 responses merged)
+	if len(req.Contents) != 3 {
+		t.Fatalf("contents len = %d, want 3: %+v", len(req.Contents), req.Contents)
+	}
+	model := req.Contents[1]
+	if model.Role != "model" || len(model.Parts) != 2 || model.Parts[0].FunctionCall == nil ||
+		model.Parts[1].FunctionCall.Name != "get_weather" || string(model.Parts[1].FunctionCall.Args) != `{"city":"Rome"}` {
+		t.Errorf("model content = %+v", model)
+	}
+	results := req.Contents[2]
+	if results.Role != "user" || len(results.Parts) != 2 {
+		t.Fatalf("results content = %+v", results)
+	}
+	first := results.Parts[0].FunctionResponse
+	if first == nil || first.Name != "get_weather" || string(first.Response) != `{"temp":21}` {
+		t.Errorf("first response = %+v", first)
+	}
+	second := results.Parts[1].FunctionResponse
+	if second == nil || string(second.Response) != `{"content":"sunny"}` {
+		t.Errorf("non-object result must be wrapped, got %+v", second)
+	}
+}
+
+func TestBuildToolConfigModes(t *testing.T) {
+	cases := []struct {
+		choice *ir.ToolChoice
+		want   string
+	}{
+		{&ir.ToolChoice{Mode: ir.ToolChoiceAuto}, "AUTO"},
+		{&ir.ToolChoice{Mode: ir.ToolChoiceNone}, "NONE"},
+		{&ir.ToolChoice{Mode: ir.ToolChoiceRequired}, "ANY"},
+	}
+	for _, tc := range cases {
+		got := buildToolConfig(tc.choice)
+		if got == nil || got.FunctionCallingConfig.Mode != tc.want {
+			t.Errorf("mode %q → %+v, want %s", tc.choice.Mode, got, tc.want)
+		}
+	}
+	if buildToolConfig(nil) != nil {
+		t.Error("nil choice must produce no toolConfig")
+	}
+}
+
+func TestChatCompletionFunctionCall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}]},"finishReason":"STOP"}],
+			"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"totalTokenCount":15}
+		}`))
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL))
+	resp, err := p.ChatCompletion(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "gemini-2.0-flash",
+		Messages: []ir.Message{{Role: "user", Content: "weather?"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("tool calls = %+v", resp.ToolCalls)
+	}
+	tc := resp.ToolCalls[0]
+	if tc.Function.Name != "get_weather" || tc.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("tool call = %+v", tc)
+	}
+	if !strings.HasPrefix(tc.ID, "call_") {
+		t.Errorf("synthesized id = %q", tc.ID)
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("finish = %q, want tool_calls", resp.FinishReason)
+	}
+}
+
+func TestChatCompletionStreamFunctionCall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"id":"fc-1","name":"get_weather","args":{"city":"Rome"}}}]}}]}` + "\n\n"))
+		_, _ = w.Write([]byte(`data: {"candidates":[{"content":{"role":"model","parts":[{"text":""}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"totalTokenCount":5}}` + "\n\n"))
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL))
+	stream, err := p.ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "gemini-2.0-flash",
+		Messages: []ir.Message{{Role: "user", Content: "weather?"}},
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer stream.Close()
+
+	var deltas []ir.ToolCallDelta
+	var finish string
+	for {
+		c, err := stream.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		for _, ch := range c.Choices {
+			deltas = append(deltas, ch.Delta.ToolCalls...)
+			if ch.FinishReason != "" {
+				finish = ch.FinishReason
+			}
+		}
+	}
+	if len(deltas) != 1 || deltas[0].ID != "fc-1" || deltas[0].Function.Arguments != `{"city":"Rome"}` {
+		t.Errorf("deltas = %+v", deltas)
+	}
+	if finish != "tool_calls" {
+		t.Errorf("finish = %q", finish)
+	}
+}
@@ -19,18 +19,25 @@ type Provider struct {
 	inner *openaicompat.Provider
 }
 
-var _ inferrouter.Provider = (*Provider)(nil)
+var (
+	_ inferrouter.Provider          = (*Provider)(nil)
+	_ inferrouter.ToolProvider      = (*Provider)(nil)
+	_ inferrouter.EmbeddingProvider = (*Provider)(nil)
+	_ inferrouter.ChoicesProvider   = (*Provider)(nil)
+)
 
 // Option configures the Gonka provider.
 type Option func(*config)
 
 type config struct {
-	name      string
-	models    []string
-	endpoint  Endpoint
-	timeout   time.Duration
-	transport http.RoundTripper
-	nowFunc   func() time.Time
+	name        string
+	models      []string
+	embedModels []string
+	embedBatch  int
+	endpoint    Endpoint
+	timeout     time.Duration
+	transport   http.RoundTripper
+	nowFunc     func() time.Time
 }
 
 // WithName sets the provider name (default: "gonka").
@@ -43,6 +50,17 @@ func WithModels(models ...string) Option {
 	return func(c *config) { c.models = models }
 }
 
+// WithEmbeddingModels sets the embedding models served by the node's
+// /embeddings. Without it the provider reports none.
+func WithEmbeddingModels(models ...string) Option {
+	return func(c *config) { c.embedModels = models }
+}
+
+// WithEmbedBatchSize sets the most inputs sent in one /embeddings call.
+func WithEmbedBatchSize(n int) Option {
+	return func(c *config) { c.embedBatch = n }
+}
+
 // WithEndpoint sets the Gonka node endpoint.
 func WithEndpoint(e Endpoint) Option {
 	return func(c *config) { c.endpoint = e }
@@ -95,6 +113,12 @@ func New(opts ...Option) *Provider {
 	if len(cfg.models) > 0 {
 		innerOpts = append(innerOpts, openaicompat.WithModels(cfg.models...))
 	}
+	if len(cfg.embedModels) > 0 {
+		innerOpts = append(innerOpts, openaicompat.WithEmbeddingModels(cfg.embedModels...))
+	}
+	if cfg.embedBatch > 0 {
+		innerOpts = append(innerOpts, openaicompat.WithEmbedBatchSize(cfg.embedBatch))
+	}
 
 	inner := openaicompat.New(cfg.name, cfg.endpoint.URL, innerOpts...)
 
@@ -107,6 +131,10 @@ func (p *Provider) SupportsModel(model string) bool { return p.inner.SupportsMod
 
 func (p *Provider) SupportsMultimodal() bool { return p.inner.SupportsMultimodal() }
 
+func (p *Provider) SupportsTools() bool { return p.inner.SupportsTools() }
+
+func (p *Provider) SupportsChoices() bool { return p.inner.SupportsChoices() }
+
 func (p *Provider) ChatCompletion(ctx context.Context, req inferrouter.ProviderRequest) (inferrouter.ProviderResponse, error) {
 	return p.inner.ChatCompletion(ctx, req)
 }
@@ -114,3 +142,13 @@ func (p *Provider) ChatCompletion(ctx context.Context, req inferrouter.ProviderR
 func (p *Provider) ChatCompletionStream(ctx context.Context, req inferrouter.ProviderRequest) (inferrouter.ProviderStream, error) {
 	return p.inner.ChatCompletionStream(ctx, req)
 }
+
+func (p *Provider) SupportsEmbeddingModel(model string) bool {
+	return p.inner.SupportsEmbeddingModel(model)
+}
+
+func (p *Provider) MaxBatchSize() int { return p.inner.MaxBatchSize() }
+
+func (p *Provider) Embed(ctx context.Context, req inferrouter.EmbedProviderRequest) (inferrouter.EmbedProviderResponse, error) {
+	return p.inner.Embed(ctx, req)
+}
@@ -504,3 +504,33 @@ type roundTripFunc func(*http.Request) (*http.Response, error)
 func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 	return f(req)
 }
+
+func TestProvider_Embed(t *testing.T) {
+	var gotPath, gotAddress string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAddress = r.Header.Get("X-Requester-Address")
+		fmt.Fprint(w, `{"data":[{"index":0,"embedding":[0.1,0.2]}],"model":"embed-model","usage":{"prompt_tokens":4,"total_tokens":4}}`)
+	}))
+	defer srv.Close()
+
+	p := New(
+		WithEndpoint(Endpoint{URL: srv.URL, Address: "gonka1testnode"}),
+		WithEmbeddingModels("embed-model"),
+		WithEmbedBatchSize(16),
+	)
+	assert.True(t, p.SupportsEmbeddingModel("embed-model"))
+	assert.False(t, p.SupportsEmbeddingModel("test-model"))
+	assert.Equal(t, 16, p.MaxBatchSize())
+
+	resp, err := p.Embed(context.Background(), ir.EmbedProviderRequest{
+		Auth:   ir.Auth{APIKey: validKeyHex},
+		Model:  "embed-model",
+		Inputs: []string{"hello"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/embeddings", gotPath)
+	assert.NotEmpty(t, gotAddress, "embed requests are signed like chat")
+	assert.Equal(t, [][]float32{{0.1, 0.2}}, resp.Embeddings)
+	assert.Equal(t, int64(4), resp.Usage.InputTokens)
+}
@@ -20,10 +20,16 @@ type Provider struct {
 	usage        inferrouter.Usage
 	responseFunc func(inferrouter.ProviderRequest) (inferrouter.ProviderResponse, error)
 	multimodal   bool
+	tools        bool
+	choices      bool
 	breakdownFn  func(inferrouter.ProviderRequest) inferrouter.InputTokenBreakdown
 }
 
-var _ inferrouter.Provider = (*Provider)(nil)
+var (
+	_ inferrouter.Provider        = (*Provider)(nil)
+	_ inferrouter.ToolProvider    = (*Provider)(nil)
+	_ inferrouter.ChoicesProvider = (*Provider)(nil)
+)
 
 // Option configures a mock Provider.
 type Option func(*Provider)
@@ -85,6 +91,16 @@ func WithMultimodal(enabled bool) Option {
 	return func(p *Provider) { p.multimodal = enabled }
 }
 
+// WithTools marks this mock as supporting tool calling.
+func WithTools(enabled bool) Option {
+	return func(p *Provider) { p.tools = enabled }
+}
+
+// WithChoices marks this mock as returning every completion for N above 1.
+func WithChoices(enabled bool) Option {
+	return func(p *Provider) { p.choices = enabled }
+}
+
 // WithInputBreakdownFunc lets tests supply a deterministic per-modality
 // token breakdown based on the request. When set, the returned breakdown is
 // attached to the mock response's Usage. Useful for exercising cost paths
@@ -97,6 +113,10 @@ func (p *Provider) Name() string { return p.name }
 
 func (p *Provider) SupportsMultimodal() bool { return p.multimodal }
 
+func (p *Provider) SupportsTools() bool { return p.tools }
+
+func (p *Provider) SupportsChoices() bool { return p.choices }
+
 func (p *Provider) SupportsModel(model string) bool {
 	for _, m := range p.models {
 		if m == model {
@@ -151,32 +171,49 @@ func (p *Provider) ChatCompletionStream(ctx context.Context, req inferrouter.Pro
 		return nil, err
 	}
 
-	return &mockStream{
-		chunks: []inferrouter.StreamChunk{
-			{
-				ID:    resp.ID,
-				Model: resp.Model,
-				Choices: []inferrouter.StreamDelta{
-					{Index: 0, Delta: inferrouter.Delta{Role: "assistant"}},
-				},
+	chunks := []inferrouter.StreamChunk{
+		{
+			ID:    resp.ID,
+			Model: resp.Model,
+			Choices: []inferrouter.StreamDelta{
+				{Index: 0, Delta: inferrouter.Delta{Role: "assistant"}},
 			},
-			{
-				ID:    resp.ID,
-				Model: resp.Model,
-				Choices: []inferrouter.StreamDelta{
-					{Index: 0, Delta: inferrouter.Delta{Content: resp.Content}},
-				},
+		},
+		{
+			ID:    resp.ID,
+			Model: resp.Model,
+			Choices: []inferrouter.StreamDelta{
+				{Index: 0, Delta: inferrouter.Delta{Content: resp.Content}},
 			},
-			{
-				ID:    resp.ID,
-				Model: resp.Model,
-				Choices: []inferrouter.StreamDelta{
-					{Index: 0, FinishReason: "stop"},
-				},
-				Usage: &resp.Usage,
+		},
+	}
+
+	finishReason := "stop"
+	if len(resp.ToolCalls) > 0 {
+		deltas := make([]inferrouter.ToolCallDelta, len(resp.ToolCalls))
+		for i, tc := range resp.ToolCalls {
+			deltas[i] = inferrouter.ToolCallDelta{Index: i, ID: tc.ID, Type: tc.Type, Function: tc.Function}
+		}
+		chunks = append(chunks, inferrouter.StreamChunk{
+			ID:    resp.ID,
+			Model: resp.Model,
+			Choices: []inferrouter.StreamDelta{
+				{Index: 0, Delta: inferrouter.Delta{ToolCalls: deltas}},
 			},
+		})
+		finishReason = "tool_calls"
+	}
+
+	chunks = append(chunks, inferrouter.StreamChunk{
+		ID:    resp.ID,
+		Model: resp.Model,
+		Choices: []inferrouter.StreamDelta{
+			{Index: 0, FinishReason: finishReason},
 		},
-	}, nil
+		Usage: &resp.Usage,
+	})
+
+	return &mockStream{chunks: chunks}, nil
 }
 
 // CallCount returns the number of calls made to the provider.
@@ -0,0 +1,107 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/ineyio/inferrouter"
+)
+
+// EmbeddingProvider interface compliance.
+var _ inferrouter.EmbeddingProvider = (*Provider)(nil)
+
+// defaultEmbedMaxBatch caps inputs per /api/embed call. Ollama sets no
+// limit of its own; this keeps one call to a size a local server finishes
+// well within a request timeout.
+const defaultEmbedMaxBatch = 256
+
+// WithEmbeddingModels lists the embedding models (nomic-embed-text,
+// mxbai-embed-large, ...). /api/tags does not say which models embed, so
+// embeddings are opt-in.
+func WithEmbeddingModels(models ...string) Option {
+	return func(p *Provider) { p.embedModels = models }
+}
+
+// WithEmbedBatchSize sets the most inputs sent in one /api/embed call.
+// Default 256.
+func WithEmbedBatchSize(n int) Option {
+	return func(p *Provider) { p.embedMaxBatch = n }
+}
+
+// SupportsEmbeddingModel reports whether model was listed with
+// WithEmbeddingModels.
+func (p *Provider) SupportsEmbeddingModel(model string) bool {
+	return slices.Contains(p.embedModels, model)
+}
+
+// MaxBatchSize returns the most inputs accepted in one /api/embed call.
+func (p *Provider) MaxBatchSize() int {
+	if p.embedMaxBatch > 0 {
+		return p.embedMaxBatch
+	}
+	return defaultEmbedMaxBatch
+}
+
+// Embed calls /api/embed for a batch of inputs. TaskType is ignored;
+// OutputDimensionality is sent as dimensions.
+func (p *Provider) Embed(ctx context.Context, req inferrouter.EmbedProviderRequest) (inferrouter.EmbedProviderResponse, error) {
+	body := apiEmbedRequest{
+		Model:      req.Model,
+		Input:      req.Inputs,
+		Dimensions: req.OutputDimensionality,
+		Options:    p.options,
+		KeepAlive:  p.keepAlive,
+	}
+
+	httpResp, err := p.doRequest(ctx, req.Auth, "/api/embed", body)
+	if err != nil {
+		return inferrouter.EmbedProviderResponse{}, err
+	}
+	defer httpResp.Body.Close()
+
+	if err := mapHTTPError(httpResp); err != nil {
+		return inferrouter.EmbedProviderResponse{}, err
+	}
+
+	var resp apiEmbedResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return inferrouter.EmbedProviderResponse{}, fmt.Errorf("inferrouter: decode ollama embed response: %w", err)
+	}
+
+	if len(resp.Embeddings) != len(req.Inputs) {
+		return inferrouter.EmbedProviderResponse{}, fmt.Errorf(
+			"inferrouter: ollama embed response size mismatch: got %d, want %d",
+			len(resp.Embeddings), len(req.Inputs))
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = req.Model
+	}
+	return inferrouter.EmbedProviderResponse{
+		Embeddings: resp.Embeddings,
+		Model:      model,
+		Usage: inferrouter.EmbedUsage{
+			InputTokens: resp.PromptEvalCount,
+			TotalTokens: resp.PromptEvalCount,
+		},
+	}, nil
+}
+
+// --- Ollama embed API types ---
+
+type apiEmbedRequest struct {
+	Model      string         `json:"model"`
+	Input      []string       `json:"input"`
+	Dimensions int            `json:"dimensions,omitempty"`
+	Options    map[string]any `json:"options,omitempty"`
+	KeepAlive  any            `json:"keep_alive,omitempty"`
+}
+
+type apiEmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int64       `json:"prompt_eval_count"`
+}
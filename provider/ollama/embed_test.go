@@ -0,0 +1,79 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ir "github.com/ineyio/inferrouter"
+)
+
+func TestSupportsEmbeddingModel(t *testing.T) {
+	if New().SupportsEmbeddingModel("nomic-embed-text") {
+		t.Error("embeddings are opt-in; a bare provider should report none")
+	}
+	p := New(WithEmbeddingModels("nomic-embed-text"))
+	if !p.SupportsEmbeddingModel("nomic-embed-text") || p.SupportsEmbeddingModel("llama3.1") {
+		t.Error("only the listed model should be an embedding model")
+	}
+	if p.MaxBatchSize() != 256 || New(WithEmbedBatchSize(16)).MaxBatchSize() != 16 {
+		t.Error("batch size should default to 256 and follow WithEmbedBatchSize")
+	}
+}
+
+func TestEmbed_HappyPath(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = io.WriteString(w, `{"model":"nomic-embed-text","embeddings":[[0.1,0.2],[0.3,0.4]],"prompt_eval_count":8}`)
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL), WithEmbeddingModels("nomic-embed-text"), WithKeepAlive(-1))
+	resp, err := p.Embed(context.Background(), ir.EmbedProviderRequest{
+		Model:                "nomic-embed-text",
+		Inputs:               []string{"first", "second"},
+		OutputDimensionality: 2,
+	})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if got["model"] != "nomic-embed-text" || got["dimensions"] != float64(2) || got["keep_alive"] != float64(-1) {
+		t.Errorf("request = %v", got)
+	}
+	if len(resp.Embeddings) != 2 || resp.Embeddings[1][0] != 0.3 {
+		t.Errorf("embeddings = %v", resp.Embeddings)
+	}
+	if resp.Usage.InputTokens != 8 || resp.Usage.TotalTokens != 8 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestEmbed_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"model \"bge\" not found, try pulling it first"}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL), WithEmbeddingModels("bge"), WithKeepAlive(time.Minute))
+	if _, err := p.Embed(context.Background(), ir.EmbedProviderRequest{Model: "bge", Inputs: []string{"a"}}); !errors.Is(err, ir.ErrModelNotFound) {
+		t.Errorf("err = %v, want ErrModelNotFound", err)
+	}
+
+	mismatch := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"embeddings":[[1]]}`)
+	}))
+	defer mismatch.Close()
+	p = New(WithBaseURL(mismatch.URL), WithEmbeddingModels("bge"))
+	if _, err := p.Embed(context.Background(), ir.EmbedProviderRequest{Model: "bge", Inputs: []string{"a", "b"}}); err == nil {
+		t.Error("size mismatch should fail")
+	}
+}
@@ -0,0 +1,622 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ineyio/inferrouter"
+)
+
+const defaultBaseURL = "http://localhost:11434"
+
+// Provider is the native Ollama API adapter (/api/chat, /api/embed,
+// /api/tags). Unlike Ollama's OpenAI shim it passes keep_alive and the raw
+// options map through, and reports prompt_eval_count/eval_count as usage.
+type Provider struct {
+	name       string
+	baseURL    string
+	httpClient *http.Client
+	models     []string
+	multimodal []string
+	keepAlive  any
+	options    map[string]any
+
+	embedModels   []string
+	embedMaxBatch int
+
+	mu         sync.RWMutex
+	discovered map[string]struct{} // nil until Discover succeeds
+}
+
+var (
+	_ inferrouter.Provider                = (*Provider)(nil)
+	_ inferrouter.ToolProvider            = (*Provider)(nil)
+	_ inferrouter.MultimodalModelProvider = (*Provider)(nil)
+)
+
+// Option configures the provider.
+type Option func(*Provider)
+
+// WithName sets the provider name (default: "ollama"), for routing to
+// several Ollama hosts.
+func WithName(name string) Option {
+	return func(p *Provider) { p.name = name }
+}
+
+// WithBaseURL sets the server address (default: http://localhost:11434).
+func WithBaseURL(url string) Option {
+	return func(p *Provider) { p.baseURL = strings.TrimRight(url, "/") }
+}
+
+// WithHTTPClient sets a custom HTTP client.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Provider) { p.httpClient = c }
+}
+
+// WithModels sets the list of supported models. It takes precedence over
+// Discover.
+func WithModels(models ...string) Option {
+	return func(p *Provider) { p.models = models }
+}
+
+// WithMultimodalModels lists the models that accept image parts (llava,
+// llama3.2-vision, ...). The router sends image requests only to these.
+func WithMultimodalModels(models ...string) Option {
+	return func(p *Provider) { p.multimodal = models }
+}
+
+// WithKeepAlive sets how long the server keeps a model loaded after a
+// request. Zero unloads it immediately and a negative duration keeps it
+// loaded indefinitely. Unset leaves the server default (5m).
+func WithKeepAlive(d time.Duration) Option {
+	return func(p *Provider) {
+		if d < 0 {
+			p.keepAlive = -1
+			return
+		}
+		p.keepAlive = d.String()
+	}
+}
+
+// WithOptions sets model options sent with every request (num_ctx,
+// num_gpu, repeat_penalty, ...). Request fields such as Temperature and
+// MaxTokens override the matching keys.
+func WithOptions(options map[string]any) Option {
+	return func(p *Provider) { p.options = options }
+}
+
+// New creates a new Ollama provider.
+func New(opts ...Option) *Provider {
+	p := &Provider{
+		name:       "ollama",
+		baseURL:    defaultBaseURL,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Provider) Name() string { return p.name }
+
+// SupportsModel reports whether model is served: per WithModels if set,
+// else per the last successful Discover, else any model. A name without a
+// tag matches its ":latest" tag, as in Ollama itself.
+func (p *Provider) SupportsModel(model string) bool {
+	if len(p.models) > 0 {
+		return slices.Contains(p.models, model) || slices.Contains(p.models, withLatest(model))
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.discovered == nil {
+		return true
+	}
+	_, ok := p.discovered[model]
+	if !ok {
+		_, ok = p.discovered[withLatest(model)]
+	}
+	return ok
+}
+
+// Discover lists the models pulled on the server via /api/tags and, unless
+// WithModels is set, restricts SupportsModel to them. Call it at startup and
+// again after pulling models; a failed call keeps the previous list.
+func (p *Provider) Discover(ctx context.Context) ([]string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("inferrouter: create ollama tags request: %w", err)
+	}
+	inferrouter.InjectTraceHeaders(ctx, httpReq.Header)
+
+	httpResp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, inferrouter.ErrProviderUnavailable
+	}
+	defer httpResp.Body.Close()
+
+	if err := mapHTTPError(httpResp); err != nil {
+		return nil, err
+	}
+
+	var resp apiTagsResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("inferrouter: decode ollama tags: %w", err)
+	}
+
+	names := make([]string, 0, len(resp.Models))
+	set := make(map[string]struct{}, len(resp.Models))
+	for _, m := range resp.Models {
+		name := m.Name
+		if name == "" {
+			name = m.Model
+		}
+		names = append(names, name)
+		set[name] = struct{}{}
+	}
+
+	p.mu.Lock()
+	p.discovered = set
+	p.mu.Unlock()
+	return names, nil
+}
+
+// withLatest returns model with the implicit ":latest" tag made explicit.
+func withLatest(model string) string {
+	if strings.Contains(model, ":") {
+		return model
+	}
+	return model + ":latest"
+}
+
+// SupportsMultimodal reports whether any model was configured with
+// WithMultimodalModels.
+func (p *Provider) SupportsMultimodal() bool { return len(p.multimodal) > 0 }
+
+// SupportsMultimodalModel reports whether model was configured with
+// WithMultimodalModels.
+func (p *Provider) SupportsMultimodalModel(model string) bool {
+	return slices.Contains(p.multimodal, model)
+}
+
+// SupportsTools reports that tools and tool calls are serialized. Ollama has
+// no tool_choice: "none" drops the tools, other modes leave the choice to
+// the model.
+func (p *Provider) SupportsTools() bool { return true }
+
+// --- Ollama chat API types ---
+
+type apiRequest struct {
+	Model     string             `json:"model"`
+	Messages  []apiMessage       `json:"messages"`
+	Tools     []inferrouter.Tool `json:"tools,omitempty"`
+	Format    json.RawMessage    `json:"format,omitempty"`
+	Options   map[string]any     `json:"options,omitempty"`
+	KeepAlive any                `json:"keep_alive,omitempty"`
+
+	// Stream is always sent: Ollama streams unless told otherwise.
+	Stream bool `json:"stream"`
+}
+
+type apiMessage struct {
+	Role      string        `json:"role"`
+	Content   string        `json:"content"`
+	Images    []string      `json:"images,omitempty"`
+	ToolCalls []apiToolCall `json:"tool_calls,omitempty"`
+	ToolName  string        `json:"tool_name,omitempty"`
+}
+
+type apiToolCall struct {
+	Function apiFunctionCall `json:"function"`
+}
+
+// apiFunctionCall carries Arguments as a JSON object, not a string as in
+// the OpenAI format.
+type apiFunctionCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// apiResponse is a whole non-streamed reply, or one line of a stream.
+type apiResponse struct {
+	Model           string     `json:"model"`
+	Message         apiMessage `json:"message"`
+	Done            bool       `json:"done"`
+	DoneReason      string     `json:"done_reason"`
+	PromptEvalCount int64      `json:"prompt_eval_count"`
+	EvalCount       int64      `json:"eval_count"`
+	Error           string     `json:"error"`
+}
+
+type apiTagsResponse struct {
+	Models []struct {
+		Name  string `json:"name"`
+		Model string `json:"model"`
+	} `json:"models"`
+}
+
+func (p *Provider) ChatCompletion(ctx context.Context, req inferrouter.ProviderRequest) (inferrouter.ProviderResponse, error) {
+	body, err := p.buildRequest(req, false)
+	if err != nil {
+		return inferrouter.ProviderResponse{}, err
+	}
+
+	httpResp, err := p.doRequest(ctx, req.Auth, "/api/chat", body)
+	if err != nil {
+		return inferrouter.ProviderResponse{}, err
+	}
+	defer httpResp.Body.Close()
+
+	if err := mapHTTPError(httpResp); err != nil {
+		return inferrouter.ProviderResponse{}, err
+	}
+
+	var resp apiResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return inferrouter.ProviderResponse{}, fmt.Errorf("inferrouter: decode ollama response: %w", err)
+	}
+	if resp.Error != "" {
+		return inferrouter.ProviderResponse{}, fmt.Errorf("%w: %s", inferrouter.ErrProviderUnavailable, resp.Error)
+	}
+
+	toolCalls := fromAPIToolCalls(resp.Message.ToolCalls)
+	return inferrouter.ProviderResponse{
+		Content:      resp.Message.Content,
+		ToolCalls:    toolCalls,
+		FinishReason: finishReason(resp.DoneReason, len(toolCalls) > 0),
+		Model:        resp.Model,
+		Usage:        buildUsage(resp, req),
+	}, nil
+}
+
+func (p *Provider) ChatCompletionStream(ctx context.Context, req inferrouter.ProviderRequest) (inferrouter.ProviderStream, error) {
+	body, err := p.buildRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := p.doRequest(ctx, req.Auth, "/api/chat", body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mapHTTPError(httpResp); err != nil {
+		httpResp.Body.Close()
+		return nil, err
+	}
+
+	return &ndjsonStream{
+		reader: bufio.NewReader(httpResp.Body),
+		body:   httpResp.Body,
+		req:    req,
+	}, nil
+}
+
+func (p *Provider) buildRequest(req inferrouter.ProviderRequest, stream bool) (apiRequest, error) {
+	callNames := toolCallNames(req.Messages)
+
+	msgs := make([]apiMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		msg, err := buildMessage(m, callNames)
+		if err != nil {
+			return apiRequest{}, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	out := apiRequest{
+		Model:     req.Model,
+		Messages:  msgs,
+		Format:    buildFormat(req.ResponseFormat),
+		Options:   p.buildOptions(req),
+		KeepAlive: p.keepAlive,
+		Stream:    stream,
+	}
+	if req.ToolChoice == nil || req.ToolChoice.Mode != inferrouter.ToolChoiceNone {
+		out.Tools = req.Tools
+	}
+	return out, nil
+}
+
+// buildMessage maps one message. Text parts are joined into content and
+// images go to images as base64; Ollama has no audio or video input.
+func buildMessage(m inferrouter.Message, callNames map[string]string) (apiMessage, error) {
+	msg := apiMessage{Role: m.Role, Content: m.Content}
+
+	if len(m.Parts) > 0 {
+		var text strings.Builder
+		for _, part := range m.Parts {
+			switch part.Type {
+			case inferrouter.PartText:
+				text.WriteString(part.Text)
+			case inferrouter.PartImage:
+				msg.Images = append(msg.Images, base64.StdEncoding.EncodeToString(part.Data))
+			default:
+				return apiMessage{}, fmt.Errorf("%w: ollama does not accept %s parts", inferrouter.ErrInvalidRequest, part.Type)
+			}
+		}
+		msg.Content = text.String()
+	}
+
+	for _, tc := range m.ToolCalls {
+		args := json.RawMessage(tc.Function.Arguments)
+		if !json.Valid(args) {
+			args = json.RawMessage("{}")
+		}
+		msg.ToolCalls = append(msg.ToolCalls, apiToolCall{Function: apiFunctionCall{Name: tc.Function.Name, Arguments: args}})
+	}
+
+	if m.Role == "tool" {
+		msg.ToolName = m.Name
+		if msg.ToolName == "" {
+			msg.ToolName = callNames[m.ToolCallID]
+		}
+	}
+	return msg, nil
+}
+
+// toolCallNames maps tool call IDs to function names, so results that only
+// carry a ToolCallID can be sent with the tool_name Ollama expects.
+func toolCallNames(msgs []inferrouter.Message) map[string]string {
+	names := make(map[string]string)
+	for _, m := range msgs {
+		for _, tc := range m.ToolCalls {
+			names[tc.ID] = tc.Function.Name
+		}
+	}
+	return names
+}
+
+// buildFormat maps ResponseFormat to Ollama's format: "json", or the schema
+// itself for structured output.
+func buildFormat(rf *inferrouter.ResponseFormat) json.RawMessage {
+	if rf == nil {
+		return nil
+	}
+	switch rf.Type {
+	case inferrouter.ResponseFormatJSON:
+		return json.RawMessage(`"json"`)
+	case inferrouter.ResponseFormatJSONSchema:
+		if len(rf.Schema) == 0 {
+			return json.RawMessage(`"json"`)
+		}
+		return rf.Schema
+	default:
+		return nil
+	}
+}
+
+// buildOptions layers the request's sampling fields over WithOptions.
+func (p *Provider) buildOptions(req inferrouter.ProviderRequest) map[string]any {
+	opts := maps.Clone(p.options)
+	set := func(key string, v any) {
+		if opts == nil {
+			opts = make(map[string]any)
+		}
+		opts[key] = v
+	}
+	if req.Temperature != nil {
+		set("temperature", *req.Temperature)
+	}
+	if req.TopP != nil {
+		set("top_p", *req.TopP)
+	}
+	if req.MaxTokens != nil {
+		set("num_predict", *req.MaxTokens)
+	}
+	if len(req.Stop) > 0 {
+		set("stop", req.Stop)
+	}
+	if req.Seed != nil {
+		set("seed", *req.Seed)
+	}
+	if req.PresencePenalty != nil {
+		set("presence_penalty", *req.PresencePenalty)
+	}
+	if req.FrequencyPenalty != nil {
+		set("frequency_penalty", *req.FrequencyPenalty)
+	}
+	return opts
+}
+
+// fromAPIToolCalls maps tool calls. Ollama assigns no IDs, so they are
+// numbered per response.
+func fromAPIToolCalls(calls []apiToolCall) []inferrouter.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]inferrouter.ToolCall, len(calls))
+	for i, c := range calls {
+		out[i] = inferrouter.ToolCall{
+			ID:       fmt.Sprintf("call_%d", i),
+			Type:     "function",
+			Function: inferrouter.FunctionCall{Name: c.Function.Name, Arguments: arguments(c.Function.Arguments)},
+		}
+	}
+	return out
+}
+
+func arguments(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "{}"
+	}
+	return string(raw)
+}
+
+// finishReason maps done_reason to the OpenAI vocabulary.
+func finishReason(reason string, toolCalls bool) string {
+	switch {
+	case toolCalls:
+		return "tool_calls"
+	case reason == "length":
+		return "length"
+	default:
+		return "stop"
+	}
+}
+
+// buildUsage maps prompt_eval_count/eval_count. Ollama does not split the
+// prompt by modality, so only text-only requests get an InputBreakdown.
+func buildUsage(resp apiResponse, req inferrouter.ProviderRequest) inferrouter.Usage {
+	u := inferrouter.Usage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+	}
+	if !req.HasMedia {
+		u.InputBreakdown = &inferrouter.InputTokenBreakdown{Text: resp.PromptEvalCount}
+	}
+	return u
+}
+
+// doRequest POSTs body as JSON to path. Ollama itself has no auth; an
+// APIKey is sent as a bearer token for proxies in front of it.
+func (p *Provider) doRequest(ctx context.Context, auth inferrouter.Auth, path string, body any) (*http.Response, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("inferrouter: marshal ollama request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("inferrouter: create ollama request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if auth.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+auth.APIKey)
+	}
+	inferrouter.InjectTraceHeaders(ctx, httpReq.Header)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, inferrouter.ErrProviderUnavailable
+	}
+	return resp, nil
+}
+
+func mapHTTPError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+
+	detail := http.StatusText(resp.StatusCode)
+	if err == nil && len(body) > 0 {
+		detail = string(body)
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			detail = apiErr.Error
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		wait, _ := inferrouter.ParseRateLimitHeaders(resp.Header).Backoff()
+		return &inferrouter.RateLimitError{RetryAfter: wait, Detail: detail}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %s", inferrouter.ErrAuthFailed, detail)
+	case http.StatusNotFound:
+		// "model 'x' not found, try pulling it first"
+		return fmt.Errorf("%w: %s", inferrouter.ErrModelNotFound, detail)
+	case http.StatusBadRequest:
+		if inferrouter.IsContextLengthMessage(detail) {
+			return fmt.Errorf("%w: %s", inferrouter.ErrContextLengthExceeded, detail)
+		}
+		return fmt.Errorf("%w: %s", inferrouter.ErrInvalidRequest, detail)
+	default:
+		return fmt.Errorf("%w: HTTP %d: %s", inferrouter.ErrProviderUnavailable, resp.StatusCode, detail)
+	}
+}
+
+// ndjsonStream reads /api/chat's newline-delimited JSON stream. Each line is
+// an apiResponse; the last has done set and carries the usage.
+type ndjsonStream struct {
+	reader    *bufio.Reader
+	body      io.ReadCloser
+	req       inferrouter.ProviderRequest
+	parseErrs int // consecutive parse errors
+	started   bool
+	toolCalls int
+	done      bool
+}
+
+func (s *ndjsonStream) Next() (inferrouter.StreamChunk, error) {
+	for {
+		if s.done {
+			return inferrouter.StreamChunk{}, io.EOF
+		}
+		line, err := s.reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				// s.done is still false: the body ended before the final
+				// "done": true line, so the stream died mid-reply.
+				return inferrouter.StreamChunk{}, fmt.Errorf("%w: stream ended before done: %v", inferrouter.ErrProviderUnavailable, err)
+			}
+			continue
+		}
+
+		var resp apiResponse
+		if jerr := json.Unmarshal(line, &resp); jerr != nil {
+			s.parseErrs++
+			if s.parseErrs >= 3 {
+				return inferrouter.StreamChunk{}, fmt.Errorf("inferrouter: %d consecutive malformed ollama chunks: %w", s.parseErrs, jerr)
+			}
+			continue
+		}
+		s.parseErrs = 0
+
+		if resp.Error != "" {
+			return inferrouter.StreamChunk{}, fmt.Errorf("%w: %s", inferrouter.ErrProviderUnavailable, resp.Error)
+		}
+		return s.chunk(resp), nil
+	}
+}
+
+func (s *ndjsonStream) chunk(resp apiResponse) inferrouter.StreamChunk {
+	delta := inferrouter.Delta{Content: resp.Message.Content}
+	if !s.started {
+		delta.Role = "assistant"
+		s.started = true
+	}
+	// Ollama sends each tool call whole, in one line.
+	for _, tc := range resp.Message.ToolCalls {
+		delta.ToolCalls = append(delta.ToolCalls, inferrouter.ToolCallDelta{
+			Index:    s.toolCalls,
+			ID:       fmt.Sprintf("call_%d", s.toolCalls),
+			Type:     "function",
+			Function: inferrouter.FunctionCall{Name: tc.Function.Name, Arguments: arguments(tc.Function.Arguments)},
+		})
+		s.toolCalls++
+	}
+
+	out := inferrouter.StreamChunk{
+		Model:   resp.Model,
+		Choices: []inferrouter.StreamDelta{{Delta: delta}},
+	}
+	if resp.Done {
+		s.done = true
+		out.Choices[0].FinishReason = finishReason(resp.DoneReason, s.toolCalls > 0)
+		usage := buildUsage(resp, s.req)
+		out.Usage = &usage
+	}
+	return out
+}
+
+func (s *ndjsonStream) Close() error {
+	return s.body.Close()
+}
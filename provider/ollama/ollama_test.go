@@ -0,0 +1,352 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ir "github.com/ineyio/inferrouter"
+)
+
+func TestName(t *testing.T) {
+	if New().Name() != "ollama" {
+		t.Error("default name should be ollama")
+	}
+	if New(WithName("gpu-box")).Name() != "gpu-box" {
+		t.Error("WithName should override the name")
+	}
+}
+
+func TestSupportsModelStatic(t *testing.T) {
+	p := New()
+	if !p.SupportsModel("anything") {
+		t.Error("without a list or discovery every model is accepted")
+	}
+	p = New(WithModels("llama3.1:latest", "qwen2.5:7b"))
+	if !p.SupportsModel("llama3.1") || !p.SupportsModel("qwen2.5:7b") || p.SupportsModel("qwen2.5") {
+		t.Error("static list should match exact names and the implicit :latest tag")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/tags" {
+			t.Errorf("%s %s", r.Method, r.URL.Path)
+		}
+		_, _ = io.WriteString(w, `{"models":[
+			{"name":"llama3.1:latest","model":"llama3.1:latest","size":4920753328},
+			{"name":"nomic-embed-text:latest","model":"nomic-embed-text:latest"},
+			{"name":"qwen2.5:7b","model":"qwen2.5:7b"}
+		]}`)
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL))
+	names, err := p.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if len(names) != 3 || names[0] != "llama3.1:latest" {
+		t.Errorf("names = %v", names)
+	}
+	for model, want := range map[string]bool{
+		"llama3.1":        true,
+		"llama3.1:latest": true,
+		"qwen2.5:7b":      true,
+		"qwen2.5":         false,
+		"mistral":         false,
+	} {
+		if got := p.SupportsModel(model); got != want {
+			t.Errorf("SupportsModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
+
+func TestDiscoverFailureKeepsPreviousList(t *testing.T) {
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		_, _ = io.WriteString(w, `{"models":[{"name":"llama3.1:latest"}]}`)
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL))
+	if _, err := p.Discover(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	if _, err := p.Discover(context.Background()); !errors.Is(err, ir.ErrProviderUnavailable) {
+		t.Errorf("err = %v, want ErrProviderUnavailable", err)
+	}
+	if !p.SupportsModel("llama3.1") || p.SupportsModel("mistral") {
+		t.Error("a failed Discover should keep the previous list")
+	}
+}
+
+func TestBuildRequest(t *testing.T) {
+	temp, topP := 0.2, 0.9
+	p := New(
+		WithKeepAlive(10*time.Minute),
+		WithOptions(map[string]any{"num_ctx": 8192, "temperature": 0.7}),
+	)
+	req, err := p.buildRequest(ir.ProviderRequest{
+		Model: "llava",
+		Messages: []ir.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Parts: []ir.Part{
+				{Type: ir.PartText, Text: "what is this?"},
+				{Type: ir.PartImage, MIMEType: "image/png", Data: []byte{1, 2, 3}},
+			}},
+		},
+		Temperature:    &temp,
+		TopP:           &topP,
+		MaxTokens:      ir.IntPtr(64),
+		Stop:           []string{"END"},
+		ResponseFormat: &ir.ResponseFormat{Type: ir.ResponseFormatJSON},
+	}, false)
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"stream":false`,
+		`"keep_alive":"10m0s"`,
+		`"format":"json"`,
+		`{"role":"system","content":"be brief"}`,
+		`{"role":"user","content":"what is this?","images":["AQID"]}`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("body missing %s:\n%s", want, body)
+		}
+	}
+
+	opts := req.Options
+	if opts["num_ctx"] != 8192 || opts["temperature"] != 0.2 || opts["top_p"] != 0.9 || opts["num_predict"] != 64 {
+		t.Errorf("options = %v, want provider options with request fields on top", opts)
+	}
+	if p.options["temperature"] != 0.7 {
+		t.Error("request fields must not leak into the provider's options")
+	}
+
+	if _, err := p.buildRequest(ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Parts: []ir.Part{{Type: ir.PartAudio, MIMEType: "audio/wav", Data: []byte{1}}}}},
+	}, false); !errors.Is(err, ir.ErrInvalidRequest) {
+		t.Errorf("audio: err = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestBuildRequestTools(t *testing.T) {
+	p := New()
+	tools := []ir.Tool{{Type: "function", Function: ir.ToolFunction{Name: "weather", Parameters: json.RawMessage(`{"type":"object"}`)}}}
+	msgs := []ir.Message{
+		{Role: "user", Content: "weather?"},
+		{Role: "assistant", ToolCalls: []ir.ToolCall{{ID: "call_0", Type: "function", Function: ir.FunctionCall{Name: "weather", Arguments: `{"city":"Paris"}`}}}},
+		{Role: "tool", ToolCallID: "call_0", Content: "sunny"},
+	}
+
+	req, err := p.buildRequest(ir.ProviderRequest{Messages: msgs, Tools: tools}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Tools) != 1 {
+		t.Errorf("tools = %+v", req.Tools)
+	}
+	if tc := req.Messages[1].ToolCalls; len(tc) != 1 || string(tc[0].Function.Arguments) != `{"city":"Paris"}` {
+		t.Errorf("tool calls = %+v", tc)
+	}
+	if req.Messages[2].ToolName != "weather" {
+		t.Errorf("tool_name = %q, want it looked up from the call", req.Messages[2].ToolName)
+	}
+
+	req, _ = p.buildRequest(ir.ProviderRequest{Messages: msgs, Tools: tools, ToolChoice: &ir.ToolChoice{Mode: ir.ToolChoiceNone}}, false)
+	if len(req.Tools) != 0 {
+		t.Error("tool choice none should drop the tools")
+	}
+}
+
+func TestChatCompletionHappyPath(t *testing.T) {
+	var got apiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Error("no API key, no Authorization header")
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = io.WriteString(w, `{"model":"llama3.1","created_at":"2024-07-22T20:33:28Z",
+			"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"weather","arguments":{"city":"Paris"}}}]},
+			"done":true,"done_reason":"stop","prompt_eval_count":26,"eval_count":12}`)
+	}))
+	defer srv.Close()
+
+	p := New(WithBaseURL(srv.URL))
+	resp, err := p.ChatCompletion(context.Background(), ir.ProviderRequest{
+		Model:    "llama3.1",
+		Messages: []ir.Message{{Role: "user", Content: "weather in Paris?"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if got.Model != "llama3.1" || got.Stream {
+		t.Errorf("request = %+v", got)
+	}
+	if resp.FinishReason != "tool_calls" || len(resp.ToolCalls) != 1 {
+		t.Fatalf("resp = %+v", resp)
+	}
+	tc := resp.ToolCalls[0]
+	if tc.ID != "call_0" || tc.Function.Name != "weather" || tc.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("tool call = %+v", tc)
+	}
+	u := resp.Usage
+	if u.PromptTokens != 26 || u.CompletionTokens != 12 || u.TotalTokens != 38 || u.InputBreakdown == nil || u.InputBreakdown.Text != 26 {
+		t.Errorf("usage = %+v", u)
+	}
+}
+
+func TestChatCompletionErrorMapping(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusNotFound, `{"error":"model 'llama9' not found, try pulling it first"}`, ir.ErrModelNotFound},
+		{http.StatusBadRequest, `{"error":"invalid format"}`, ir.ErrInvalidRequest},
+		{http.StatusUnauthorized, `unauthorized`, ir.ErrAuthFailed},
+		{http.StatusTooManyRequests, `{"error":"server busy"}`, ir.ErrRateLimited},
+		{http.StatusInternalServerError, `{"error":"model requires more system memory"}`, ir.ErrProviderUnavailable},
+	}
+	for _, tc := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, tc.body, tc.status)
+		}))
+		_, err := New(WithBaseURL(srv.URL)).ChatCompletion(context.Background(), ir.ProviderRequest{
+			Model:    "llama9",
+			Messages: []ir.Message{{Role: "user", Content: "hi"}},
+		})
+		srv.Close()
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%d: err = %v, want %v", tc.status, err, tc.want)
+		}
+	}
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	var got apiRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = io.WriteString(w, `{"model":"llama3.1","message":{"role":"assistant","content":"Hel"},"done":false}
+{"model":"llama3.1","message":{"role":"assistant","content":"lo"},"done":false}
+
+{"model":"llama3.1","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":10,"eval_count":2}
+`)
+	}))
+	defer srv.Close()
+
+	stream, err := New(WithBaseURL(srv.URL)).ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Model:    "llama3.1",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	defer stream.Close()
+	if !got.Stream {
+		t.Error("request did not ask for a stream")
+	}
+
+	var (
+		text   strings.Builder
+		role   string
+		finish string
+		usage  *ir.Usage
+	)
+	for {
+		chunk, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		c := chunk.Choices[0]
+		if c.Delta.Role != "" {
+			role = c.Delta.Role
+		}
+		text.WriteString(c.Delta.Content)
+		if c.FinishReason != "" {
+			finish = c.FinishReason
+			usage = chunk.Usage
+		}
+	}
+	if text.String() != "Hello" || role != "assistant" || finish != "length" {
+		t.Errorf("text = %q, role = %q, finish = %q", text.String(), role, finish)
+	}
+	if usage == nil || usage.PromptTokens != 10 || usage.CompletionTokens != 2 || usage.TotalTokens != 12 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestChatCompletionStreamError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"model":"llama3.1","message":{"role":"assistant","content":"Hel"},"done":false}
+{"error":"an error was encountered while running the model"}
+`)
+	}))
+	defer srv.Close()
+
+	stream, err := New(WithBaseURL(srv.URL)).ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("first Next: %v", err)
+	}
+	if _, err := stream.Next(); !errors.Is(err, ir.ErrProviderUnavailable) {
+		t.Errorf("err = %v, want ErrProviderUnavailable", err)
+	}
+}
+
+// A body that ends before the "done": true line is a dead stream, not a
+// finished reply.
+func TestChatCompletionStreamTruncated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"model":"llama3.1","message":{"role":"assistant","content":"Hel"},"done":false}
+`)
+	}))
+	defer srv.Close()
+
+	stream, err := New(WithBaseURL(srv.URL)).ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("first Next: %v", err)
+	}
+	_, err = stream.Next()
+	if errors.Is(err, io.EOF) || !errors.Is(err, ir.ErrProviderUnavailable) {
+		t.Errorf("err = %v, want ErrProviderUnavailable", err)
+	}
+}
@@ -0,0 +1,207 @@
+package openaicompat
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/ineyio/inferrouter"
+)
+
+// EmbeddingProvider interface compliance.
+var _ inferrouter.EmbeddingProvider = (*Provider)(nil)
+
+// defaultEmbedMaxBatch is OpenAI's limit on inputs per /embeddings call.
+// Self-hosted servers are often lower (TEI defaults to 32); set theirs with
+// WithEmbedBatchSize.
+const defaultEmbedMaxBatch = 2048
+
+// openAIEmbedModels are the embedding models NewOpenAI enables by default.
+var openAIEmbedModels = []string{
+	"text-embedding-3-small",
+	"text-embedding-3-large",
+	"text-embedding-ada-002",
+}
+
+// WithEmbeddingModels lists the models served by the endpoint's /embeddings.
+// Chat and embedding models share one endpoint but not one namespace, so
+// embeddings are opt-in: without this option (or NewOpenAI's defaults) the
+// provider reports no embedding models.
+func WithEmbeddingModels(models ...string) Option {
+	return func(p *Provider) { p.embedModels = models }
+}
+
+// WithEmbedBatchSize sets the most inputs sent in one /embeddings call.
+// Default 2048.
+func WithEmbedBatchSize(n int) Option {
+	return func(p *Provider) { p.embedMaxBatch = n }
+}
+
+// WithBase64Embeddings asks for encoding_format "base64", which is about a
+// quarter the size of the JSON float arrays on the wire. Responses are
+// decoded in either format regardless.
+func WithBase64Embeddings() Option {
+	return func(p *Provider) { p.embedBase64 = true }
+}
+
+// SupportsEmbeddingModel reports whether model was listed with
+// WithEmbeddingModels. It is independent of SupportsModel.
+func (p *Provider) SupportsEmbeddingModel(model string) bool {
+	return slices.Contains(p.embedModels, model)
+}
+
+// MaxBatchSize returns the most inputs accepted in one /embeddings call.
+func (p *Provider) MaxBatchSize() int {
+	if p.embedMaxBatch > 0 {
+		return p.embedMaxBatch
+	}
+	return defaultEmbedMaxBatch
+}
+
+// Embed calls /embeddings for a batch of inputs. The router guarantees
+// len(req.Inputs) <= MaxBatchSize() before calling. TaskType has no OpenAI
+// equivalent and is ignored; OutputDimensionality is sent as dimensions.
+func (p *Provider) Embed(ctx context.Context, req inferrouter.EmbedProviderRequest) (inferrouter.EmbedProviderResponse, error) {
+	body := apiEmbedRequest{
+		Model:      req.Model,
+		Input:      req.Inputs,
+		Dimensions: req.OutputDimensionality,
+	}
+	if p.embedBase64 {
+		body.EncodingFormat = "base64"
+	}
+
+	httpResp, err := p.doEmbedRequest(ctx, req.Auth, body)
+	if err != nil {
+		return inferrouter.EmbedProviderResponse{}, err
+	}
+	defer httpResp.Body.Close()
+
+	if err := mapHTTPError(httpResp); err != nil {
+		return inferrouter.EmbedProviderResponse{}, err
+	}
+
+	var resp apiEmbedResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return inferrouter.EmbedProviderResponse{}, fmt.Errorf("inferrouter: decode embed response: %w", err)
+	}
+
+	if len(resp.Data) != len(req.Inputs) {
+		return inferrouter.EmbedProviderResponse{}, fmt.Errorf(
+			"inferrouter: embed response size mismatch: got %d, want %d",
+			len(resp.Data), len(req.Inputs))
+	}
+
+	// Data is ordered by index in practice, but the index is what the API
+	// promises.
+	embeddings := make([][]float32, len(resp.Data))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(embeddings) || embeddings[d.Index] != nil {
+			return inferrouter.EmbedProviderResponse{}, fmt.Errorf("inferrouter: embed response has bad index %d", d.Index)
+		}
+		embeddings[d.Index] = d.Embedding.values
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = req.Model
+	}
+
+	usage := inferrouter.EmbedUsage{
+		InputTokens: resp.Usage.PromptTokens,
+		TotalTokens: resp.Usage.TotalTokens,
+	}
+	if usage.InputTokens == 0 {
+		// Some servers omit usage. Estimate with the router's heuristic,
+		// as the gemini adapter does, so Commit lines up with Reserve.
+		for _, in := range req.Inputs {
+			usage.InputTokens += int64(len(in)) / 4
+		}
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.InputTokens
+	}
+
+	return inferrouter.EmbedProviderResponse{
+		Embeddings: embeddings,
+		Model:      model,
+		Usage:      usage,
+	}, nil
+}
+
+// --- OpenAI embed API types ---
+
+type apiEmbedRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format,omitempty"`
+}
+
+type apiEmbedResponse struct {
+	Data []struct {
+		Index     int          `json:"index"`
+		Embedding apiEmbedding `json:"embedding"`
+	} `json:"data"`
+	Model string `json:"model"`
+	Usage struct {
+		PromptTokens int64 `json:"prompt_tokens"`
+		TotalTokens  int64 `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// apiEmbedding is one vector: a JSON float array, or with encoding_format
+// "base64" a base64 string of little-endian float32s.
+type apiEmbedding struct {
+	values []float32
+}
+
+func (e *apiEmbedding) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '"' {
+		return json.Unmarshal(data, &e.values)
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("base64 embedding: %w", err)
+	}
+	if len(raw)%4 != 0 {
+		return fmt.Errorf("base64 embedding: %d bytes is not a float32 array", len(raw))
+	}
+	e.values = make([]float32, len(raw)/4)
+	for i := range e.values {
+		e.values[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:]))
+	}
+	return nil
+}
+
+// doEmbedRequest is analogous to doRequest for chat, but typed for embed.
+func (p *Provider) doEmbedRequest(ctx context.Context, auth inferrouter.Auth, body apiEmbedRequest) (*http.Response, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("inferrouter: marshal embed request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/embeddings", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("inferrouter: create embed request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+auth.APIKey)
+	inferrouter.InjectTraceHeaders(ctx, httpReq.Header)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, inferrouter.ErrProviderUnavailable
+	}
+	return resp, nil
+}
@@ -0,0 +1,180 @@
+package openaicompat
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ir "github.com/ineyio/inferrouter"
+)
+
+func TestSupportsEmbeddingModel(t *testing.T) {
+	if New("together", "http://x").SupportsEmbeddingModel("BAAI/bge-large-en-v1.5") {
+		t.Error("embeddings are opt-in; a bare provider should report none")
+	}
+	p := New("together", "http://x", WithEmbeddingModels("BAAI/bge-large-en-v1.5"))
+	if !p.SupportsEmbeddingModel("BAAI/bge-large-en-v1.5") || p.SupportsEmbeddingModel("meta-llama/Llama-3-8b") {
+		t.Error("only the listed model should be an embedding model")
+	}
+
+	openai := NewOpenAI()
+	if !openai.SupportsEmbeddingModel("text-embedding-3-small") || openai.SupportsEmbeddingModel("gpt-4o") {
+		t.Error("NewOpenAI should enable the text-embedding models only")
+	}
+	if NewOpenAI(WithEmbeddingModels("custom")).SupportsEmbeddingModel("text-embedding-3-small") {
+		t.Error("WithEmbeddingModels should replace NewOpenAI's defaults")
+	}
+}
+
+func TestMaxBatchSize(t *testing.T) {
+	if got := New("x", "http://x").MaxBatchSize(); got != 2048 {
+		t.Errorf("default = %d, want 2048", got)
+	}
+	if got := New("tei", "http://x", WithEmbedBatchSize(32)).MaxBatchSize(); got != 32 {
+		t.Errorf("configured = %d, want 32", got)
+	}
+}
+
+func TestEmbed_HappyPath(t *testing.T) {
+	var gotBody apiEmbedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("auth = %q", r.Header.Get("Authorization"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		// Out of order on purpose: the index decides placement.
+		_, _ = io.WriteString(w, `{"object":"list","model":"text-embedding-3-small","data":[
+			{"object":"embedding","index":1,"embedding":[0.3,0.4]},
+			{"object":"embedding","index":0,"embedding":[0.1,0.2]}
+		],"usage":{"prompt_tokens":12,"total_tokens":12}}`)
+	}))
+	defer srv.Close()
+
+	p := NewOpenAI(func(p *Provider) { p.baseURL = srv.URL })
+	resp, err := p.Embed(context.Background(), ir.EmbedProviderRequest{
+		Auth:                 ir.Auth{APIKey: "k"},
+		Model:                "text-embedding-3-small",
+		Inputs:               []string{"first", "second"},
+		TaskType:             "RETRIEVAL_DOCUMENT",
+		OutputDimensionality: 2,
+	})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if gotBody.Model != "text-embedding-3-small" || len(gotBody.Input) != 2 || gotBody.Dimensions != 2 || gotBody.EncodingFormat != "" {
+		t.Errorf("request = %+v", gotBody)
+	}
+	if len(resp.Embeddings) != 2 || resp.Embeddings[0][0] != 0.1 || resp.Embeddings[1][1] != 0.4 {
+		t.Errorf("embeddings = %v", resp.Embeddings)
+	}
+	if resp.Usage.InputTokens != 12 || resp.Usage.TotalTokens != 12 || resp.Model != "text-embedding-3-small" {
+		t.Errorf("resp = %+v", resp)
+	}
+}
+
+func TestEmbed_Base64(t *testing.T) {
+	want := []float32{0.5, -1.25, float32(math.Pi)}
+	raw := make([]byte, 4*len(want))
+	for i, v := range want {
+		binary.LittleEndian.PutUint32(raw[i*4:], math.Float32bits(v))
+	}
+	encoded := base64.StdEncoding.EncodeToString(raw)
+
+	var gotFormat string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body apiEmbedRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotFormat = body.EncodingFormat
+		_, _ = io.WriteString(w, `{"data":[{"index":0,"embedding":"`+encoded+`"}],"usage":{"prompt_tokens":3,"total_tokens":3}}`)
+	}))
+	defer srv.Close()
+
+	p := New("tei", srv.URL, WithEmbeddingModels("bge"), WithBase64Embeddings())
+	resp, err := p.Embed(context.Background(), ir.EmbedProviderRequest{Model: "bge", Inputs: []string{"x"}})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if gotFormat != "base64" {
+		t.Errorf("encoding_format = %q, want base64", gotFormat)
+	}
+	if len(resp.Embeddings) != 1 || len(resp.Embeddings[0]) != 3 {
+		t.Fatalf("embeddings = %v", resp.Embeddings)
+	}
+	for i, v := range want {
+		if resp.Embeddings[0][i] != v {
+			t.Errorf("value %d = %v, want %v", i, resp.Embeddings[0][i], v)
+		}
+	}
+	if resp.Model != "bge" {
+		t.Errorf("model = %q, want the requested bge when the server omits it", resp.Model)
+	}
+}
+
+func TestEmbed_EstimatesMissingUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"data":[{"index":0,"embedding":[1]}]}`)
+	}))
+	defer srv.Close()
+
+	p := New("tei", srv.URL, WithEmbeddingModels("bge"))
+	resp, err := p.Embed(context.Background(), ir.EmbedProviderRequest{Model: "bge", Inputs: []string{strings.Repeat("a", 40)}})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if resp.Usage.InputTokens != 10 || resp.Usage.TotalTokens != 10 {
+		t.Errorf("usage = %+v, want the len/4 estimate of 10", resp.Usage)
+	}
+}
+
+func TestEmbed_ErrorMapping(t *testing.T) {
+	cases := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusTooManyRequests, ir.ErrRateLimited},
+		{http.StatusUnauthorized, ir.ErrAuthFailed},
+		{http.StatusBadRequest, ir.ErrInvalidRequest},
+		{http.StatusServiceUnavailable, ir.ErrProviderUnavailable},
+	}
+	for _, tc := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"error":{"message":"nope"}}`, tc.status)
+		}))
+		p := New("x", srv.URL, WithEmbeddingModels("m"))
+		_, err := p.Embed(context.Background(), ir.EmbedProviderRequest{Model: "m", Inputs: []string{"a"}})
+		srv.Close()
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%d: err = %v, want %v", tc.status, err, tc.want)
+		}
+	}
+}
+
+func TestEmbed_BadResponse(t *testing.T) {
+	cases := map[string]string{
+		"size mismatch":   `{"data":[{"index":0,"embedding":[1]}]}`,
+		"duplicate index": `{"data":[{"index":0,"embedding":[1]},{"index":0,"embedding":[2]}]}`,
+		"bad base64":      `{"data":[{"index":0,"embedding":"!!"},{"index":1,"embedding":[2]}]}`,
+	}
+	for name, body := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, body)
+		}))
+		p := New("x", srv.URL, WithEmbeddingModels("m"))
+		_, err := p.Embed(context.Background(), ir.EmbedProviderRequest{Model: "m", Inputs: []string{"a", "b"}})
+		srv.Close()
+		if err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/ineyio/inferrouter"
@@ -20,9 +22,19 @@ type Provider struct {
 	baseURL    string
 	httpClient *http.Client
 	models     []string
+	multimodal []string
+
+	embedModels   []string
+	embedMaxBatch int
+	embedBase64   bool
 }
 
-var _ inferrouter.Provider = (*Provider)(nil)
+var (
+	_ inferrouter.Provider                = (*Provider)(nil)
+	_ inferrouter.ToolProvider            = (*Provider)(nil)
+	_ inferrouter.MultimodalModelProvider = (*Provider)(nil)
+	_ inferrouter.ChoicesProvider         = (*Provider)(nil)
+)
 
 // Option configures the provider.
 type Option func(*Provider)
@@ -37,6 +49,13 @@ func WithModels(models ...string) Option {
 	return func(p *Provider) { p.models = models }
 }
 
+// WithMultimodalModels lists the models that accept media parts. The router
+// sends image and audio requests only to these; every other model behind the
+// endpoint is treated as text-only.
+func WithMultimodalModels(models ...string) Option {
+	return func(p *Provider) { p.multimodal = models }
+}
+
 // New creates a new OpenAI-compatible provider.
 func New(name, baseURL string, opts ...Option) *Provider {
 	p := &Provider{
@@ -50,8 +69,10 @@ func New(name, baseURL string, opts ...Option) *Provider {
 	return p
 }
 
-// NewOpenAI creates a provider for OpenAI.
+// NewOpenAI creates a provider for OpenAI, with the text-embedding-3 and
+// ada-002 embedding models enabled.
 func NewOpenAI(opts ...Option) *Provider {
+	opts = append([]Option{WithEmbeddingModels(openAIEmbedModels...)}, opts...)
 	return New("openai", "https://api.openai.com/v1", opts...)
 }
 
@@ -67,10 +88,26 @@ func NewCerebro(opts ...Option) *Provider {
 
 func (p *Provider) Name() string { return p.name }
 
-// SupportsMultimodal returns false. OpenAI-compatible backends support a
-// content[] array format for vision, but this provider does not yet serialize
-// Parts into that format — advertising true would silently strip media.
-func (p *Provider) SupportsMultimodal() bool { return false }
+// SupportsMultimodal reports whether any model was configured with
+// WithMultimodalModels. Media parts are sent in the content[] array format:
+// images as image_url data URLs, audio as input_audio.
+func (p *Provider) SupportsMultimodal() bool { return len(p.multimodal) > 0 }
+
+// SupportsMultimodalModel reports whether model was configured with
+// WithMultimodalModels. Endpoints mix vision and text-only models, so the
+// router asks per model rather than per provider.
+func (p *Provider) SupportsMultimodalModel(model string) bool {
+	return slices.Contains(p.multimodal, model)
+}
+
+// SupportsTools reports that the OpenAI tools/tool_calls format is serialized.
+// Whether a particular model behind the endpoint honours it is up to the
+// backend; this adapter never drops tool definitions.
+func (p *Provider) SupportsTools() bool { return true }
+
+// SupportsChoices reports that n is forwarded and every returned choice is
+// mapped.
+func (p *Provider) SupportsChoices() bool { return true }
 
 func (p *Provider) SupportsModel(model string) bool {
 	if len(p.models) == 0 {
@@ -93,11 +130,110 @@ type apiRequest struct {
 	TopP        *float64     `json:"top_p,omitempty"`
 	Stream      bool         `json:"stream,omitempty"`
 	Stop        []string     `json:"stop,omitempty"`
+	Tools       []apiTool    `json:"tools,omitempty"`
+	ToolChoice  any          `json:"tool_choice,omitempty"`
+
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	N                *int     `json:"n,omitempty"`
+
+	ResponseFormat *apiResponseFormat `json:"response_format,omitempty"`
+}
+
+type apiResponseFormat struct {
+	Type       string         `json:"type"`
+	JSONSchema *apiJSONSchema `json:"json_schema,omitempty"`
+}
+
+type apiJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema,omitempty"`
+	Strict bool            `json:"strict,omitempty"`
 }
 
 type apiMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string        `json:"role"`
+	Content    apiContent    `json:"content"`
+	Name       string        `json:"name,omitempty"`
+	ToolCalls  []apiToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string        `json:"tool_call_id,omitempty"`
+}
+
+// apiContent is a message's content: a plain string, or the content[] array
+// of typed parts when the message carries media.
+type apiContent struct {
+	Text  string
+	Parts []apiContentPart
+}
+
+type apiContentPart struct {
+	Type       string         `json:"type"`
+	Text       string         `json:"text,omitempty"`
+	ImageURL   *apiImageURL   `json:"image_url,omitempty"`
+	InputAudio *apiInputAudio `json:"input_audio,omitempty"`
+}
+
+type apiImageURL struct {
+	URL string `json:"url"`
+}
+
+type apiInputAudio struct {
+	Data   string `json:"data"`
+	Format string `json:"format"`
+}
+
+func (c apiContent) MarshalJSON() ([]byte, error) {
+	if c.Parts != nil {
+		return json.Marshal(c.Parts)
+	}
+	return json.Marshal(c.Text)
+}
+
+// UnmarshalJSON accepts a string, null, or a content[] array, whose text
+// parts are concatenated into Text.
+func (c *apiContent) UnmarshalJSON(data []byte) error {
+	*c = apiContent{}
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &c.Parts); err != nil {
+			return err
+		}
+		var b strings.Builder
+		for _, p := range c.Parts {
+			b.WriteString(p.Text)
+		}
+		c.Text = b.String()
+		return nil
+	}
+	if string(data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(data, &c.Text)
+}
+
+type apiTool struct {
+	Type     string          `json:"type"`
+	Function apiToolFunction `json:"function"`
+}
+
+type apiToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// apiToolCall is used both for complete calls (responses, request history)
+// and for streamed deltas, where Index identifies the call being extended.
+type apiToolCall struct {
+	Index    *int            `json:"index,omitempty"`
+	ID       string          `json:"id,omitempty"`
+	Type     string          `json:"type,omitempty"`
+	Function apiFunctionCall `json:"function"`
+}
+
+type apiFunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
 }
 
 // apiResponse is the OpenAI chat completion response format.
@@ -109,11 +245,19 @@ type apiResponse struct {
 		Message      apiMessage `json:"message"`
 		FinishReason string     `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int64 `json:"prompt_tokens"`
-		CompletionTokens int64 `json:"completion_tokens"`
-		TotalTokens      int64 `json:"total_tokens"`
-	} `json:"usage"`
+	Usage apiUsage `json:"usage"`
+}
+
+type apiUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+
+	PromptTokensDetails *struct {
+		CachedTokens int64 `json:"cached_tokens"`
+		AudioTokens  int64 `json:"audio_tokens"`
+		ImageTokens  int64 `json:"image_tokens"`
+	} `json:"prompt_tokens_details,omitempty"`
 }
 
 // apiStreamChunk is a single SSE chunk.
@@ -123,20 +267,20 @@ type apiStreamChunk struct {
 	Choices []struct {
 		Index int `json:"index"`
 		Delta struct {
-			Role    string `json:"role,omitempty"`
-			Content string `json:"content,omitempty"`
+			Role      string        `json:"role,omitempty"`
+			Content   string        `json:"content,omitempty"`
+			ToolCalls []apiToolCall `json:"tool_calls,omitempty"`
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason,omitempty"`
 	} `json:"choices"`
-	Usage *struct {
-		PromptTokens     int64 `json:"prompt_tokens"`
-		CompletionTokens int64 `json:"completion_tokens"`
-		TotalTokens      int64 `json:"total_tokens"`
-	} `json:"usage,omitempty"`
+	Usage *apiUsage `json:"usage,omitempty"`
 }
 
 func (p *Provider) ChatCompletion(ctx context.Context, req inferrouter.ProviderRequest) (inferrouter.ProviderResponse, error) {
-	body := p.buildRequest(req, false)
+	body, err := p.buildRequest(req, false)
+	if err != nil {
+		return inferrouter.ProviderResponse{}, err
+	}
 
 	httpResp, err := p.doRequest(ctx, req.Auth, body)
 	if err != nil {
@@ -157,21 +301,33 @@ func (p *Provider) ChatCompletion(ctx context.Context, req inferrouter.ProviderR
 		return inferrouter.ProviderResponse{}, fmt.Errorf("inferrouter: empty choices in response")
 	}
 
-	return inferrouter.ProviderResponse{
+	out := inferrouter.ProviderResponse{
 		ID:           resp.ID,
-		Content:      resp.Choices[0].Message.Content,
+		Content:      resp.Choices[0].Message.Content.Text,
+		ToolCalls:    fromAPIToolCalls(resp.Choices[0].Message.ToolCalls),
 		FinishReason: resp.Choices[0].FinishReason,
 		Model:        resp.Model,
-		Usage: inferrouter.Usage{
-			PromptTokens:     resp.Usage.PromptTokens,
-			CompletionTokens: resp.Usage.CompletionTokens,
-			TotalTokens:      resp.Usage.TotalTokens,
-		},
-	}, nil
+		Usage:        resp.Usage.toUsage(),
+		RateLimits:   inferrouter.ParseRateLimitHeaders(httpResp.Header),
+	}
+	if len(resp.Choices) > 1 {
+		out.Choices = make([]inferrouter.Choice, len(resp.Choices))
+		for i, c := range resp.Choices {
+			out.Choices[i] = inferrouter.Choice{
+				Index:        c.Index,
+				Message:      inferrouter.Message{Role: "assistant", Content: c.Message.Content.Text, ToolCalls: fromAPIToolCalls(c.Message.ToolCalls)},
+				FinishReason: c.FinishReason,
+			}
+		}
+	}
+	return out, nil
 }
 
 func (p *Provider) ChatCompletionStream(ctx context.Context, req inferrouter.ProviderRequest) (inferrouter.ProviderStream, error) {
-	body := p.buildRequest(req, true)
+	body, err := p.buildRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
 
 	httpResp, err := p.doRequest(ctx, req.Auth, body)
 	if err != nil {
@@ -184,16 +340,33 @@ func (p *Provider) ChatCompletionStream(ctx context.Context, req inferrouter.Pro
 	}
 
 	return &sseStream{
-		reader: bufio.NewReader(httpResp.Body),
-		body:   httpResp.Body,
+		reader:     bufio.NewReader(httpResp.Body),
+		body:       httpResp.Body,
+		rateLimits: inferrouter.ParseRateLimitHeaders(httpResp.Header),
 	}, nil
 }
 
-func (p *Provider) buildRequest(req inferrouter.ProviderRequest, stream bool) apiRequest {
+func (p *Provider) buildRequest(req inferrouter.ProviderRequest, stream bool) (apiRequest, error) {
 	msgs := make([]apiMessage, len(req.Messages))
 	for i, m := range req.Messages {
-		msgs[i] = apiMessage{Role: m.Role, Content: m.Content}
+		content, err := buildContent(m)
+		if err != nil {
+			return apiRequest{}, err
+		}
+		msgs[i] = apiMessage{
+			Role:       m.Role,
+			Content:    content,
+			Name:       m.Name,
+			ToolCalls:  toAPIToolCalls(m.ToolCalls),
+			ToolCallID: m.ToolCallID,
+		}
 	}
+	n := req.N
+	if stream {
+		// Several choices would interleave on one stream; ask for one.
+		n = nil
+	}
+
 	return apiRequest{
 		Model:       req.Model,
 		Messages:    msgs,
@@ -202,9 +375,204 @@ func (p *Provider) buildRequest(req inferrouter.ProviderRequest, stream bool) ap
 		TopP:        req.TopP,
 		Stream:      stream,
 		Stop:        req.Stop,
+		Tools:       buildTools(req.Tools),
+		ToolChoice:  buildToolChoice(req.ToolChoice),
+
+		Seed:             req.Seed,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		N:                n,
+		ResponseFormat:   buildResponseFormat(req.ResponseFormat),
+	}, nil
+}
+
+// buildContent maps a message to a plain string, or to the content[] array
+// when it has Parts. Images become base64 data URLs and audio becomes
+// input_audio; video has no OpenAI representation and is rejected.
+func buildContent(m inferrouter.Message) (apiContent, error) {
+	if len(m.Parts) == 0 {
+		return apiContent{Text: m.Content}, nil
+	}
+	parts := make([]apiContentPart, 0, len(m.Parts))
+	for _, p := range m.Parts {
+		switch p.Type {
+		case inferrouter.PartText:
+			parts = append(parts, apiContentPart{Type: "text", Text: p.Text})
+		case inferrouter.PartImage:
+			parts = append(parts, apiContentPart{Type: "image_url", ImageURL: &apiImageURL{
+				URL: "data:" + p.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(p.Data),
+			}})
+		case inferrouter.PartAudio:
+			parts = append(parts, apiContentPart{Type: "input_audio", InputAudio: &apiInputAudio{
+				Data:   base64.StdEncoding.EncodeToString(p.Data),
+				Format: audioFormat(p.MIMEType),
+			}})
+		default:
+			return apiContent{}, fmt.Errorf("%w: %s parts are not supported by OpenAI-compatible providers", inferrouter.ErrInvalidRequest, p.Type)
+		}
+	}
+	return apiContent{Parts: parts}, nil
+}
+
+// audioFormat maps a MIME type to input_audio's format name ("wav", "mp3").
+// Unknown subtypes are passed through for the backend to judge.
+func audioFormat(mimeType string) string {
+	sub := strings.TrimPrefix(mimeType, "audio/")
+	switch sub {
+	case "mpeg", "mp3":
+		return "mp3"
+	case "wav", "wave", "x-wav", "vnd.wave":
+		return "wav"
+	default:
+		return sub
 	}
 }
 
+// toUsage maps the usage block. Audio and image tokens reported in
+// prompt_tokens_details become an InputBreakdown with the rest counted as
+// text; without them the breakdown stays nil.
+func (u apiUsage) toUsage() inferrouter.Usage {
+	out := inferrouter.Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if d := u.PromptTokensDetails; d != nil {
+		out.CachedTokens = d.CachedTokens
+		if d.AudioTokens > 0 || d.ImageTokens > 0 {
+			out.InputBreakdown = &inferrouter.InputTokenBreakdown{
+				Text:  u.PromptTokens - d.AudioTokens - d.ImageTokens,
+				Audio: d.AudioTokens,
+				Image: d.ImageTokens,
+			}
+		}
+	}
+	return out
+}
+
+// buildResponseFormat maps ResponseFormat to OpenAI's response_format. Plain
+// text is the API default and is omitted.
+func buildResponseFormat(rf *inferrouter.ResponseFormat) *apiResponseFormat {
+	if rf == nil {
+		return nil
+	}
+	switch rf.Type {
+	case inferrouter.ResponseFormatJSON:
+		return &apiResponseFormat{Type: string(rf.Type)}
+	case inferrouter.ResponseFormatJSONSchema:
+		name := rf.Name
+		if name == "" {
+			name = "response"
+		}
+		return &apiResponseFormat{
+			Type: string(rf.Type),
+			JSONSchema: &apiJSONSchema{
+				Name:   name,
+				Schema: rf.Schema,
+				Strict: rf.Strict,
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+func buildTools(tools []inferrouter.Tool) []apiTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make([]apiTool, len(tools))
+	for i, t := range tools {
+		typ := t.Type
+		if typ == "" {
+			typ = "function"
+		}
+		out[i] = apiTool{
+			Type: typ,
+			Function: apiToolFunction{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  t.Function.Parameters,
+			},
+		}
+	}
+	return out
+}
+
+// buildToolChoice maps ToolChoice to OpenAI's tool_choice, which is either a
+// mode string or an object naming the forced function.
+func buildToolChoice(tc *inferrouter.ToolChoice) any {
+	if tc == nil {
+		return nil
+	}
+	if tc.Function != "" {
+		return map[string]any{
+			"type":     "function",
+			"function": map[string]string{"name": tc.Function},
+		}
+	}
+	if tc.Mode == "" {
+		return nil
+	}
+	return string(tc.Mode)
+}
+
+func toAPIToolCalls(calls []inferrouter.ToolCall) []apiToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]apiToolCall, len(calls))
+	for i, c := range calls {
+		typ := c.Type
+		if typ == "" {
+			typ = "function"
+		}
+		out[i] = apiToolCall{
+			ID:       c.ID,
+			Type:     typ,
+			Function: apiFunctionCall{Name: c.Function.Name, Arguments: c.Function.Arguments},
+		}
+	}
+	return out
+}
+
+func fromAPIToolCalls(calls []apiToolCall) []inferrouter.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]inferrouter.ToolCall, len(calls))
+	for i, c := range calls {
+		out[i] = inferrouter.ToolCall{
+			ID:       c.ID,
+			Type:     c.Type,
+			Function: inferrouter.FunctionCall{Name: c.Function.Name, Arguments: c.Function.Arguments},
+		}
+	}
+	return out
+}
+
+// fromAPIToolCallDeltas maps streamed tool call fragments. Index is required
+// by the OpenAI format; a backend that omits it gets position order.
+func fromAPIToolCallDeltas(calls []apiToolCall) []inferrouter.ToolCallDelta {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]inferrouter.ToolCallDelta, len(calls))
+	for i, c := range calls {
+		idx := i
+		if c.Index != nil {
+			idx = *c.Index
+		}
+		out[i] = inferrouter.ToolCallDelta{
+			Index:    idx,
+			ID:       c.ID,
+			Type:     c.Type,
+			Function: inferrouter.FunctionCall{Name: c.Function.Name, Arguments: c.Function.Arguments},
+		}
+	}
+	return out
+}
+
 func (p *Provider) doRequest(ctx context.Context, auth inferrouter.Auth, body apiRequest) (*http.Response, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
@@ -219,6 +587,7 @@ func (p *Provider) doRequest(ctx context.Context, auth inferrouter.Auth, body ap
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+auth.APIKey)
+	inferrouter.InjectTraceHeaders(ctx, httpReq.Header)
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
@@ -246,11 +615,18 @@ func mapHTTPError(resp *http.Response) error {
 
 	switch resp.StatusCode {
 	case http.StatusTooManyRequests:
-		return fmt.Errorf("%w: %s", inferrouter.ErrRateLimited, detail)
+		wait, _ := inferrouter.ParseRateLimitHeaders(resp.Header).Backoff()
+		return &inferrouter.RateLimitError{RetryAfter: wait, Detail: detail}
 	case http.StatusUnauthorized, http.StatusForbidden:
 		return fmt.Errorf("%w: %s", inferrouter.ErrAuthFailed, detail)
-	case http.StatusBadRequest:
-		return fmt.Errorf("%w: %s", inferrouter.ErrInvalidRequest, detail)
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		if inferrouter.IsContextLengthMessage(detail) {
+			return fmt.Errorf("%w: %s", inferrouter.ErrContextLengthExceeded, detail)
+		}
+		if resp.StatusCode == http.StatusBadRequest {
+			return fmt.Errorf("%w: %s", inferrouter.ErrInvalidRequest, detail)
+		}
+		return fmt.Errorf("%w: HTTP %d: %s", inferrouter.ErrProviderUnavailable, resp.StatusCode, detail)
 	default:
 		return fmt.Errorf("%w: HTTP %d: %s", inferrouter.ErrProviderUnavailable, resp.StatusCode, detail)
 	}
@@ -258,16 +634,28 @@ func mapHTTPError(resp *http.Response) error {
 
 // sseStream parses Server-Sent Events from an HTTP response body.
 type sseStream struct {
-	reader    *bufio.Reader
-	body      io.ReadCloser
-	parseErrs int // consecutive parse errors
+	reader     *bufio.Reader
+	body       io.ReadCloser
+	parseErrs  int  // consecutive parse errors
+	finished   bool // a finish_reason was seen
+	rateLimits *inferrouter.RateLimitInfo
 }
 
+var _ inferrouter.RateLimitReporter = (*sseStream)(nil)
+
+// RateLimits returns the rate limit headers the stream was opened with.
+func (s *sseStream) RateLimits() *inferrouter.RateLimitInfo { return s.rateLimits }
+
 func (s *sseStream) Next() (inferrouter.StreamChunk, error) {
 	for {
 		line, err := s.reader.ReadString('\n')
 		if err != nil {
-			return inferrouter.StreamChunk{}, io.EOF
+			// Some servers close after the last choice without [DONE]; any
+			// other end of the body is a dead stream the router may fail over.
+			if err == io.EOF && s.finished {
+				return inferrouter.StreamChunk{}, io.EOF
+			}
+			return inferrouter.StreamChunk{}, fmt.Errorf("%w: stream ended before [DONE]: %v", inferrouter.ErrProviderUnavailable, err)
 		}
 
 		line = strings.TrimSpace(line)
@@ -281,6 +669,7 @@ func (s *sseStream) Next() (inferrouter.StreamChunk, error) {
 
 		data := strings.TrimPrefix(line, "data: ")
 		if data == "[DONE]" {
+			s.finished = true
 			return inferrouter.StreamChunk{}, io.EOF
 		}
 
@@ -300,19 +689,23 @@ func (s *sseStream) Next() (inferrouter.StreamChunk, error) {
 		}
 
 		for _, c := range chunk.Choices {
+			if c.FinishReason != "" {
+				s.finished = true
+			}
 			result.Choices = append(result.Choices, inferrouter.StreamDelta{
-				Index:        c.Index,
-				Delta:        inferrouter.Delta{Role: c.Delta.Role, Content: c.Delta.Content},
+				Index: c.Index,
+				Delta: inferrouter.Delta{
+					Role:      c.Delta.Role,
+					Content:   c.Delta.Content,
+					ToolCalls: fromAPIToolCallDeltas(c.Delta.ToolCalls),
+				},
 				FinishReason: c.FinishReason,
 			})
 		}
 
 		if chunk.Usage != nil {
-			result.Usage = &inferrouter.Usage{
-				PromptTokens:     chunk.Usage.PromptTokens,
-				CompletionTokens: chunk.Usage.CompletionTokens,
-				TotalTokens:      chunk.Usage.TotalTokens,
-			}
+			usage := chunk.Usage.toUsage()
+			result.Usage = &usage
 		}
 
 		return result, nil
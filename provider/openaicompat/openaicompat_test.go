@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/quota"
 )
 
 func TestSupportsModel(t *testing.T) {
@@ -109,7 +112,7 @@ func TestChatCompletionHappyPath(t *testing.T) {
 	if gotBody.Stream {
 		t.Error("non-stream request should have stream=false")
 	}
-	if len(gotBody.Messages) != 1 || gotBody.Messages[0].Content != "hi" {
+	if len(gotBody.Messages) != 1 || gotBody.Messages[0].Content.Text != "hi" {
 		t.Errorf("body messages = %+v", gotBody.Messages)
 	}
 	if gotBody.Temperature == nil || *gotBody.Temperature != 0.7 {
@@ -137,19 +140,32 @@ func TestChatCompletionErrorMapping(t *testing.T) {
 	cases := []struct {
 		name     string
 		status   int
+		body     string
 		wantSent error
 	}{
-		{"429", http.StatusTooManyRequests, ir.ErrRateLimited},
-		{"401", http.StatusUnauthorized, ir.ErrAuthFailed},
-		{"403", http.StatusForbidden, ir.ErrAuthFailed},
-		{"400", http.StatusBadRequest, ir.ErrInvalidRequest},
-		{"500", http.StatusInternalServerError, ir.ErrProviderUnavailable},
-		{"502", http.StatusBadGateway, ir.ErrProviderUnavailable},
+		{"429", http.StatusTooManyRequests, "", ir.ErrRateLimited},
+		{"401", http.StatusUnauthorized, "", ir.ErrAuthFailed},
+		{"403", http.StatusForbidden, "", ir.ErrAuthFailed},
+		{"400", http.StatusBadRequest, "", ir.ErrInvalidRequest},
+		{"500", http.StatusInternalServerError, "", ir.ErrProviderUnavailable},
+		{"502", http.StatusBadGateway, "", ir.ErrProviderUnavailable},
+		{"400 openai context", http.StatusBadRequest,
+			`{"error":{"message":"This model's maximum context length is 8192 tokens. However, your messages resulted in 9000 tokens.","code":"context_length_exceeded"}}`,
+			ir.ErrContextLengthExceeded},
+		{"400 llama.cpp context", http.StatusBadRequest,
+			`{"error":{"code":400,"message":"the request exceeds the available context size, try increasing it"}}`,
+			ir.ErrContextLengthExceeded},
+		{"413 context", http.StatusRequestEntityTooLarge, `{"error":"prompt is too long"}`, ir.ErrContextLengthExceeded},
+		{"413", http.StatusRequestEntityTooLarge, "", ir.ErrProviderUnavailable},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
+			body := tc.body
+			if body == "" {
+				body = `{"error":"oops"}`
+			}
 			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				http.Error(w, `{"error":"oops"}`, tc.status)
+				http.Error(w, body, tc.status)
 			}))
 			defer srv.Close()
 
@@ -310,6 +326,106 @@ func TestChatCompletionStreamFailsOnPersistentMalformed(t *testing.T) {
 	}
 }
 
+// cutStreamServer sends the SSE head and one role-only chunk, then drops the
+// connection mid chunked body, as a reset upstream would.
+func cutStreamServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		event := "data: {\"id\":\"c1\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\"}}]}\n\n"
+		_, _ = fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\nTransfer-Encoding: chunked\r\n\r\n%x\r\n%s\r\n", len(event), event)
+		_ = buf.Flush()
+	}))
+}
+
+func TestChatCompletionStreamCutIsUnavailable(t *testing.T) {
+	srv := cutStreamServer(t)
+	defer srv.Close()
+
+	p := New("x", srv.URL)
+	stream, err := p.ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "m",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer stream.Close()
+
+	if _, err := stream.Next(); err != nil {
+		t.Fatalf("first chunk: %v", err)
+	}
+	_, err = stream.Next()
+	if errors.Is(err, io.EOF) || !errors.Is(err, ir.ErrProviderUnavailable) {
+		t.Errorf("err = %v, want ErrProviderUnavailable", err)
+	}
+}
+
+// A connection dropped before [DONE] must reach the router as a failure, so
+// stream failover moves to the next account instead of ending cleanly.
+func TestChatCompletionStreamCutFailsOver(t *testing.T) {
+	cut := cutStreamServer(t)
+	defer cut.Close()
+	spare := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data: {\"id\":\"c2\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ok\"},\"finish_reason\":\"stop\"}]}\n\n"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer spare.Close()
+
+	cfg := ir.Config{
+		Models: []ir.ModelMapping{{Alias: "m", Models: []ir.ModelRef{
+			{Provider: "cut", Model: "m"},
+			{Provider: "spare", Model: "m"},
+		}}},
+		Accounts: []ir.AccountConfig{
+			{Provider: "cut", ID: "cut-acc", DailyFree: 10, QuotaUnit: ir.QuotaRequests},
+			{Provider: "spare", ID: "spare-acc", DailyFree: 10, QuotaUnit: ir.QuotaRequests},
+		},
+	}
+	r, err := ir.NewRouter(cfg, []ir.Provider{New("cut", cut.URL), New("spare", spare.URL)},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithStreamFailover(ir.StreamFailoverBeforeContent))
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	stream, err := r.ChatCompletionStream(context.Background(), ir.ChatRequest{
+		Model:    "m",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	var text strings.Builder
+	for {
+		c, err := stream.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		for _, d := range c.Choices {
+			text.WriteString(d.Delta.Content)
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if text.String() != "ok" {
+		t.Errorf("text = %q, want the spare's reply", text.String())
+	}
+	if got := stream.Routing(); got.Provider != "spare" || got.Attempts != 2 {
+		t.Errorf("routing = %+v, want spare after 2 attempts", got)
+	}
+}
+
 func TestChatCompletionStreamSkipsCommentsAndEmpty(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// SSE keep-alive comments and blank lines must be ignored.
@@ -356,3 +472,391 @@ func TestChatCompletionStreamErrorStatus(t *testing.T) {
 		t.Errorf("err = %v, want ErrRateLimited", err)
 	}
 }
+
+func TestChatCompletionTools(t *testing.T) {
+	var gotBody map[string]any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		_, _ = w.Write([]byte(`{
+			"id": "resp-1",
+			"model": "m",
+			"choices": [{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[
+				{"id":"call_9","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Oslo\"}"}}
+			]},"finish_reason":"tool_calls"}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}
+		}`))
+	}))
+	defer srv.Close()
+
+	p := New("openai", srv.URL)
+	if !p.SupportsTools() {
+		t.Fatal("openaicompat must advertise tool support")
+	}
+	resp, err := p.ChatCompletion(context.Background(), ir.ProviderRequest{
+		Auth:  ir.Auth{APIKey: "k"},
+		Model: "m",
+		Messages: []ir.Message{
+			{Role: "user", Content: "weather?"},
+			{Role: "assistant", ToolCalls: []ir.ToolCall{{ID: "call_1", Type: "function",
+				Function: ir.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}}}},
+			{Role: "tool", ToolCallID: "call_1", Content: `{"temp":21}`},
+		},
+		Tools: []ir.Tool{{Type: "function", Function: ir.ToolFunction{
+			Name:       "get_weather",
+			Parameters: json.RawMessage(`{"type":"object"}`),
+		}}},
+		ToolChoice: &ir.ToolChoice{Function: "get_weather"},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+
+	tools, _ := gotBody["tools"].([]any)
+	if len(tools) != 1 {
+		t.Fatalf("tools = %v", gotBody["tools"])
+	}
+	fn := tools[0].(map[string]any)["function"].(map[string]any)
+	if fn["name"] != "get_weather" || fn["parameters"].(map[string]any)["type"] != "object" {
+		t.Errorf("tool function = %v", fn)
+	}
+	choice, _ := gotBody["tool_choice"].(map[string]any)
+	if choice["type"] != "function" || choice["function"].(map[string]any)["name"] != "get_weather" {
+		t.Errorf("tool_choice = %v", gotBody["tool_choice"])
+	}
+
+	msgs := gotBody["messages"].([]any)
+	assistant := msgs[1].(map[string]any)
+	calls := assistant["tool_calls"].([]any)
+	if len(calls) != 1 || calls[0].(map[string]any)["id"] != "call_1" {
+		t.Errorf("assistant tool_calls = %v", assistant["tool_calls"])
+	}
+	if _, ok := calls[0].(map[string]any)["index"]; ok {
+		t.Error("request tool_calls must not carry a stream index")
+	}
+	toolMsg := msgs[2].(map[string]any)
+	if toolMsg["role"] != "tool" || toolMsg["tool_call_id"] != "call_1" {
+		t.Errorf("tool message = %v", toolMsg)
+	}
+
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_9" ||
+		resp.ToolCalls[0].Function.Arguments != `{"city":"Oslo"}` {
+		t.Errorf("resp.ToolCalls = %+v", resp.ToolCalls)
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("finish = %q", resp.FinishReason)
+	}
+}
+
+func TestBuildToolChoiceModes(t *testing.T) {
+	if got := buildToolChoice(nil); got != nil {
+		t.Errorf("nil choice = %v", got)
+	}
+	if got := buildToolChoice(&ir.ToolChoice{Mode: ir.ToolChoiceNone}); got != "none" {
+		t.Errorf("none = %v", got)
+	}
+	if got := buildToolChoice(&ir.ToolChoice{Mode: ir.ToolChoiceRequired}); got != "required" {
+		t.Errorf("required = %v", got)
+	}
+}
+
+func TestChatCompletionStreamToolCallDeltas(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data: {\"id\":\"c1\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"get_weather\",\"arguments\":\"\"}}]}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"id\":\"c1\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"{\\\"city\\\":\"}}]}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"id\":\"c1\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"\\\"Rome\\\"}\"}}]},\"finish_reason\":\"tool_calls\"}]}\n\n"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	p := New("x", srv.URL)
+	stream, err := p.ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "m",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer stream.Close()
+
+	var args, id, name, finish string
+	for {
+		c, err := stream.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		for _, d := range c.Choices[0].Delta.ToolCalls {
+			if d.Index != 0 {
+				t.Errorf("index = %d", d.Index)
+			}
+			if d.ID != "" {
+				id = d.ID
+			}
+			if d.Function.Name != "" {
+				name = d.Function.Name
+			}
+			args += d.Function.Arguments
+		}
+		if c.Choices[0].FinishReason != "" {
+			finish = c.Choices[0].FinishReason
+		}
+	}
+
+	if id != "call_1" || name != "get_weather" || args != `{"city":"Rome"}` {
+		t.Errorf("assembled call id=%q name=%q args=%q", id, name, args)
+	}
+	if finish != "tool_calls" {
+		t.Errorf("finish = %q", finish)
+	}
+}
+
+func TestBuildRequestResponseFormat(t *testing.T) {
+	p := New("test", "http://x")
+	req, err := p.buildRequest(ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+		ResponseFormat: &ir.ResponseFormat{
+			Type:   ir.ResponseFormatJSONSchema,
+			Schema: json.RawMessage(`{"type":"object"}`),
+			Strict: true,
+		},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"response_format":{"type":"json_schema","json_schema":{"name":"response","schema":{"type":"object"},"strict":true}}`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("body = %s, want it to contain %s", body, want)
+	}
+
+	if got := buildResponseFormat(&ir.ResponseFormat{Type: ir.ResponseFormatJSON}); got == nil || got.Type != "json_object" || got.JSONSchema != nil {
+		t.Errorf("json_object = %+v", got)
+	}
+	if got := buildResponseFormat(&ir.ResponseFormat{Type: ir.ResponseFormatText}); got != nil {
+		t.Errorf("text should be omitted, got %+v", got)
+	}
+}
+
+func TestBuildRequestSamplingParams(t *testing.T) {
+	p := New("test", "http://x")
+	n, seed, penalty := 2, 7, 0.5
+	in := ir.ProviderRequest{
+		Messages:        []ir.Message{{Role: "user", Content: "hi"}},
+		N:               &n,
+		Seed:            &seed,
+		PresencePenalty: &penalty,
+	}
+
+	req, err := p.buildRequest(in, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"seed":7`, `"presence_penalty":0.5`, `"n":2`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("body = %s, want it to contain %s", body, want)
+		}
+	}
+	if strings.Contains(string(body), "frequency_penalty") {
+		t.Errorf("body = %s, want unset frequency_penalty omitted", body)
+	}
+
+	if req, _ := p.buildRequest(in, true); req.N != nil {
+		t.Errorf("stream n = %d, want unset", *req.N)
+	}
+}
+
+func TestSupportsMultimodalModel(t *testing.T) {
+	p := New("test", "http://x")
+	if p.SupportsMultimodal() || p.SupportsMultimodalModel("gpt-4o") {
+		t.Error("without WithMultimodalModels every model should be text-only")
+	}
+	p = New("test", "http://x", WithMultimodalModels("gpt-4o"))
+	if !p.SupportsMultimodal() || !p.SupportsMultimodalModel("gpt-4o") || p.SupportsMultimodalModel("gpt-3.5-turbo") {
+		t.Error("only gpt-4o should accept media")
+	}
+}
+
+func TestBuildRequestContentParts(t *testing.T) {
+	p := New("test", "http://x")
+	req, err := p.buildRequest(ir.ProviderRequest{
+		Messages: []ir.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Parts: []ir.Part{
+				{Type: ir.PartText, Text: "what is this?"},
+				{Type: ir.PartImage, MIMEType: "image/png", Data: []byte{1, 2, 3}},
+				{Type: ir.PartAudio, MIMEType: "audio/mpeg", Data: []byte{4, 5, 6}},
+			}},
+		},
+		HasMedia: true,
+	}, false)
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+	body, err := json.Marshal(req.Messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"role":"system","content":"be brief"},{"role":"user","content":[` +
+		`{"type":"text","text":"what is this?"},` +
+		`{"type":"image_url","image_url":{"url":"data:image/png;base64,AQID"}},` +
+		`{"type":"input_audio","input_audio":{"data":"BAUG","format":"mp3"}}]}]`
+	if string(body) != want {
+		t.Errorf("messages =\n%s\nwant\n%s", body, want)
+	}
+
+	_, err = p.buildRequest(ir.ProviderRequest{
+		Messages: []ir.Message{{Role: "user", Parts: []ir.Part{{Type: ir.PartVideo, MIMEType: "video/mp4", Data: []byte{1}}}}},
+	}, false)
+	if !errors.Is(err, ir.ErrInvalidRequest) {
+		t.Errorf("video: err = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestChatCompletionMediaUsageBreakdown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"x","model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"a cat"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":900,"completion_tokens":5,"total_tokens":905,
+				"prompt_tokens_details":{"cached_tokens":100,"audio_tokens":200,"image_tokens":600}}}`))
+	}))
+	defer srv.Close()
+
+	p := New("openai", srv.URL, WithMultimodalModels("gpt-4o"))
+	resp, err := p.ChatCompletion(context.Background(), ir.ProviderRequest{
+		Model:    "gpt-4o",
+		Messages: []ir.Message{{Role: "user", Parts: []ir.Part{{Type: ir.PartImage, MIMEType: "image/jpeg", Data: []byte{1}}}}},
+		HasMedia: true,
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if resp.Content != "a cat" || resp.Usage.CachedTokens != 100 {
+		t.Errorf("resp = %+v", resp)
+	}
+	want := ir.InputTokenBreakdown{Text: 100, Audio: 200, Image: 600}
+	if b := resp.Usage.InputBreakdown; b == nil || *b != want {
+		t.Errorf("breakdown = %+v, want %+v", b, want)
+	}
+}
+
+func TestChatCompletionRateLimitHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ratelimit-remaining-requests-day", "0")
+		w.Header().Set("x-ratelimit-reset-requests-day", "3600")
+		w.Header().Set("x-ratelimit-remaining-tokens-minute", "1200")
+		w.Header().Set("x-ratelimit-reset-tokens-minute", "20.5")
+		_, _ = w.Write([]byte(`{"id":"x","model":"m","choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer srv.Close()
+
+	p := New("cerebras", srv.URL)
+	resp, err := p.ChatCompletion(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "m",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	rl := resp.RateLimits
+	if rl == nil || rl.RemainingRequests == nil || rl.RemainingTokens == nil {
+		t.Fatalf("RateLimits = %+v, want requests and tokens", rl)
+	}
+	if *rl.RemainingRequests != 0 || rl.ResetRequests != time.Hour {
+		t.Errorf("requests = %d reset %v, want 0 reset 1h", *rl.RemainingRequests, rl.ResetRequests)
+	}
+	if *rl.RemainingTokens != 1200 || rl.ResetTokens != 20500*time.Millisecond {
+		t.Errorf("tokens = %d reset %v, want 1200 reset 20.5s", *rl.RemainingTokens, rl.ResetTokens)
+	}
+}
+
+func TestChatCompletionStreamRateLimitHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("x-ratelimit-remaining-requests", "7")
+		w.Header().Set("x-ratelimit-reset-requests", "6m0s")
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	p := New("openai", srv.URL)
+	stream, err := p.ChatCompletionStream(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "m",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer stream.Close()
+
+	rep, ok := stream.(ir.RateLimitReporter)
+	if !ok {
+		t.Fatal("stream does not implement RateLimitReporter")
+	}
+	rl := rep.RateLimits()
+	if rl == nil || rl.RemainingRequests == nil || *rl.RemainingRequests != 7 || rl.ResetRequests != 6*time.Minute {
+		t.Errorf("RateLimits = %+v, want 7 requests resetting in 6m", rl)
+	}
+}
+
+func TestChatCompletion429RetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		http.Error(w, `{"error":"slow down"}`, http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	p := New("x", srv.URL)
+	_, err := p.ChatCompletion(context.Background(), ir.ProviderRequest{
+		Auth:     ir.Auth{APIKey: "k"},
+		Model:    "m",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+	})
+	var rle *ir.RateLimitError
+	if !errors.As(err, &rle) {
+		t.Fatalf("err = %v, want *RateLimitError", err)
+	}
+	if rle.RetryAfter != 7*time.Second {
+		t.Errorf("RetryAfter = %v, want 7s", rle.RetryAfter)
+	}
+}
+
+func TestChatCompletionReturnsEveryChoice(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"id":"c1","model":"m","choices":[
+			{"index":0,"message":{"role":"assistant","content":"one"},"finish_reason":"stop"},
+			{"index":1,"message":{"role":"assistant","content":"two"},"finish_reason":"length"}
+		],"usage":{"prompt_tokens":2,"completion_tokens":6,"total_tokens":8}}`)
+	}))
+	defer srv.Close()
+
+	n := 2
+	resp, err := New("x", srv.URL).ChatCompletion(context.Background(), ir.ProviderRequest{
+		Model:    "m",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+		N:        &n,
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if resp.Content != "one" || len(resp.Choices) != 2 {
+		t.Fatalf("resp = %+v", resp)
+	}
+	if c := resp.Choices[1]; c.Index != 1 || c.Message.Content != "two" || c.FinishReason != "length" {
+		t.Errorf("choice 1 = %+v", c)
+	}
+}
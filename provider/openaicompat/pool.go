@@ -2,6 +2,7 @@ package openaicompat
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/ineyio/inferrouter"
 )
@@ -27,15 +28,27 @@ import (
 // the same provider name are a configuration error.
 //
 // opts apply to every provider in the pool (e.g. a shared WithHTTPClient
-// with a generous timeout for slow gateways).
+// with a generous timeout for slow gateways). Models whose account
+// model_info lists a media input type are passed to WithMultimodalModels,
+// and models it marks embedding: true to WithEmbeddingModels.
 func FromAccounts(accounts []inferrouter.AccountConfig, opts ...Option) ([]inferrouter.Provider, error) {
-	urls := make(map[string]string) // provider name → base URL
-	var order []string              // deterministic provider order
+	urls := make(map[string]string)    // provider name → base URL
+	media := make(map[string][]string) // provider name → multimodal models
+	embed := make(map[string][]string) // provider name → embedding models
+	var order []string                 // deterministic provider order
 
 	for _, acc := range accounts {
 		if acc.BaseURL == "" {
 			continue
 		}
+		for model, info := range acc.ModelInfo {
+			if acceptsMedia(info) && !slices.Contains(media[acc.Provider], model) {
+				media[acc.Provider] = append(media[acc.Provider], model)
+			}
+			if info.Embedding && !slices.Contains(embed[acc.Provider], model) {
+				embed[acc.Provider] = append(embed[acc.Provider], model)
+			}
+		}
 		if existing, ok := urls[acc.Provider]; ok {
 			if existing != acc.BaseURL {
 				return nil, fmt.Errorf("inferrouter: provider %q has conflicting base URLs: %q and %q",
@@ -49,7 +62,26 @@ func FromAccounts(accounts []inferrouter.AccountConfig, opts ...Option) ([]infer
 
 	providers := make([]inferrouter.Provider, 0, len(order))
 	for _, name := range order {
-		providers = append(providers, New(name, urls[name], opts...))
+		popts := slices.Clip(opts)
+		if models := media[name]; len(models) > 0 {
+			slices.Sort(models)
+			popts = append(popts, WithMultimodalModels(models...))
+		}
+		if models := embed[name]; len(models) > 0 {
+			slices.Sort(models)
+			popts = append(popts, WithEmbeddingModels(models...))
+		}
+		providers = append(providers, New(name, urls[name], popts...))
 	}
 	return providers, nil
 }
+
+// acceptsMedia reports whether info lists an input type besides text.
+func acceptsMedia(info inferrouter.ModelInfo) bool {
+	for _, t := range info.Input {
+		if t != inferrouter.PartText {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,17 @@
 package openaicompat
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/quota"
 )
 
 func TestFromAccountsBuildsOneProviderPerName(t *testing.T) {
@@ -81,3 +88,82 @@ func TestFromAccountsTrimsTrailingSlashViaNew(t *testing.T) {
 		t.Errorf("baseURL = %q, want trailing slash trimmed", p.baseURL)
 	}
 }
+
+func TestFromAccountsMultimodalModelsFromModelInfo(t *testing.T) {
+	accounts := []inferrouter.AccountConfig{
+		{Provider: "vllm", ID: "v-1", BaseURL: "http://vllm:8000/v1", ModelInfo: map[string]inferrouter.ModelInfo{
+			"qwen2-vl":  {Input: []inferrouter.PartType{inferrouter.PartText, inferrouter.PartImage}},
+			"llama-3.1": {Input: []inferrouter.PartType{inferrouter.PartText}},
+		}},
+		{Provider: "textgate", ID: "t-1", BaseURL: "http://text/v1"},
+	}
+	providers, err := FromAccounts(accounts)
+	if err != nil {
+		t.Fatalf("FromAccounts: %v", err)
+	}
+
+	vllm := providers[0].(*Provider)
+	if !vllm.SupportsMultimodal() || !vllm.SupportsMultimodalModel("qwen2-vl") || vllm.SupportsMultimodalModel("llama-3.1") {
+		t.Errorf("vllm multimodal models = %v, want [qwen2-vl]", vllm.multimodal)
+	}
+	if providers[1].SupportsMultimodal() {
+		t.Error("textgate has no media models and should be text-only")
+	}
+}
+
+// An embedding model declared in YAML model_info reaches /embeddings through
+// a router built from FromAccounts, as the gateway builds it.
+func TestFromAccountsEmbeddingConfigRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		_, _ = io.WriteString(w, `{"data":[{"index":0,"embedding":[0.5]}],"usage":{"prompt_tokens":1,"total_tokens":1}}`)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+models:
+  - alias: bge
+    models:
+      - {provider: tei, model: BAAI/bge-large-en-v1.5}
+accounts:
+  - provider: tei
+    id: tei-1
+    base_url: ` + srv.URL + `
+    quota_unit: requests
+    daily_free: 10
+    model_info:
+      BAAI/bge-large-en-v1.5: {embedding: true}
+      llama-3.1: {context_window: 8192}
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := inferrouter.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	providers, err := FromAccounts(cfg.Accounts)
+	if err != nil {
+		t.Fatalf("FromAccounts: %v", err)
+	}
+	tei := providers[0].(*Provider)
+	if !tei.SupportsEmbeddingModel("BAAI/bge-large-en-v1.5") || tei.SupportsEmbeddingModel("llama-3.1") {
+		t.Errorf("embedding models = %v, want [BAAI/bge-large-en-v1.5]", tei.embedModels)
+	}
+
+	router, err := inferrouter.NewRouter(cfg, providers, inferrouter.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	resp, err := router.Embed(context.Background(), inferrouter.EmbedRequest{Model: "bge", Inputs: []string{"a"}})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(resp.Embeddings) != 1 || resp.Embeddings[0][0] != 0.5 {
+		t.Errorf("embeddings = %v", resp.Embeddings)
+	}
+}
@@ -1,6 +1,9 @@
 package inferrouter
 
-import "context"
+import (
+	"context"
+	"math"
+)
 
 // QuotaStore manages per-account quota reservations.
 type QuotaStore interface {
@@ -39,3 +42,34 @@ const (
 	QuotaRequests QuotaUnit = "requests"
 	QuotaDollars  QuotaUnit = "dollars"
 )
+
+// NanodollarsPerDollar is the fixed-point scale of QuotaDollars amounts.
+// QuotaStore implementations count int64s, so a dollar quota is held in
+// nanodollars: exact for per-token prices down to $0.001 per million tokens,
+// and good for budgets up to about $9 billion.
+const NanodollarsPerDollar = 1_000_000_000
+
+// ToNanodollars converts dollars to QuotaDollars units, rounding to the
+// nearest nanodollar.
+func ToNanodollars(dollars float64) int64 {
+	return int64(math.Round(dollars * NanodollarsPerDollar))
+}
+
+// FromNanodollars converts QuotaDollars units back to dollars.
+func FromNanodollars(n int64) float64 {
+	return float64(n) / NanodollarsPerDollar
+}
+
+// quotaAmount expresses the size of an attempt in the account's quota unit:
+// one request, its tokens, or its dollar cost in nanodollars. Used for the
+// reservation (estimates) and the commit (actual usage) alike.
+func quotaAmount(unit QuotaUnit, tokens int64, dollars float64) int64 {
+	switch unit {
+	case QuotaRequests:
+		return 1
+	case QuotaDollars:
+		return ToNanodollars(dollars)
+	default:
+		return tokens
+	}
+}
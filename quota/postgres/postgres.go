@@ -1,6 +1,8 @@
-// Package postgres provides a PostgreSQL-backed QuotaStore for inferrouter.
+// Package postgres provides a PostgreSQL-backed QuotaStore and SpendStore for
+// inferrouter.
 //
-// Quota state is stored in PostgreSQL tables with transactional Reserve/Commit/Rollback.
+// Quota state is stored in PostgreSQL tables with transactional Reserve/Commit/Rollback,
+// daily spend in one upserted row per account and day.
 // This makes it safe for multi-instance deployments and provides durability across restarts.
 package postgres
 
@@ -65,7 +67,13 @@ func (s *Store) EnsureSchema(ctx context.Context) error {
 			key TEXT PRIMARY KEY,
 			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
 		);
-	`, s.quotasTable(), s.idempotencyTable())
+		CREATE TABLE IF NOT EXISTS %s (
+			account_id TEXT NOT NULL,
+			day DATE NOT NULL,
+			dollars DOUBLE PRECISION NOT NULL DEFAULT 0,
+			PRIMARY KEY (account_id, day)
+		);
+	`, s.quotasTable(), s.idempotencyTable(), s.spendTable())
 	_, err := s.pool.Exec(ctx, q)
 	if err != nil {
 		return fmt.Errorf("inferrouter/postgres: ensure schema: %w", err)
@@ -45,7 +45,7 @@ func newTestStore(t *testing.T, pool *pgxpool.Pool) *quotapg.Store {
 		t.Fatalf("ensure schema: %v", err)
 	}
 	t.Cleanup(func() {
-		pool.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %squotas, %sidempotency", prefix, prefix))
+		pool.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %[1]squotas, %[1]sidempotency, %[1]sspend", prefix))
 	})
 	return s
 }
@@ -289,7 +289,7 @@ func TestTablePrefixIsolation(t *testing.T) {
 		t.Fatalf("ensure schema s2: %v", err)
 	}
 	t.Cleanup(func() {
-		pool.Exec(ctx, "DROP TABLE IF EXISTS test_iso1_quotas, test_iso1_idempotency, test_iso2_quotas, test_iso2_idempotency")
+		pool.Exec(ctx, "DROP TABLE IF EXISTS test_iso1_quotas, test_iso1_idempotency, test_iso1_spend, test_iso2_quotas, test_iso2_idempotency, test_iso2_spend")
 	})
 
 	s1.SetQuota("acct1", 100, inferrouter.QuotaTokens)
@@ -330,3 +330,50 @@ func TestCleanupIdempotency(t *testing.T) {
 		t.Fatalf("expected 5 deleted, got %d", deleted)
 	}
 }
+
+func TestSpendRecordAndGet(t *testing.T) {
+	pool := newTestPool(t)
+	store := newTestStore(t, pool)
+	ctx := context.Background()
+
+	got, err := store.GetSpend(ctx, "acct1")
+	if err != nil || got != 0 {
+		t.Fatalf("fresh account: got %v, %v", got, err)
+	}
+
+	for _, d := range []float64{0.25, 0.5} {
+		if err := store.RecordSpend(ctx, "acct1", d); err != nil {
+			t.Fatalf("record spend: %v", err)
+		}
+	}
+	got, err = store.GetSpend(ctx, "acct1")
+	if err != nil {
+		t.Fatalf("get spend: %v", err)
+	}
+	if got < 0.7499 || got > 0.7501 {
+		t.Fatalf("expected 0.75, got %v", got)
+	}
+}
+
+func TestConcurrentRecordSpend(t *testing.T) {
+	pool := newTestPool(t)
+	store := newTestStore(t, pool)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.RecordSpend(ctx, "acct1", 0.5); err != nil {
+				t.Errorf("record spend: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, _ := store.GetSpend(ctx, "acct1")
+	if got != 10 {
+		t.Fatalf("expected 10, got %v", got)
+	}
+}
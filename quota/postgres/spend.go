@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/ineyio/inferrouter"
+)
+
+var _ inferrouter.SpendStore = (*Store)(nil)
+
+func (s *Store) spendTable() string { return s.tablePrefix + "spend" }
+
+// todayUTC is the spend row's day. A new day is a new row, so the daily reset
+// needs no update.
+func todayUTC() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// RecordSpend adds dollars to the account's spend for today (upsert).
+func (s *Store) RecordSpend(ctx context.Context, accountID string, dollars float64) error {
+	_, err := s.pool.Exec(ctx,
+		fmt.Sprintf(`INSERT INTO %[1]s (account_id, day, dollars) VALUES ($1, $2, $3)
+			ON CONFLICT (account_id, day) DO UPDATE SET dollars = %[1]s.dollars + EXCLUDED.dollars`,
+			s.spendTable()),
+		accountID, todayUTC(), dollars,
+	)
+	if err != nil {
+		return fmt.Errorf("inferrouter/postgres: record spend: %w", err)
+	}
+	return nil
+}
+
+// GetSpend returns the account's spend for today.
+func (s *Store) GetSpend(ctx context.Context, accountID string) (float64, error) {
+	var dollars float64
+	err := s.pool.QueryRow(ctx,
+		fmt.Sprintf(`SELECT dollars FROM %s WHERE account_id = $1 AND day = $2`, s.spendTable()),
+		accountID, todayUTC(),
+	).Scan(&dollars)
+	if err == pgx.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("inferrouter/postgres: get spend: %w", err)
+	}
+	return dollars, nil
+}
+
+// CleanupSpend removes spend rows for days older than olderThan. Only today's
+// row is ever read; older ones are kept for inspection until cleaned up.
+func (s *Store) CleanupSpend(ctx context.Context, olderThan time.Duration) (int64, error) {
+	cutoff := todayUTC().Add(-olderThan)
+	tag, err := s.pool.Exec(ctx,
+		fmt.Sprintf(`DELETE FROM %s WHERE day < $1`, s.spendTable()),
+		cutoff,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("inferrouter/postgres: cleanup spend: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,293 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/ineyio/inferrouter"
+)
+
+// RateLimiter is a Redis-backed inferrouter.RateLimitStore. Every replica
+// that points at the same Redis shares one set of sliding windows, so eight
+// gateways behind a load balancer together allow the configured RPM, not
+// eight times it.
+//
+// The windows follow inferrouter.RateLimiter: a request is recorded only if
+// every request window (RPM/RPH/RPD) and token window (TPM/TPD) permits it.
+// A request entry expires exactly one window after it was recorded. Tokens
+// are counted per bucket, one second for TPM and one minute for TPD, so a
+// token charge leaves its window up to one bucket early. Each check-and-record
+// is one Lua script timed by the Redis server's clock, so concurrent replicas
+// cannot both take the last slot and clock skew between them does not matter.
+//
+// Limits themselves are not stored in Redis: each replica applies its own
+// config, as the router does at construction.
+type RateLimiter struct {
+	client    goredis.Cmdable
+	keyPrefix string
+
+	mu              sync.RWMutex
+	modelLimits     map[string]inferrouter.Limits // key: "accountID:model"
+	accountDefaults map[string]inferrouter.Limits // key: accountID
+}
+
+var _ inferrouter.RateLimitStore = (*RateLimiter)(nil)
+
+// RateLimiterOption configures RateLimiter.
+type RateLimiterOption func(*RateLimiter)
+
+// WithRateLimitKeyPrefix sets the Redis key prefix (default "inferrouter:ratelimit:").
+func WithRateLimitKeyPrefix(prefix string) RateLimiterOption {
+	return func(rl *RateLimiter) { rl.keyPrefix = prefix }
+}
+
+// NewRateLimiter creates a Redis-backed rate limiter.
+// The client must be a connected *goredis.Client or *goredis.ClusterClient,
+// on Redis 4.0 or later: the scripts read the server clock before they write
+// and set several hash fields in one HSET.
+func NewRateLimiter(client goredis.Cmdable, opts ...RateLimiterOption) *RateLimiter {
+	rl := &RateLimiter{
+		client:          client,
+		keyPrefix:       "inferrouter:ratelimit:",
+		modelLimits:     make(map[string]inferrouter.Limits),
+		accountDefaults: make(map[string]inferrouter.Limits),
+	}
+	for _, opt := range opts {
+		opt(rl)
+	}
+	return rl
+}
+
+// keys returns the request window, token window and provider block keys for
+// the pair. The hash tag keeps them all in one Cluster slot, as a script
+// touching several keys requires.
+func (rl *RateLimiter) keys(accountID, model string) (requests, tpm, tpd, blocked string) {
+	base := rl.keyPrefix + "{" + accountID + ":" + model + "}:"
+	return base + "req", base + "tpm", base + "tpd", base + "block"
+}
+
+// SetModelLimits configures rate limits for a specific (account, model) pair.
+func (rl *RateLimiter) SetModelLimits(accountID, model string, limits inferrouter.Limits) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.modelLimits[accountID+":"+model] = limits
+}
+
+// SetAccountDefault configures fallback rate limits for models without explicit limits.
+func (rl *RateLimiter) SetAccountDefault(accountID string, limits inferrouter.Limits) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.accountDefaults[accountID] = limits
+}
+
+// LimitsFor returns the model-specific limits if configured, else the account defaults.
+func (rl *RateLimiter) LimitsFor(accountID, model string) inferrouter.Limits {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	if l, ok := rl.modelLimits[accountID+":"+model]; ok {
+		return l
+	}
+	return rl.accountDefaults[accountID]
+}
+
+// tokenWindowLua defines the token window helpers shared by acquireScript and
+// adjustScript. A token window is a hash of per-bucket token sums plus two
+// bookkeeping fields: "sum", the running total of the live buckets, and
+// "head", the oldest bucket not yet dropped. Reading a window drops the
+// buckets that fell out of it and subtracts them from the total, so each
+// bucket is visited once however much traffic the window holds.
+//
+// All timestamps come from redis.call("TIME"): replicas with skewed clocks
+// still share one window. A script may only write after TIME under effects
+// replication, the default since Redis 5; redis.replicate_commands() turns it
+// on for Redis 4 and is a no-op after.
+const tokenWindowLua = `
+redis.replicate_commands()
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local minute = 60000
+local hour = 3600000
+local day = 86400000
+
+-- TPM counts tokens in one-second buckets, TPD in one-minute buckets.
+local tpm_bucket = 1000
+local tpd_bucket = minute
+
+local function window_sum(key, bucket_ms, window_ms)
+    local head = tonumber(redis.call("HGET", key, "head"))
+    if head == nil then
+        return 0
+    end
+    local sum = tonumber(redis.call("HGET", key, "sum")) or 0
+    local oldest = math.floor(now / bucket_ms) - window_ms / bucket_ms + 1
+    if head >= oldest then
+        return sum
+    end
+    -- The key expires a window after its last write, so at most one
+    -- window's worth of buckets is left to drop.
+    while head < oldest do
+        local n = tonumber(redis.call("HGET", key, head))
+        if n then
+            sum = sum - n
+            redis.call("HDEL", key, head)
+        end
+        head = head + 1
+    end
+    redis.call("HSET", key, "head", head, "sum", sum)
+    return sum
+end
+
+local function window_add(key, bucket_ms, window_ms, tokens)
+    local bucket = math.floor(now / bucket_ms)
+    redis.call("HINCRBY", key, bucket, tokens)
+    redis.call("HINCRBY", key, "sum", tokens)
+    redis.call("HSETNX", key, "head", bucket)
+    redis.call("PEXPIRE", key, window_ms)
+end
+`
+
+// acquireScript atomically checks and records one request.
+// KEYS[1] = request window sorted set (score = ms timestamp)
+// KEYS[2] = TPM token window hash
+// KEYS[3] = TPD token window hash
+// KEYS[4] = provider block key
+// ARGV[1] = member id
+// ARGV[2] = estimated tokens
+// ARGV[3..7] = rpm, rph, rpd, tpm, tpd (0 = unlimited)
+//
+// Returns:
+//
+//	0 = allowed and recorded
+//	1 = a request window is full, or the provider reported the budget gone
+//	2 = the tokens do not fit a token window
+var acquireScript = goredis.NewScript(tokenWindowLua + `
+local req_key = KEYS[1]
+local tpm_key = KEYS[2]
+local tpd_key = KEYS[3]
+local block_key = KEYS[4]
+local member = ARGV[1]
+local tokens = tonumber(ARGV[2])
+local rpm = tonumber(ARGV[3])
+local rph = tonumber(ARGV[4])
+local rpd = tonumber(ARGV[5])
+local tpm = tonumber(ARGV[6])
+local tpd = tonumber(ARGV[7])
+
+-- The provider's own word beats the local windows.
+if redis.call("EXISTS", block_key) == 1 then
+    return 1
+end
+
+-- Request windows. An entry recorded exactly one window ago has expired.
+local req_window = minute
+if rph > 0 then req_window = hour end
+if rpd > 0 then req_window = day end
+local limit_requests = rpm > 0 or rph > 0 or rpd > 0
+if limit_requests then
+    redis.call("ZREMRANGEBYSCORE", req_key, "-inf", now - req_window)
+    if rpd > 0 and redis.call("ZCARD", req_key) >= rpd then
+        return 1
+    end
+    if rph > 0 and redis.call("ZCOUNT", req_key, "(" .. (now - hour), "+inf") >= rph then
+        return 1
+    end
+    if rpm > 0 and redis.call("ZCOUNT", req_key, "(" .. (now - minute), "+inf") >= rpm then
+        return 1
+    end
+end
+
+-- Token windows. Corrections from AdjustTokens land in the same buckets.
+if tpd > 0 and window_sum(tpd_key, tpd_bucket, day) + tokens > tpd then
+    return 2
+end
+if tpm > 0 and window_sum(tpm_key, tpm_bucket, minute) + tokens > tpm then
+    return 2
+end
+
+if limit_requests then
+    redis.call("ZADD", req_key, now, member)
+    redis.call("PEXPIRE", req_key, req_window)
+end
+if tokens > 0 then
+    if tpd > 0 then window_add(tpd_key, tpd_bucket, day, tokens) end
+    if tpm > 0 then window_add(tpm_key, tpm_bucket, minute, tokens) end
+end
+return 0
+`)
+
+// Acquire records a request expected to use tokens tokens if every window
+// permits it. Returns inferrouter.ErrRPMExceeded or ErrTPMExceeded when one
+// does not, and a wrapped Redis error when the check could not be made.
+func (rl *RateLimiter) Acquire(ctx context.Context, accountID, model string, tokens int64) error {
+	limits := rl.LimitsFor(accountID, model)
+	reqKey, tpmKey, tpdKey, blockKey := rl.keys(accountID, model)
+
+	result, err := acquireScript.Run(ctx, rl.client,
+		[]string{reqKey, tpmKey, tpdKey, blockKey},
+		uuid.New().String(), tokens,
+		limits.RPM, limits.RPH, limits.RPD, limits.TPM, limits.TPD,
+	).Int64()
+	if err != nil {
+		return fmt.Errorf("inferrouter/redis: rate limit acquire: %w", err)
+	}
+
+	switch result {
+	case 0:
+		return nil
+	case 1:
+		return inferrouter.ErrRPMExceeded
+	case 2:
+		return inferrouter.ErrTPMExceeded
+	default:
+		return fmt.Errorf("inferrouter/redis: unexpected acquire result: %d", result)
+	}
+}
+
+// adjustScript adds a token correction to the current bucket of each
+// limited token window.
+// KEYS[1] = TPM token window hash
+// KEYS[2] = TPD token window hash
+// ARGV[1] = delta tokens
+// ARGV[2..3] = tpm, tpd (0 = unlimited)
+var adjustScript = goredis.NewScript(tokenWindowLua + `
+local delta = tonumber(ARGV[1])
+if tonumber(ARGV[3]) > 0 then window_add(KEYS[2], tpd_bucket, day, delta) end
+if tonumber(ARGV[2]) > 0 then window_add(KEYS[1], tpm_bucket, minute, delta) end
+return 0
+`)
+
+// AdjustTokens records a correction of delta tokens (actual minus estimated)
+// in the pair's token windows. No-op without token limits. Errors are
+// dropped: the attempt has already settled, and the charge expires with the
+// window anyway.
+func (rl *RateLimiter) AdjustTokens(ctx context.Context, accountID, model string, delta int64) {
+	limits := rl.LimitsFor(accountID, model)
+	if delta == 0 || (limits.TPM == 0 && limits.TPD == 0) {
+		return
+	}
+	_, tpmKey, tpdKey, _ := rl.keys(accountID, model)
+	_ = adjustScript.Run(ctx, rl.client, []string{tpmKey, tpdKey}, delta, limits.TPM, limits.TPD).Err()
+}
+
+// SyncFromProvider blocks the pair for every replica when the provider
+// reports its budget gone, and lifts the block when a report shows budget
+// left. Errors are dropped, as in AdjustTokens.
+func (rl *RateLimiter) SyncFromProvider(ctx context.Context, accountID, model string, info *inferrouter.RateLimitInfo) {
+	if info == nil {
+		return
+	}
+	_, _, _, blockKey := rl.keys(accountID, model)
+
+	wait, exhausted := info.Backoff()
+	switch {
+	case exhausted:
+		rl.client.Set(ctx, blockKey, "1", wait)
+	case info.RemainingRequests != nil || info.RemainingTokens != nil:
+		rl.client.Del(ctx, blockKey)
+	}
+}
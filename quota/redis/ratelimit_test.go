@@ -0,0 +1,156 @@
+//go:build integration
+
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/ineyio/inferrouter"
+	quotaredis "github.com/ineyio/inferrouter/quota/redis"
+)
+
+func newTestRateLimiter(t *testing.T, client *goredis.Client) *quotaredis.RateLimiter {
+	t.Helper()
+	prefix := "test:" + t.Name() + ":"
+	t.Cleanup(func() {
+		ctx := context.Background()
+		iter := client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			client.Del(ctx, iter.Val())
+		}
+	})
+	return quotaredis.NewRateLimiter(client, quotaredis.WithRateLimitKeyPrefix(prefix))
+}
+
+func TestRateLimiterRPMSharedAcrossReplicas(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	// Two limiters on the same keys stand in for two gateway replicas.
+	a := newTestRateLimiter(t, client)
+	b := quotaredis.NewRateLimiter(client, quotaredis.WithRateLimitKeyPrefix("test:"+t.Name()+":"))
+	for _, rl := range []*quotaredis.RateLimiter{a, b} {
+		rl.SetModelLimits("acct1", "m", inferrouter.Limits{RPM: 3})
+	}
+
+	if err := a.Acquire(ctx, "acct1", "m", 0); err != nil {
+		t.Fatalf("acquire 1: %v", err)
+	}
+	if err := b.Acquire(ctx, "acct1", "m", 0); err != nil {
+		t.Fatalf("acquire 2: %v", err)
+	}
+	if err := a.Acquire(ctx, "acct1", "m", 0); err != nil {
+		t.Fatalf("acquire 3: %v", err)
+	}
+	if err := b.Acquire(ctx, "acct1", "m", 0); !errors.Is(err, inferrouter.ErrRPMExceeded) {
+		t.Fatalf("expected ErrRPMExceeded on the other replica, got %v", err)
+	}
+
+	// Other models on the account have their own window.
+	if err := b.Acquire(ctx, "acct1", "other", 0); err != nil {
+		t.Fatalf("unlimited model: %v", err)
+	}
+}
+
+func TestRateLimiterConcurrentAcquire(t *testing.T) {
+	client := newTestClient(t)
+	rl := newTestRateLimiter(t, client)
+	ctx := context.Background()
+
+	rl.SetAccountDefault("acct1", inferrouter.Limits{RPM: 10})
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rl.Acquire(ctx, "acct1", "m", 0) == nil {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed.Load() != 10 {
+		t.Fatalf("expected exactly 10 allowed, got %d", allowed.Load())
+	}
+}
+
+func TestRateLimiterTPMAndAdjust(t *testing.T) {
+	client := newTestClient(t)
+	rl := newTestRateLimiter(t, client)
+	ctx := context.Background()
+
+	rl.SetModelLimits("acct1", "m", inferrouter.Limits{TPM: 100})
+
+	if err := rl.Acquire(ctx, "acct1", "m", 80); err != nil {
+		t.Fatalf("acquire 80: %v", err)
+	}
+	if err := rl.Acquire(ctx, "acct1", "m", 30); !errors.Is(err, inferrouter.ErrTPMExceeded) {
+		t.Fatalf("expected ErrTPMExceeded, got %v", err)
+	}
+
+	// The request used 20 tokens, not 80.
+	rl.AdjustTokens(ctx, "acct1", "m", -60)
+	if err := rl.Acquire(ctx, "acct1", "m", 30); err != nil {
+		t.Fatalf("acquire after correction: %v", err)
+	}
+}
+
+func TestRateLimiterTPDSharedAcrossReplicas(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	a := newTestRateLimiter(t, client)
+	b := quotaredis.NewRateLimiter(client, quotaredis.WithRateLimitKeyPrefix("test:"+t.Name()+":"))
+	for _, rl := range []*quotaredis.RateLimiter{a, b} {
+		rl.SetModelLimits("acct1", "m", inferrouter.Limits{TPM: 1000, TPD: 150})
+	}
+
+	if err := a.Acquire(ctx, "acct1", "m", 100); err != nil {
+		t.Fatalf("acquire 100: %v", err)
+	}
+	if err := b.Acquire(ctx, "acct1", "m", 100); !errors.Is(err, inferrouter.ErrTPMExceeded) {
+		t.Fatalf("expected ErrTPMExceeded from the day window, got %v", err)
+	}
+
+	// The correction reaches both windows on every replica.
+	b.AdjustTokens(ctx, "acct1", "m", -90)
+	if err := b.Acquire(ctx, "acct1", "m", 100); err != nil {
+		t.Fatalf("acquire after correction: %v", err)
+	}
+}
+
+func TestRateLimiterSyncFromProvider(t *testing.T) {
+	client := newTestClient(t)
+	rl := newTestRateLimiter(t, client)
+	ctx := context.Background()
+
+	zero, some := int64(0), int64(5)
+	rl.SyncFromProvider(ctx, "acct1", "m", &inferrouter.RateLimitInfo{
+		RemainingRequests: &zero,
+		ResetRequests:     200 * time.Millisecond,
+	})
+	if err := rl.Acquire(ctx, "acct1", "m", 0); !errors.Is(err, inferrouter.ErrRPMExceeded) {
+		t.Fatalf("expected blocked pair, got %v", err)
+	}
+
+	rl.SyncFromProvider(ctx, "acct1", "m", &inferrouter.RateLimitInfo{RemainingRequests: &some})
+	if err := rl.Acquire(ctx, "acct1", "m", 0); err != nil {
+		t.Fatalf("budget reported back: %v", err)
+	}
+
+	rl.SyncFromProvider(ctx, "acct1", "m", &inferrouter.RateLimitInfo{RetryAfter: 100 * time.Millisecond})
+	time.Sleep(150 * time.Millisecond)
+	if err := rl.Acquire(ctx, "acct1", "m", 0); err != nil {
+		t.Fatalf("block should have expired: %v", err)
+	}
+}
@@ -1,7 +1,11 @@
-// Package redis provides a Redis-backed QuotaStore for inferrouter.
+// Package redis provides a Redis-backed QuotaStore, SpendStore and
+// RateLimitStore for inferrouter.
 //
 // Quota state is stored in Redis hashes with atomic Lua scripts for
-// Reserve/Commit/Rollback. This makes it safe for multi-instance deployments.
+// Reserve/Commit/Rollback; daily spend in one float counter per account and
+// day. Rate limit windows are sorted sets, checked and updated by one Lua
+// script per request. This makes all three safe for multi-instance
+// deployments.
 package redis
 
 import (
@@ -294,3 +294,50 @@ func TestKeyPrefixIsolation(t *testing.T) {
 		t.Fatalf("s2 expected 200, got %d", r2)
 	}
 }
+
+func TestSpendRecordAndGet(t *testing.T) {
+	client := newTestClient(t)
+	store := newTestStore(t, client)
+	ctx := context.Background()
+
+	got, err := store.GetSpend(ctx, "acct1")
+	if err != nil || got != 0 {
+		t.Fatalf("fresh account: got %v, %v", got, err)
+	}
+
+	for _, d := range []float64{0.25, 0.5} {
+		if err := store.RecordSpend(ctx, "acct1", d); err != nil {
+			t.Fatalf("record spend: %v", err)
+		}
+	}
+	got, err = store.GetSpend(ctx, "acct1")
+	if err != nil {
+		t.Fatalf("get spend: %v", err)
+	}
+	if got < 0.7499 || got > 0.7501 {
+		t.Fatalf("expected 0.75, got %v", got)
+	}
+}
+
+func TestConcurrentRecordSpend(t *testing.T) {
+	client := newTestClient(t)
+	store := newTestStore(t, client)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.RecordSpend(ctx, "acct1", 0.5); err != nil {
+				t.Errorf("record spend: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, _ := store.GetSpend(ctx, "acct1")
+	if got != 10 {
+		t.Fatalf("expected 10, got %v", got)
+	}
+}
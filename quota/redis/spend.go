@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/ineyio/inferrouter"
+)
+
+var _ inferrouter.SpendStore = (*Store)(nil)
+
+// spendTTL keeps a day's spend key past midnight UTC, for late readers, and
+// then lets Redis drop it.
+const spendTTL = 48 * time.Hour
+
+// spendKey is the account's spend counter for the UTC day of now. A new day
+// is a new key, so the daily reset needs no script.
+func (s *Store) spendKey(accountID string, now time.Time) string {
+	return s.keyPrefix + "spend:" + accountID + ":" + now.UTC().Format("2006-01-02")
+}
+
+// RecordSpend adds dollars to the account's spend for today (INCRBYFLOAT).
+func (s *Store) RecordSpend(ctx context.Context, accountID string, dollars float64) error {
+	key := s.spendKey(accountID, time.Now())
+	_, err := s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.IncrByFloat(ctx, key, dollars)
+		pipe.Expire(ctx, key, spendTTL)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("inferrouter/redis: record spend: %w", err)
+	}
+	return nil
+}
+
+// GetSpend returns the account's spend for today.
+func (s *Store) GetSpend(ctx context.Context, accountID string) (float64, error) {
+	dollars, err := s.client.Get(ctx, s.spendKey(accountID, time.Now())).Float64()
+	if errors.Is(err, goredis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("inferrouter/redis: get spend: %w", err)
+	}
+	return dollars, nil
+}
@@ -1,6 +1,8 @@
 package inferrouter
 
 import (
+	"context"
+	"strings"
 	"sync"
 	"time"
 )
@@ -11,15 +13,62 @@ type Limits struct {
 	RPM int `yaml:"rpm"` // requests per minute
 	RPH int `yaml:"rph"` // requests per hour
 	RPD int `yaml:"rpd"` // requests per day
+
+	TPM int64 `yaml:"tpm"` // tokens per minute
+	TPD int64 `yaml:"tpd"` // tokens per day
+
+	// MaxConcurrency caps requests in flight at once for the account/model.
+	// Enforced by the router through its InflightTracker, not by RateLimiter.
+	MaxConcurrency int `yaml:"max_concurrency"`
 }
 
 // IsZero returns true if no limits are configured.
 func (l Limits) IsZero() bool {
-	return l.RPM == 0 && l.RPH == 0 && l.RPD == 0
+	return l.RPM == 0 && l.RPH == 0 && l.RPD == 0 &&
+		l.TPM == 0 && l.TPD == 0 && l.MaxConcurrency == 0
+}
+
+// RateLimitStore enforces per-(account, model) rate limits. The router calls
+// SetModelLimits/SetAccountDefault once per account at construction, Acquire
+// before every attempt, AdjustTokens when the attempt settles, and
+// SyncFromProvider with whatever the provider reported about its own limits.
+//
+// RateLimiter is the in-process implementation. It counts only the requests
+// of one process, so N replicas together allow N times the configured limits;
+// quota/redis provides an implementation whose windows are shared.
+type RateLimitStore interface {
+	// SetModelLimits configures limits for a specific (account, model) pair.
+	SetModelLimits(accountID, model string, limits Limits)
+
+	// SetAccountDefault configures fallback limits for models without
+	// explicit ones.
+	SetAccountDefault(accountID string, limits Limits)
+
+	// LimitsFor returns the limits in force for the (account, model) pair.
+	LimitsFor(accountID, model string) Limits
+
+	// Acquire records a request expected to use tokens tokens if every window
+	// permits it. It returns ErrRPMExceeded or ErrTPMExceeded when a window is
+	// full; any other error also makes the router skip the candidate.
+	Acquire(ctx context.Context, accountID, model string, tokens int64) error
+
+	// AdjustTokens corrects the tokens charged by Acquire by delta (actual
+	// minus estimated). Best effort: the attempt has already settled.
+	AdjustTokens(ctx context.Context, accountID, model string, delta int64)
+
+	// SyncFromProvider applies a provider's report of its own limits. Best
+	// effort, like AdjustTokens. A nil info is ignored.
+	SyncFromProvider(ctx context.Context, accountID, model string, info *RateLimitInfo)
 }
 
+var _ RateLimitStore = (*RateLimiter)(nil)
+
 // RateLimiter enforces per-(account, model) rate limits using sliding windows.
-// Thread-safe. Supports RPM, RPH, and RPD simultaneously.
+// Thread-safe. Supports RPM, RPH, RPD, TPM and TPD simultaneously.
+//
+// Token windows are charged up front with the request's estimate (see
+// Acquire) and corrected with the actual usage once it is known
+// (AdjustTokens), the same reserve-then-commit shape as QuotaStore.
 //
 // Lookup order: model-specific limits first, then account-level defaults.
 // This allows Cerebras-style configs where each model has independent limits,
@@ -28,12 +77,22 @@ type RateLimiter struct {
 	mu              sync.Mutex
 	windows         map[string]*multiWindow // key: "accountID:model"
 	accountDefaults map[string]Limits       // key: accountID
+	blocked         map[string]time.Time    // key: "accountID:model" → provider says empty until
 	now             func() time.Time
 }
 
 type multiWindow struct {
 	limits Limits
 	times  []time.Time
+	tokens []tokenEntry
+}
+
+// tokenEntry is a charge against the token windows. Corrections are separate
+// entries (negative when the estimate was too high) rather than edits of the
+// original, so a correction expires slightly after the charge it corrects.
+type tokenEntry struct {
+	at time.Time
+	n  int64
 }
 
 // NewRateLimiter creates a new RateLimiter.
@@ -41,6 +100,7 @@ func NewRateLimiter() *RateLimiter {
 	return &RateLimiter{
 		windows:         make(map[string]*multiWindow),
 		accountDefaults: make(map[string]Limits),
+		blocked:         make(map[string]time.Time),
 		now:             time.Now,
 	}
 }
@@ -74,31 +134,124 @@ func (rl *RateLimiter) SetLimit(accountID string, rpm int) {
 // Returns true and records the request if under all limits.
 // Returns false if any limit is exceeded.
 func (rl *RateLimiter) Allow(accountID, model string) bool {
+	return rl.Acquire(context.Background(), accountID, model, 0) == nil
+}
+
+// Acquire is Allow for a request expected to use tokens tokens: it checks the
+// request windows and the token windows, and records the request and its
+// tokens only if all of them permit it. It returns ErrRPMExceeded when a
+// request window is full (or the provider reported the budget gone, see
+// SyncFromProvider) and ErrTPMExceeded when the tokens do not fit.
+func (rl *RateLimiter) Acquire(_ context.Context, accountID, model string, tokens int64) error {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
 	key := accountID + ":" + model
-	w, ok := rl.windows[key]
-	if !ok {
-		// No model-specific limits — check account defaults.
-		defaults, hasDefault := rl.accountDefaults[accountID]
-		if !hasDefault || defaults.IsZero() {
-			return true // no limits configured
-		}
-		// Lazily create window from account defaults.
-		w = &multiWindow{
-			limits: defaults,
-			times:  make([]time.Time, 0, max(defaults.RPM, 16)),
+	now := rl.now()
+
+	// The provider's own word beats the local windows.
+	if until, ok := rl.blocked[key]; ok {
+		if now.Before(until) {
+			return ErrRPMExceeded
 		}
-		rl.windows[key] = w
+		delete(rl.blocked, key)
 	}
 
-	return w.allow(rl.now())
+	w := rl.window(key, accountID)
+	if w == nil {
+		return nil // no limits configured
+	}
+	return w.acquire(now, tokens)
+}
+
+// AdjustTokens corrects the tokens Acquire charged for a request once its
+// usage is known: delta is actual minus estimated, negative when the estimate
+// was too high or the request never ran. No-op without token limits.
+func (rl *RateLimiter) AdjustTokens(_ context.Context, accountID, model string, delta int64) {
+	if delta == 0 {
+		return
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	w := rl.windows[accountID+":"+model]
+	if w == nil || (w.limits.TPM == 0 && w.limits.TPD == 0) {
+		return
+	}
+	w.tokens = append(w.tokens, tokenEntry{at: rl.now(), n: delta})
+}
+
+// LimitsFor returns the limits in force for the (account, model) pair: the
+// model-specific ones if configured, else the account defaults.
+func (rl *RateLimiter) LimitsFor(accountID, model string) Limits {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if w, ok := rl.windows[accountID+":"+model]; ok {
+		return w.limits
+	}
+	return rl.accountDefaults[accountID]
+}
+
+// window returns the pair's window, lazily creating it from the account
+// defaults. Nil means no limits apply. Must be called with rl.mu held.
+func (rl *RateLimiter) window(key, accountID string) *multiWindow {
+	if w, ok := rl.windows[key]; ok {
+		return w
+	}
+	// No model-specific limits — check account defaults.
+	defaults, hasDefault := rl.accountDefaults[accountID]
+	if !hasDefault || defaults.IsZero() {
+		return nil
+	}
+	w := &multiWindow{
+		limits: defaults,
+		times:  make([]time.Time, 0, max(defaults.RPM, 16)),
+	}
+	rl.windows[key] = w
+	return w
 }
 
-// allow checks all time windows and records the request if permitted.
-// Must be called with rl.mu held.
-func (w *multiWindow) allow(now time.Time) bool {
+// SyncFromProvider applies the rate limit state a provider reported for the
+// (account, model) pair. When the provider says requests or tokens are down to
+// zero, or asks to retry after a delay, Allow refuses the pair until the
+// reported reset, so the router skips it without spending an attempt. A later
+// report with budget left lifts the block early. A nil info is ignored.
+func (rl *RateLimiter) SyncFromProvider(_ context.Context, accountID, model string, info *RateLimitInfo) {
+	if info == nil {
+		return
+	}
+	wait, exhausted := info.Backoff()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	key := accountID + ":" + model
+	switch {
+	case exhausted:
+		rl.blocked[key] = rl.now().Add(wait)
+	case info.RemainingRequests != nil || info.RemainingTokens != nil:
+		delete(rl.blocked, key)
+	}
+}
+
+// acquire checks all time windows and records the request and its tokens if
+// permitted. Must be called with rl.mu held.
+func (w *multiWindow) acquire(now time.Time, tokens int64) error {
+	if !w.requestsOK(now) {
+		return ErrRPMExceeded
+	}
+	if !w.tokensOK(now, tokens) {
+		return ErrTPMExceeded
+	}
+	w.times = append(w.times, now)
+	if tokens > 0 && (w.limits.TPM > 0 || w.limits.TPD > 0) {
+		w.tokens = append(w.tokens, tokenEntry{at: now, n: tokens})
+	}
+	return nil
+}
+
+// requestsOK prunes the request log and reports whether one more request
+// fits in every request window.
+func (w *multiWindow) requestsOK(now time.Time) bool {
 	// Prune entries older than the longest window (24h for RPD).
 	maxWindow := time.Minute
 	if w.limits.RPH > 0 {
@@ -150,7 +303,43 @@ func (w *multiWindow) allow(now time.Time) bool {
 		}
 	}
 
-	w.times = append(w.times, now)
+	return true
+}
+
+// tokensOK prunes the token log and reports whether tokens more fit in every
+// token window.
+func (w *multiWindow) tokensOK(now time.Time, tokens int64) bool {
+	if w.limits.TPM == 0 && w.limits.TPD == 0 {
+		return true
+	}
+
+	maxWindow := time.Minute
+	if w.limits.TPD > 0 {
+		maxWindow = 24 * time.Hour
+	}
+	cutoff := now.Add(-maxWindow)
+	minCutoff := now.Add(-time.Minute)
+
+	valid := w.tokens[:0]
+	var day, minute int64
+	for _, e := range w.tokens {
+		if !e.at.After(cutoff) {
+			continue
+		}
+		valid = append(valid, e)
+		day += e.n
+		if e.at.After(minCutoff) {
+			minute += e.n
+		}
+	}
+	w.tokens = valid
+
+	if w.limits.TPD > 0 && day+tokens > w.limits.TPD {
+		return false
+	}
+	if w.limits.TPM > 0 && minute+tokens > w.limits.TPM {
+		return false
+	}
 	return true
 }
 
@@ -164,6 +353,7 @@ func (rl *RateLimiter) Reset() {
 			times:  make([]time.Time, 0, max(w.limits.RPM, 16)),
 		}
 	}
+	rl.blocked = make(map[string]time.Time)
 }
 
 // ResetAccount clears state for all models under an account.
@@ -178,4 +368,9 @@ func (rl *RateLimiter) ResetAccount(accountID string) {
 			}
 		}
 	}
+	for key := range rl.blocked {
+		if strings.HasPrefix(key, accountID+":") {
+			delete(rl.blocked, key)
+		}
+	}
 }
@@ -0,0 +1,172 @@
+package inferrouter
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitInfo is a provider's own account of the rate limit budget left
+// after a call, parsed from its response headers. It is authoritative where
+// the local RateLimiter is an approximation: other clients of the same
+// organisation spend the same budget, and a restart empties the local windows.
+//
+// A nil field means the provider did not say.
+type RateLimitInfo struct {
+	// RemainingRequests and RemainingTokens are the tightest of the windows
+	// the provider reported (OpenAI's unsuffixed headers, or Cerebras-style
+	// -minute/-hour/-day variants).
+	RemainingRequests *int64
+	RemainingTokens   *int64
+
+	// ResetRequests and ResetTokens are how long until the window that
+	// produced the remaining count refills.
+	ResetRequests time.Duration
+	ResetTokens   time.Duration
+
+	// RetryAfter is the provider's explicit back-off (Retry-After), if any.
+	RetryAfter time.Duration
+}
+
+// defaultProviderBackoff is the wait assumed when a provider reports an empty
+// budget without saying when it refills.
+const defaultProviderBackoff = time.Minute
+
+// Backoff reports whether the provider said to stop sending — requests or
+// tokens down to zero, or an explicit Retry-After — and for how long. When
+// several apply the longest wait wins; an empty window without a reset time
+// counts as one minute.
+func (i *RateLimitInfo) Backoff() (time.Duration, bool) {
+	if i == nil {
+		return 0, false
+	}
+	exhausted := false
+	var wait time.Duration
+	if i.RemainingRequests != nil && *i.RemainingRequests <= 0 {
+		exhausted = true
+		wait = max(wait, resetOrDefault(i.ResetRequests))
+	}
+	if i.RemainingTokens != nil && *i.RemainingTokens <= 0 {
+		exhausted = true
+		wait = max(wait, resetOrDefault(i.ResetTokens))
+	}
+	if i.RetryAfter > 0 {
+		exhausted = true
+		wait = max(wait, i.RetryAfter)
+	}
+	return wait, exhausted
+}
+
+func resetOrDefault(reset time.Duration) time.Duration {
+	if reset > 0 {
+		return reset
+	}
+	return defaultProviderBackoff
+}
+
+// RateLimitReporter is an OPTIONAL capability interface for a ProviderStream
+// that can report the rate limit headers it was opened with. The router
+// discovers it via type assertion, the same way ProviderResponse.RateLimits
+// is used on the non-streaming path.
+type RateLimitReporter interface {
+	RateLimits() *RateLimitInfo
+}
+
+// rateLimitWindows are the header suffixes providers use for their windows:
+// OpenAI sends one unsuffixed set, Cerebras one per window.
+var rateLimitWindows = []string{"", "-minute", "-hour", "-day"}
+
+// ParseRateLimitHeaders extracts x-ratelimit-remaining-*/x-ratelimit-reset-*
+// and Retry-After from a provider response. It returns nil when none of them
+// is present, so providers can assign the result unconditionally.
+func ParseRateLimitHeaders(h http.Header) *RateLimitInfo {
+	var info RateLimitInfo
+	found := false
+
+	for _, kind := range []string{"requests", "tokens"} {
+		remaining, reset, ok := tightestWindow(h, kind)
+		if !ok {
+			continue
+		}
+		found = true
+		if kind == "requests" {
+			info.RemainingRequests, info.ResetRequests = &remaining, reset
+		} else {
+			info.RemainingTokens, info.ResetTokens = &remaining, reset
+		}
+	}
+
+	if d, ok := parseRetryAfter(h.Get("Retry-After"), time.Now()); ok {
+		info.RetryAfter = d
+		found = true
+	}
+
+	if !found {
+		return nil
+	}
+	return &info
+}
+
+// tightestWindow returns the smallest remaining count across the windows
+// reported for kind, with that window's reset. When several windows are
+// exhausted the longest reset wins: the budget is back only when all are.
+func tightestWindow(h http.Header, kind string) (remaining int64, reset time.Duration, ok bool) {
+	for _, suffix := range rateLimitWindows {
+		v := h.Get("X-Ratelimit-Remaining-" + kind + suffix)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			continue
+		}
+		r, _ := parseResetDuration(h.Get("X-Ratelimit-Reset-" + kind + suffix))
+		rem := int64(n)
+		if !ok || rem < remaining || (rem == remaining && r > reset) {
+			remaining, reset, ok = rem, r, true
+		}
+	}
+	return remaining, reset, ok
+}
+
+// parseResetDuration reads a reset header. OpenAI and Groq send Go-style
+// durations ("6m0s", "20ms"); Cerebras sends seconds as a float; a few send a
+// Unix timestamp.
+func parseResetDuration(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return max(d, 0), true
+	}
+	secs, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, false
+	}
+	// Anything past 2001 in seconds is an absolute time, not a delay.
+	if secs > 1e9 {
+		return max(time.Until(time.Unix(int64(secs), 0)), 0), true
+	}
+	return time.Duration(secs * float64(time.Second)), true
+}
+
+// parseRetryAfter reads Retry-After in either of its HTTP forms: delay in
+// seconds, or an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
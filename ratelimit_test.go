@@ -1,6 +1,8 @@
 package inferrouter
 
 import (
+	"context"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -196,3 +198,192 @@ func TestRateLimiter_CerebrasScenario(t *testing.T) {
 	// qwen-3-235b still has budget.
 	assert.True(t, rl.Allow("cerebras-free", "qwen-3-235b"), "qwen should be independent")
 }
+
+func TestRateLimiter_TPM_BlocksOnEstimate(t *testing.T) {
+	rl := NewRateLimiter()
+	ctx := context.Background()
+	rl.SetModelLimits("acc1", "m", Limits{TPM: 100})
+
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+
+	require.NoError(t, rl.Acquire(ctx, "acc1", "m", 60))
+	assert.ErrorIs(t, rl.Acquire(ctx, "acc1", "m", 60), ErrTPMExceeded, "60+60 > 100")
+	require.NoError(t, rl.Acquire(ctx, "acc1", "m", 40), "a smaller request still fits")
+
+	rl.now = func() time.Time { return now.Add(61 * time.Second) }
+	assert.NoError(t, rl.Acquire(ctx, "acc1", "m", 100), "minute window has rolled over")
+}
+
+func TestRateLimiter_TPM_RejectedRequestIsNotCounted(t *testing.T) {
+	rl := NewRateLimiter()
+	ctx := context.Background()
+	rl.SetModelLimits("acc1", "m", Limits{RPM: 2, TPM: 100})
+
+	require.NoError(t, rl.Acquire(ctx, "acc1", "m", 90))
+	assert.ErrorIs(t, rl.Acquire(ctx, "acc1", "m", 50), ErrTPMExceeded)
+	// The rejected attempt used neither a request nor tokens.
+	assert.NoError(t, rl.Acquire(ctx, "acc1", "m", 10))
+	assert.ErrorIs(t, rl.Acquire(ctx, "acc1", "m", 0), ErrRPMExceeded)
+}
+
+func TestRateLimiter_AdjustTokens(t *testing.T) {
+	rl := NewRateLimiter()
+	ctx := context.Background()
+	rl.SetModelLimits("acc1", "m", Limits{TPM: 100})
+
+	require.NoError(t, rl.Acquire(ctx, "acc1", "m", 80))
+	assert.ErrorIs(t, rl.Acquire(ctx, "acc1", "m", 30), ErrTPMExceeded)
+
+	// The request used 20 tokens, not the 80 estimated.
+	rl.AdjustTokens(ctx, "acc1", "m", -60)
+	require.NoError(t, rl.Acquire(ctx, "acc1", "m", 30))
+
+	// Underestimates are charged too.
+	rl.AdjustTokens(ctx, "acc1", "m", 50)
+	assert.ErrorIs(t, rl.Acquire(ctx, "acc1", "m", 1), ErrTPMExceeded)
+}
+
+func TestRateLimiter_TPD(t *testing.T) {
+	rl := NewRateLimiter()
+	ctx := context.Background()
+	rl.SetModelLimits("acc1", "m", Limits{TPM: 1000, TPD: 1500})
+
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+	require.NoError(t, rl.Acquire(ctx, "acc1", "m", 1000))
+
+	rl.now = func() time.Time { return now.Add(2 * time.Minute) }
+	require.NoError(t, rl.Acquire(ctx, "acc1", "m", 500))
+	assert.ErrorIs(t, rl.Acquire(ctx, "acc1", "m", 1), ErrTPMExceeded, "TPD reached while TPM is free")
+
+	rl.now = func() time.Time { return now.Add(25 * time.Hour) }
+	assert.NoError(t, rl.Acquire(ctx, "acc1", "m", 1000))
+}
+
+func TestRateLimiter_LimitsFor(t *testing.T) {
+	rl := NewRateLimiter()
+	rl.SetAccountDefault("acc1", Limits{RPM: 10})
+	rl.SetModelLimits("acc1", "m", Limits{TPM: 100, MaxConcurrency: 2})
+
+	assert.Equal(t, Limits{TPM: 100, MaxConcurrency: 2}, rl.LimitsFor("acc1", "m"))
+	assert.Equal(t, Limits{RPM: 10}, rl.LimitsFor("acc1", "other"))
+	assert.True(t, rl.LimitsFor("acc2", "m").IsZero())
+}
+
+func TestRateLimiter_SyncFromProvider_BlocksUntilReset(t *testing.T) {
+	rl := NewRateLimiter()
+	ctx := context.Background()
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+
+	zero := int64(0)
+	rl.SyncFromProvider(ctx, "cerebras-free", "gpt-oss-120b", &RateLimitInfo{
+		RemainingRequests: &zero,
+		ResetRequests:     30 * time.Second,
+	})
+	assert.False(t, rl.Allow("cerebras-free", "gpt-oss-120b"), "provider says empty")
+	assert.True(t, rl.Allow("cerebras-free", "qwen-3-235b"), "other models are unaffected")
+
+	rl.now = func() time.Time { return now.Add(31 * time.Second) }
+	assert.True(t, rl.Allow("cerebras-free", "gpt-oss-120b"), "window has reset")
+}
+
+func TestRateLimiter_SyncFromProvider_LongestResetWins(t *testing.T) {
+	rl := NewRateLimiter()
+	ctx := context.Background()
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+
+	zero := int64(0)
+	rl.SyncFromProvider(ctx, "acc1", "m", &RateLimitInfo{
+		RemainingRequests: &zero,
+		ResetRequests:     time.Second,
+		RemainingTokens:   &zero,
+		ResetTokens:       time.Minute,
+	})
+
+	rl.now = func() time.Time { return now.Add(2 * time.Second) }
+	assert.False(t, rl.Allow("acc1", "m"), "tokens are still exhausted")
+	rl.now = func() time.Time { return now.Add(61 * time.Second) }
+	assert.True(t, rl.Allow("acc1", "m"))
+}
+
+func TestRateLimiter_SyncFromProvider_BudgetLiftsBlock(t *testing.T) {
+	rl := NewRateLimiter()
+	ctx := context.Background()
+
+	zero, some := int64(0), int64(42)
+	rl.SyncFromProvider(ctx, "acc1", "m", &RateLimitInfo{RemainingRequests: &zero, ResetRequests: time.Hour})
+	assert.False(t, rl.Allow("acc1", "m"))
+
+	// The org's budget refilled early (or another client released it).
+	rl.SyncFromProvider(ctx, "acc1", "m", &RateLimitInfo{RemainingRequests: &some})
+	assert.True(t, rl.Allow("acc1", "m"))
+
+	rl.SyncFromProvider(ctx, "acc1", "m", nil)
+	assert.True(t, rl.Allow("acc1", "m"), "nil info is a no-op")
+}
+
+func TestRateLimiter_SyncFromProvider_RetryAfterAndResetAccount(t *testing.T) {
+	rl := NewRateLimiter()
+	ctx := context.Background()
+
+	rl.SyncFromProvider(ctx, "acc1", "m", &RateLimitInfo{RetryAfter: time.Hour})
+	assert.False(t, rl.Allow("acc1", "m"))
+
+	rl.ResetAccount("acc1")
+	assert.True(t, rl.Allow("acc1", "m"))
+}
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		assert.Nil(t, ParseRateLimitHeaders(http.Header{"Content-Type": {"application/json"}}))
+	})
+
+	t.Run("openai", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("x-ratelimit-remaining-requests", "59")
+		h.Set("x-ratelimit-reset-requests", "1s")
+		h.Set("x-ratelimit-remaining-tokens", "0")
+		h.Set("x-ratelimit-reset-tokens", "6m0s")
+
+		info := ParseRateLimitHeaders(h)
+		require.NotNil(t, info)
+		require.NotNil(t, info.RemainingRequests)
+		assert.Equal(t, int64(59), *info.RemainingRequests)
+		assert.Equal(t, time.Second, info.ResetRequests)
+		require.NotNil(t, info.RemainingTokens)
+		assert.Equal(t, int64(0), *info.RemainingTokens)
+		assert.Equal(t, 6*time.Minute, info.ResetTokens)
+		assert.Zero(t, info.RetryAfter)
+	})
+
+	t.Run("cerebras picks the tightest window", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("x-ratelimit-remaining-requests-day", "0")
+		h.Set("x-ratelimit-reset-requests-day", "33011.5")
+		h.Set("x-ratelimit-remaining-tokens-minute", "59000")
+		h.Set("x-ratelimit-reset-tokens-minute", "12.25")
+
+		info := ParseRateLimitHeaders(h)
+		require.NotNil(t, info)
+		assert.Equal(t, int64(0), *info.RemainingRequests)
+		assert.Equal(t, time.Duration(33011.5*float64(time.Second)), info.ResetRequests)
+		assert.Equal(t, int64(59000), *info.RemainingTokens)
+		assert.Equal(t, 12250*time.Millisecond, info.ResetTokens)
+	})
+
+	t.Run("retry-after", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("Retry-After", "7")
+		info := ParseRateLimitHeaders(h)
+		require.NotNil(t, info)
+		assert.Nil(t, info.RemainingRequests)
+		assert.Equal(t, 7*time.Second, info.RetryAfter)
+
+		h.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		info = ParseRateLimitHeaders(h)
+		assert.InDelta(t, float64(time.Hour), float64(info.RetryAfter), float64(2*time.Second))
+	})
+}
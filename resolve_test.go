@@ -235,6 +235,9 @@ func TestErrorTaxonomyContract(t *testing.T) {
 		assert.True(t, ir.IsRetryable(ir.ErrProviderUnavailable))
 		assert.True(t, ir.IsRetryable(ir.ErrQuotaExceeded))
 		assert.True(t, ir.IsRetryable(ir.ErrRPMExceeded))
+		assert.True(t, ir.IsRetryable(ir.ErrContextLengthExceeded))
+		assert.False(t, ir.IsFatal(ir.ErrContextLengthExceeded),
+			"a longer-context step may still take the request")
 		assert.False(t, ir.IsRetryable(ir.ErrMultimodalUnavailable),
 			"callers degrade on this one deliberately, they don't retry it")
 	})
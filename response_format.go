@@ -0,0 +1,118 @@
+package inferrouter
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ResponseFormatType selects how the model must shape its answer.
+type ResponseFormatType string
+
+const (
+	// ResponseFormatText is the default free-form answer.
+	ResponseFormatText ResponseFormatType = "text"
+	// ResponseFormatJSON asks for any syntactically valid JSON value.
+	ResponseFormatJSON ResponseFormatType = "json_object"
+	// ResponseFormatJSONSchema asks for JSON conforming to Schema.
+	ResponseFormatJSONSchema ResponseFormatType = "json_schema"
+)
+
+// ResponseFormat constrains the shape of the answer. Adapters map it to the
+// provider's native structured-output switch (OpenAI response_format, Gemini
+// responseMimeType/responseJsonSchema).
+type ResponseFormat struct {
+	Type ResponseFormatType `json:"type"`
+
+	// Name labels the schema. OpenAI requires one; "response" is used when
+	// empty. Ignored by providers that don't name schemas.
+	Name string `json:"name,omitempty"`
+
+	// Schema is the JSON Schema for ResponseFormatJSONSchema, passed to the
+	// provider verbatim. Gemini gets it as responseJsonSchema, which takes
+	// standard JSON Schema, not as responseSchema, whose OpenAPI subset
+	// spells and supports keywords differently (nullable, no $ref); write the
+	// schema in JSON Schema for both.
+	Schema json.RawMessage `json:"schema,omitempty"`
+
+	// Strict requests strict schema adherence where the provider supports it
+	// (OpenAI "strict": true).
+	Strict bool `json:"strict,omitempty"`
+
+	// Validate makes the router check the returned content itself: invalid
+	// JSON, or JSON that does not satisfy Schema, counts as a retryable
+	// failure of that candidate and the ladder moves to the next step. The
+	// check runs on ChatCompletion only — a stream has already been handed to
+	// the caller by the time its content is complete.
+	Validate bool `json:"validate,omitempty"`
+}
+
+// validateResponseFormat rejects a malformed ResponseFormat before any
+// candidate is tried: a broken schema is the caller's bug, and letting it
+// surface as a per-candidate mismatch would walk the whole ladder for nothing.
+func validateResponseFormat(rf *ResponseFormat) error {
+	if rf == nil {
+		return nil
+	}
+	switch rf.Type {
+	case "", ResponseFormatText, ResponseFormatJSON:
+		return nil
+	case ResponseFormatJSONSchema:
+		if len(rf.Schema) == 0 {
+			return fmt.Errorf("%w: response_format json_schema requires a schema", ErrInvalidRequest)
+		}
+		var schema any
+		if err := json.Unmarshal(rf.Schema, &schema); err != nil {
+			return fmt.Errorf("%w: response_format schema: %v", ErrInvalidRequest, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown response_format type %q", ErrInvalidRequest, rf.Type)
+	}
+}
+
+// checkResponseChoices runs checkResponseFormat on every completion of resp.
+// A turn that ends in tool calls carries no answer to validate.
+func checkResponseChoices(rf *ResponseFormat, resp ProviderResponse) error {
+	if len(resp.Choices) == 0 {
+		if len(resp.ToolCalls) > 0 {
+			return nil
+		}
+		return checkResponseFormat(rf, resp.Content)
+	}
+	for _, c := range resp.Choices {
+		if len(c.Message.ToolCalls) > 0 {
+			continue
+		}
+		if err := checkResponseFormat(rf, c.Message.Content); err != nil {
+			return fmt.Errorf("choice %d: %w", c.Index, err)
+		}
+	}
+	return nil
+}
+
+// checkResponseFormat validates content against rf when rf asks for it.
+// Returns nil when no validation was requested or the content conforms, and
+// an error wrapping ErrResponseFormatMismatch otherwise. rf must have passed
+// validateResponseFormat.
+func checkResponseFormat(rf *ResponseFormat, content string) error {
+	if rf == nil || !rf.Validate {
+		return nil
+	}
+	switch rf.Type {
+	case ResponseFormatJSON:
+		if !json.Valid([]byte(content)) {
+			return fmt.Errorf("%w: content is not valid JSON", ErrResponseFormatMismatch)
+		}
+	case ResponseFormatJSONSchema:
+		var doc any
+		if err := json.Unmarshal([]byte(content), &doc); err != nil {
+			return fmt.Errorf("%w: content is not valid JSON: %v", ErrResponseFormatMismatch, err)
+		}
+		var schema any
+		_ = json.Unmarshal(rf.Schema, &schema) // checked by validateResponseFormat
+		if err := validateSchema(schema, doc, "$"); err != nil {
+			return fmt.Errorf("%w: %v", ErrResponseFormatMismatch, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package inferrouter_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/provider/mock"
+	"github.com/ineyio/inferrouter/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var citySchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {"city": {"type": "string"}, "temp": {"type": "number"}},
+	"required": ["city", "temp"],
+	"additionalProperties": false
+}`)
+
+// answeringStep records the attempt and answers with fixed content.
+func answeringStep(log *attemptLog, name, content string) *mock.Provider {
+	return mock.New(
+		mock.WithName(name),
+		mock.WithModels("ladder-model"),
+		mock.WithResponseFunc(func(ir.ProviderRequest) (ir.ProviderResponse, error) {
+			log.record(name)
+			return ir.ProviderResponse{
+				Content: content,
+				Model:   "ladder-model",
+				Usage:   ir.Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2},
+			}, nil
+		}),
+	)
+}
+
+func schemaRequest(validate bool) ir.ChatRequest {
+	return ir.ChatRequest{
+		Model:    "ladder",
+		Messages: []ir.Message{{Role: "user", Content: "weather in Paris as JSON"}},
+		ResponseFormat: &ir.ResponseFormat{
+			Type:     ir.ResponseFormatJSONSchema,
+			Name:     "weather",
+			Schema:   citySchema,
+			Validate: validate,
+		},
+	}
+}
+
+// With validation on, a candidate whose answer breaks the schema is treated
+// as a retryable failure and the next step serves.
+func TestResponseFormat_MismatchFallsThrough(t *testing.T) {
+	log := &attemptLog{}
+	sloppy := answeringStep(log, "sloppy-step", `{"city":"Paris"}`)
+	strict := answeringStep(log, "strict-step", `{"city":"Paris","temp":21.5}`)
+
+	cfg := ladderConfig("sloppy-step", "strict-step")
+	r, err := ir.NewRouter(cfg, []ir.Provider{sloppy, strict},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	require.NoError(t, err)
+
+	resp, err := r.ChatCompletion(context.Background(), schemaRequest(true))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"sloppy-step", "strict-step"}, log.snapshot())
+	assert.Equal(t, "strict-step", resp.Routing.Provider)
+	assert.Equal(t, 2, resp.Routing.Attempts)
+}
+
+// With N > 1 every completion is validated, not just the first.
+func TestResponseFormat_ChecksEveryChoice(t *testing.T) {
+	log := &attemptLog{}
+	good := `{"city":"Paris","temp":21.5}`
+	mixed := mock.New(
+		mock.WithName("mixed-step"),
+		mock.WithModels("ladder-model"),
+		mock.WithResponseFunc(func(ir.ProviderRequest) (ir.ProviderResponse, error) {
+			log.record("mixed-step")
+			return ir.ProviderResponse{
+				Content: good,
+				Model:   "ladder-model",
+				Choices: []ir.Choice{
+					{Index: 0, Message: ir.Message{Role: "assistant", Content: good}},
+					{Index: 1, Message: ir.Message{Role: "assistant", Content: `{"city":"Paris"}`}},
+				},
+			}, nil
+		}),
+	)
+	strict := answeringStep(log, "strict-step", good)
+
+	r, err := ir.NewRouter(ladderConfig("mixed-step", "strict-step"), []ir.Provider{mixed, strict},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	require.NoError(t, err)
+
+	resp, err := r.ChatCompletion(context.Background(), schemaRequest(true))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mixed-step", "strict-step"}, log.snapshot())
+	assert.Equal(t, "strict-step", resp.Routing.Provider)
+}
+
+// Without Validate the router passes the content through untouched.
+func TestResponseFormat_NoValidationPassesThrough(t *testing.T) {
+	log := &attemptLog{}
+	sloppy := answeringStep(log, "sloppy-step", "not json at all")
+
+	cfg := ladderConfig("sloppy-step")
+	r, err := ir.NewRouter(cfg, []ir.Provider{sloppy},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	require.NoError(t, err)
+
+	resp, err := r.ChatCompletion(context.Background(), schemaRequest(false))
+	require.NoError(t, err)
+	assert.Equal(t, "not json at all", resp.Choices[0].Message.Content)
+}
+
+// Every step failing validation ends in ErrAllFailed carrying the mismatch
+// for each candidate.
+func TestResponseFormat_AllMismatch(t *testing.T) {
+	log := &attemptLog{}
+	cfg := ladderConfig("one", "two")
+	r, err := ir.NewRouter(cfg, []ir.Provider{
+		answeringStep(log, "one", "[]"),
+		answeringStep(log, "two", `{"city":1,"temp":2}`),
+	}, ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	require.NoError(t, err)
+
+	_, err = r.ChatCompletion(context.Background(), schemaRequest(true))
+	require.ErrorIs(t, err, ir.ErrAllFailed)
+
+	var rerr *ir.RouterError
+	require.True(t, errors.As(err, &rerr))
+	require.Len(t, rerr.Tried, 2)
+	for _, tried := range rerr.Tried {
+		assert.ErrorIs(t, tried.Err, ir.ErrResponseFormatMismatch)
+	}
+}
+
+// A schema that is not even JSON is the caller's bug: rejected before any
+// candidate is attempted.
+func TestResponseFormat_BrokenSchemaIsInvalidRequest(t *testing.T) {
+	log := &attemptLog{}
+	cfg := ladderConfig("only")
+	r, err := ir.NewRouter(cfg, []ir.Provider{answeringStep(log, "only", "{}")},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	require.NoError(t, err)
+
+	req := schemaRequest(true)
+	req.ResponseFormat.Schema = json.RawMessage(`{"type":`)
+	_, err = r.ChatCompletion(context.Background(), req)
+
+	assert.ErrorIs(t, err, ir.ErrInvalidRequest)
+	assert.Empty(t, log.snapshot())
+}
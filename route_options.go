@@ -0,0 +1,103 @@
+package inferrouter
+
+import "slices"
+
+// RouteOptions overrides the router's configuration for a single request:
+// pin it to an account while debugging, keep a flaky provider away from one
+// tenant, let a premium request use paid accounts. The zero value, like a nil
+// *RouteOptions, changes nothing.
+//
+// Overrides narrow the candidate list before the policy orders it. They do
+// not bypass health, cooldowns, rate limits or quota: a pinned account that
+// is unhealthy or exhausted still fails the request.
+type RouteOptions struct {
+	// Accounts and Providers, when non-empty, restrict routing to the listed
+	// account IDs and provider names.
+	Accounts  []string
+	Providers []string
+
+	// ExcludeAccounts and ExcludeProviders drop the listed account IDs and
+	// provider names. An exclusion wins over an inclusion.
+	ExcludeAccounts  []string
+	ExcludeProviders []string
+
+	// AllowPaid, when set, replaces Config.AllowPaid for this request, in
+	// either direction.
+	AllowPaid *bool
+
+	// MaxAttempts caps how many candidates are tried, skipped ones included.
+	// Zero means all of them.
+	MaxAttempts int
+
+	// MaxCost drops candidates whose estimated cost for the request, in
+	// dollars at their configured rates, exceeds it. Chat requests are
+	// estimated from the prompt plus MaxTokens of output; without MaxTokens
+	// only the prompt is priced. Zero means no cap.
+	MaxCost float64
+}
+
+// allowPaid is the effective AllowPaid for the request.
+func (o *RouteOptions) allowPaid(def bool) bool {
+	if o == nil || o.AllowPaid == nil {
+		return def
+	}
+	return *o.AllowPaid
+}
+
+// admits reports whether the options let a request use the account.
+func (o *RouteOptions) admits(provider, accountID string, estimatedCost float64) bool {
+	if o == nil {
+		return true
+	}
+	if len(o.Accounts) > 0 && !slices.Contains(o.Accounts, accountID) {
+		return false
+	}
+	if len(o.Providers) > 0 && !slices.Contains(o.Providers, provider) {
+		return false
+	}
+	if slices.Contains(o.ExcludeAccounts, accountID) || slices.Contains(o.ExcludeProviders, provider) {
+		return false
+	}
+	return o.MaxCost <= 0 || estimatedCost <= o.MaxCost
+}
+
+// capAttempts truncates an ordered candidate list to MaxAttempts.
+func capAttempts[C any](o *RouteOptions, ordered []C) []C {
+	if o == nil || o.MaxAttempts <= 0 || len(ordered) <= o.MaxAttempts {
+		return ordered
+	}
+	return ordered[:o.MaxAttempts]
+}
+
+// applyRouteOptions drops the chat candidates o does not admit.
+func applyRouteOptions(o *RouteOptions, candidates []Candidate, estimatedTokens int64, maxTokens int) []Candidate {
+	if o == nil {
+		return candidates
+	}
+	out := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		cost := calculateSpend(c, Usage{
+			PromptTokens:     estimatedTokens,
+			CompletionTokens: int64(maxTokens),
+			TotalTokens:      estimatedTokens + int64(maxTokens),
+		})
+		if o.admits(c.Provider.Name(), c.AccountID, cost) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// applyEmbedRouteOptions drops the embed candidates o does not admit.
+func applyEmbedRouteOptions(o *RouteOptions, candidates []EmbedCandidate, estimatedTokens int64) []EmbedCandidate {
+	if o == nil {
+		return candidates
+	}
+	out := make([]EmbedCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if o.admits(c.Provider.Name(), c.AccountID, float64(estimatedTokens)*c.Cost) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
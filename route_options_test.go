@@ -0,0 +1,131 @@
+package inferrouter_test
+
+import (
+	"context"
+	"testing"
+
+	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/provider/mock"
+	"github.com/ineyio/inferrouter/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRouteOptionsRouter(t *testing.T, providers ...ir.Provider) *ir.Router {
+	t.Helper()
+	cfg := declareLadder(ir.Config{
+		DefaultModel: "test-model",
+		Accounts: []ir.AccountConfig{
+			{Provider: "alpha", ID: "alpha-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "alpha", ID: "alpha-2", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "beta", ID: "beta-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "beta", ID: "beta-paid", PaidEnabled: true, QuotaUnit: ir.QuotaTokens,
+				CostPerInputToken: 0.001, CostPerOutputToken: 0.002},
+		},
+	})
+	r, err := ir.NewRouter(cfg, providers, ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	require.NoError(t, err)
+	return r
+}
+
+func TestRouteOptions_NarrowCandidates(t *testing.T) {
+	r := newRouteOptionsRouter(t,
+		mock.New(mock.WithName("alpha"), mock.WithModels("test-model")),
+		mock.New(mock.WithName("beta"), mock.WithModels("test-model")),
+	)
+	chat := func(o *ir.RouteOptions) (ir.ChatResponse, error) {
+		return r.ChatCompletion(context.Background(), ir.ChatRequest{
+			Messages: []ir.Message{{Role: "user", Content: "hello"}},
+			Route:    o,
+		})
+	}
+
+	resp, err := chat(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "alpha-1", resp.Routing.AccountID)
+
+	resp, err = chat(&ir.RouteOptions{Accounts: []string{"alpha-2"}})
+	require.NoError(t, err)
+	assert.Equal(t, "alpha-2", resp.Routing.AccountID, "pinned account")
+
+	resp, err = chat(&ir.RouteOptions{ExcludeProviders: []string{"alpha"}})
+	require.NoError(t, err)
+	assert.Equal(t, "beta-1", resp.Routing.AccountID)
+
+	resp, err = chat(&ir.RouteOptions{Providers: []string{"alpha"}, ExcludeAccounts: []string{"alpha-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "alpha-2", resp.Routing.AccountID, "exclusion wins over inclusion")
+
+	_, err = chat(&ir.RouteOptions{Accounts: []string{"beta-paid"}})
+	assert.ErrorIs(t, err, ir.ErrNoCandidates, "paid account without AllowPaid")
+
+	allow := true
+	resp, err = chat(&ir.RouteOptions{Accounts: []string{"beta-paid"}, AllowPaid: &allow})
+	require.NoError(t, err)
+	assert.Equal(t, "beta-paid", resp.Routing.AccountID, "AllowPaid override")
+
+	// 8 prompt tokens and 100 of output: 0.008 + 0.2 dollars.
+	maxTokens := 100
+	_, err = r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages:  []ir.Message{{Role: "user", Content: "hello"}},
+		MaxTokens: &maxTokens,
+		Route:     &ir.RouteOptions{Accounts: []string{"beta-paid"}, AllowPaid: &allow, MaxCost: 0.1},
+	})
+	assert.ErrorIs(t, err, ir.ErrNoCandidates, "over MaxCost")
+
+	resp, err = r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages:  []ir.Message{{Role: "user", Content: "hello"}},
+		MaxTokens: &maxTokens,
+		Route:     &ir.RouteOptions{ExcludeProviders: []string{"alpha"}, AllowPaid: &allow, MaxCost: 0.1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "beta-1", resp.Routing.AccountID, "free account costs nothing")
+}
+
+func TestRouteOptions_MaxAttempts(t *testing.T) {
+	r := newRouteOptionsRouter(t,
+		mock.New(mock.WithName("alpha"), mock.WithModels("test-model"), mock.WithError(ir.ErrProviderUnavailable)),
+		mock.New(mock.WithName("beta"), mock.WithModels("test-model")),
+	)
+
+	_, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+		Route:    &ir.RouteOptions{MaxAttempts: 2},
+	})
+	var routerErr *ir.RouterError
+	require.ErrorAs(t, err, &routerErr)
+	assert.ErrorIs(t, err, ir.ErrAllFailed)
+	assert.Len(t, routerErr.Tried, 2, "stopped before the beta accounts")
+
+	resp, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+		Route:    &ir.RouteOptions{MaxAttempts: 3},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "beta-1", resp.Routing.AccountID)
+}
+
+func TestRouteOptions_Embed(t *testing.T) {
+	embedProv := mock.NewEmbed(mock.WithEmbedSupportedModels("text-embedding-004"), mock.WithEmbedDimensions(4))
+	cfg := ir.Config{
+		DefaultModel: "text-embedding-004",
+		Accounts: []ir.AccountConfig{
+			{Provider: "mock-embed", ID: "free-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "mock-embed", ID: "free-2", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+	r := newEmbedRouter(t, cfg, embedProv)
+
+	resp, err := r.EmbedBatch(context.Background(), ir.EmbedRequest{
+		Inputs: []string{"hello"},
+		Route:  &ir.RouteOptions{ExcludeAccounts: []string{"free-1"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "free-2", resp.Routing.AccountID)
+
+	_, err = r.Embed(context.Background(), ir.EmbedRequest{
+		Inputs: []string{"hello"},
+		Route:  &ir.RouteOptions{Providers: []string{"gemini"}},
+	})
+	assert.ErrorIs(t, err, ir.ErrNoCandidates)
+}
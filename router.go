@@ -2,6 +2,7 @@ package inferrouter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -15,10 +16,24 @@ type Router struct {
 	policy      Policy
 	quotaStore  QuotaStore
 	meter       Meter
+	tracer      Tracer
 	health      *HealthTracker
-	spend       *SpendTracker
-	rateLimiter *RateLimiter
+	spend       SpendStore
+	rateLimiter RateLimitStore
 	inflight    *InflightTracker
+	latency     *LatencyTracker
+
+	// cooldown holds (account, model) pairs that answered 429, for the
+	// provider's Retry-After or rateLimitCooldown. See RateLimitError.
+	cooldown          *cooldownTracker
+	rateLimitCooldown time.Duration
+
+	// streamFailover selects whether a RouterStream may move to the next
+	// candidate after the provider stream dies. See StreamFailover.
+	streamFailover StreamFailover
+
+	// hedger is nil unless the router was built WithHedging.
+	hedger *hedger
 
 	// embedProviders is discovered at NewRouter via type-assertion: any
 	// Provider that also implements EmbeddingProvider is registered here.
@@ -60,6 +75,11 @@ func WithMeter(m Meter) Option {
 	return func(r *Router) { r.meter = m }
 }
 
+// WithTracer sets the tracer, e.g. the OpenTelemetry one in tracing/otel.
+func WithTracer(t Tracer) Option {
+	return func(r *Router) { r.tracer = t }
+}
+
 // WithHealthTracker sets the health tracker.
 func WithHealthTracker(h *HealthTracker) Option {
 	return func(r *Router) { r.health = h }
@@ -72,14 +92,47 @@ func WithHealthConfig(cfg HealthConfig) Option {
 
 // WithSpendTracker sets the spend tracker.
 func WithSpendTracker(s *SpendTracker) Option {
+	return func(r *Router) { r.spend = s.Store() }
+}
+
+// WithSpendStore sets a shared spend store, such as the ones in quota/redis
+// and quota/postgres, so MaxDailySpend holds across replicas and restarts.
+func WithSpendStore(s SpendStore) Option {
 	return func(r *Router) { r.spend = s }
 }
 
-// WithRateLimiter sets a custom rate limiter.
-func WithRateLimiter(rl *RateLimiter) Option {
+// WithRateLimiter sets a custom rate limiter: a preconfigured *RateLimiter,
+// or a shared RateLimitStore such as the one in quota/redis. The router still
+// applies each account's rpm and model_limits to it at construction.
+func WithRateLimiter(rl RateLimitStore) Option {
 	return func(r *Router) { r.rateLimiter = rl }
 }
 
+// WithLatencyTracker sets the latency tracker, e.g. to share observed
+// latencies between routers.
+func WithLatencyTracker(t *LatencyTracker) Option {
+	return func(r *Router) { r.latency = t }
+}
+
+// WithRateLimitCooldown sets how long an (account, model) pair is skipped
+// after a 429 that carried no Retry-After (default DefaultRateLimitCooldown).
+// A provider-supplied Retry-After always takes precedence.
+func WithRateLimitCooldown(d time.Duration) Option {
+	return func(r *Router) { r.rateLimitCooldown = d }
+}
+
+// WithStreamFailover lets ChatCompletionStream continue on the next candidate
+// when an open provider stream fails. Off by default: a caller that already
+// rendered part of an answer has to opt in to a different model finishing it.
+func WithStreamFailover(mode StreamFailover) Option {
+	return func(r *Router) { r.streamFailover = mode }
+}
+
+// WithHedging enables hedged requests on ChatCompletion. See HedgeConfig.
+func WithHedging(cfg HedgeConfig) Option {
+	return func(r *Router) { r.hedger = newHedger(cfg) }
+}
+
 // NewRouter creates a new Router with the given config and providers.
 // Default components (FreeFirstPolicy, MemoryQuotaStore, NoopMeter) are used
 // unless overridden via options.
@@ -112,8 +165,12 @@ func NewRouter(cfg Config, providers []Provider, opts ...Option) (*Router, error
 		providers:      provMap,
 		embedProviders: embedProvMap,
 		health:         NewHealthTracker(),
-		spend:          NewSpendTracker(),
+		spend:          NewSpendTracker().Store(),
 		inflight:       NewInflightTracker(),
+		latency:        NewLatencyTracker(),
+		cooldown:       newCooldownTracker(),
+
+		rateLimitCooldown: DefaultRateLimitCooldown,
 	}
 
 	for _, opt := range opts {
@@ -132,6 +189,9 @@ func NewRouter(cfg Config, providers []Provider, opts ...Option) (*Router, error
 	if r.meter == nil {
 		r.meter = &noopMeter{}
 	}
+	if r.tracer == nil {
+		r.tracer = noopTracer{}
+	}
 	if r.rateLimiter == nil {
 		r.rateLimiter = NewRateLimiter()
 	}
@@ -157,10 +217,10 @@ func NewRouter(cfg Config, providers []Provider, opts ...Option) (*Router, error
 	// candidate that could never reserve: dead, and silently so.
 	if init, ok := r.quotaStore.(QuotaInitializer); ok {
 		for _, acc := range cfg.Accounts {
-			if acc.DailyFree <= 0 {
+			if acc.dailyQuota() <= 0 {
 				continue
 			}
-			if err := init.SetQuota(acc.ID, acc.DailyFree, acc.QuotaUnit); err != nil {
+			if err := init.SetQuota(acc.ID, acc.dailyQuota(), acc.QuotaUnit); err != nil {
 				return nil, fmt.Errorf("inferrouter: init quota for %q: %w", acc.ID, err)
 			}
 		}
@@ -182,60 +242,158 @@ func NewRouter(cfg Config, providers []Provider, opts ...Option) (*Router, error
 // --- Domain phases of a routing request ---
 
 // prepareRoute resolves the model, builds, filters, and orders candidates.
-// When needMultimodal is true and the filter empties the list, the more
-// specific ErrMultimodalUnavailable is returned instead of ErrNoCandidates.
-func (r *Router) prepareRoute(ctx context.Context, requestModel string, needMultimodal bool) ([]Candidate, error) {
-	candidates, err := buildCandidates(ctx, r.cfg, r.providers, r.quotaStore, r.health, r.spend, r.inflight, requestModel)
+// Steps the model catalog says are too small for the request go first, with
+// ErrContextWindowExceeded when none is left. When the filter empties the
+// list because of a capability the request needs (media, tools), the more
+// specific sentinel (ErrMultimodalUnavailable, ErrToolsUnavailable) is
+// returned instead of ErrNoCandidates.
+func (r *Router) prepareRoute(ctx context.Context, rc RouteContext, needs routeNeeds) ([]Candidate, error) {
+	candidates, err := buildCandidates(ctx, r.cfg, r.providers, r.quotaStore, r.health, r.spend, r.inflight, r.latency, rc.Model)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err = fitCandidates(candidates, rc.EstimatedTokens, rc.MaxTokens)
 	if err != nil {
 		return nil, err
 	}
 
-	candidates = filterCandidates(candidates, r.cfg.AllowPaid, needMultimodal)
+	candidates, err = filterCandidates(candidates, rc.Route.allowPaid(r.cfg.AllowPaid), needs)
+	if err != nil {
+		return nil, err
+	}
+	candidates = applyRouteOptions(rc.Route, candidates, rc.EstimatedTokens, rc.MaxTokens)
 	if len(candidates) == 0 {
-		if needMultimodal {
-			return nil, ErrMultimodalUnavailable
-		}
 		return nil, ErrNoCandidates
 	}
 
 	// No policy configured — config order is the attempt order (R1).
 	if r.policy == nil {
-		return candidates, nil
+		return capAttempts(rc.Route, candidates), nil
+	}
+	if rp, ok := r.policy.(RequestPolicy); ok {
+		candidates = rp.SelectRequest(rc, candidates)
+		if len(candidates) == 0 {
+			return nil, ErrNoCandidates
+		}
+		return capAttempts(rc.Route, candidates), nil
 	}
-	return r.policy.Select(candidates), nil
+	return capAttempts(rc.Route, r.policy.Select(candidates)), nil
+}
+
+// grant is what acquire took for one attempt: the quota reservation, and the
+// tokens charged to the rate limiter's TPM/TPD windows, which settling
+// corrects to the actual usage. The in-flight slot acquire also takes is
+// released by the caller as soon as the provider call returns.
+type grant struct {
+	Reservation
+	tokens int64
+}
+
+// acquire checks the cooldown, concurrency and rate limits and reserves quota
+// for a candidate. On success the candidate holds an in-flight slot (release
+// with r.inflight.Release) and the returned grant must be settled. Otherwise
+// it returns a CandidateError and the candidate should be skipped.
+func (r *Router) acquire(ctx context.Context, c Candidate, estimatedTokens int64) (grant, *CandidateError) {
+	// A dollar quota reserves the estimated input priced at the candidate's
+	// rates; the commit replaces it with the real cost.
+	estimatedDollars := calculateSpend(c, Usage{PromptTokens: estimatedTokens, TotalTokens: estimatedTokens})
+	reserveAmount := quotaAmount(c.QuotaUnit, estimatedTokens, estimatedDollars)
+	return r.acquireFor(ctx, c.Provider.Name(), c.AccountID, c.Model, c.QuotaUnit, estimatedTokens, reserveAmount)
 }
 
-// acquire attempts RPM check and quota reservation for a candidate.
-// Returns the reservation on success, or a CandidateError if the candidate should be skipped.
-func (r *Router) acquire(ctx context.Context, c Candidate, estimatedTokens int64) (Reservation, *CandidateError) {
-	if !r.rateLimiter.Allow(c.AccountID, c.Model) {
-		return Reservation{}, &CandidateError{
-			Provider: c.Provider.Name(), AccountID: c.AccountID, Model: c.Model,
-			Err: ErrRPMExceeded,
+// acquireFor is acquire for any kind of candidate; the embed path shares it.
+// reserveAmount is the estimate in the account's quota unit.
+func (r *Router) acquireFor(ctx context.Context, provider, accountID, model string, unit QuotaUnit, estimatedTokens, reserveAmount int64) (grant, *CandidateError) {
+	ctx, span := r.tracer.Start(ctx, SpanAcquire)
+	defer span.End()
+
+	if skip := r.coolingDown(provider, accountID, model); skip != nil {
+		span.RecordError(skip)
+		return grant{}, skip
+	}
+	skip := func(err error) (grant, *CandidateError) {
+		ce := &CandidateError{
+			Provider: provider, AccountID: accountID, Model: model,
+			Err: err,
 		}
+		span.RecordError(ce)
+		return grant{}, ce
 	}
 
-	reserveAmount := estimatedTokens
-	if c.QuotaUnit == QuotaRequests {
-		reserveAmount = 1
+	limits := r.rateLimiter.LimitsFor(accountID, model)
+	if !r.inflight.Acquire(accountID, model, limits.MaxConcurrency) {
+		return skip(ErrConcurrencyExceeded)
+	}
+	if err := r.rateLimiter.Acquire(ctx, accountID, model, estimatedTokens); err != nil {
+		r.inflight.Release(accountID, model)
+		return skip(err)
 	}
 
-	reservation, err := r.quotaStore.Reserve(ctx, c.AccountID, reserveAmount, c.QuotaUnit, uuid.New().String())
+	reservation, err := r.quotaStore.Reserve(ctx, accountID, reserveAmount, unit, uuid.New().String())
 	if err != nil {
-		return Reservation{}, &CandidateError{
-			Provider: c.Provider.Name(), AccountID: c.AccountID, Model: c.Model,
-			Err: err,
+		r.inflight.Release(accountID, model)
+		r.rateLimiter.AdjustTokens(ctx, accountID, model, -estimatedTokens)
+		return skip(err)
+	}
+	return grant{Reservation: reservation, tokens: estimatedTokens}, nil
+}
+
+// coolingDown returns the skip error for a pair still in its 429 cooldown, or
+// nil. The error is a RateLimitError carrying the time left, so a caller that
+// ends up with nothing can pass a meaningful Retry-After on.
+func (r *Router) coolingDown(provider, accountID, model string) *CandidateError {
+	left := r.cooldown.remaining(accountID, model)
+	if left == 0 {
+		return nil
+	}
+	return &CandidateError{
+		Provider: provider, AccountID: accountID, Model: model,
+		Err: &RateLimitError{RetryAfter: left, Detail: "cooling down after 429"},
+	}
+}
+
+// recordFailureHealth routes a failed attempt to the right tracker. A 429
+// starts a cooldown for the pair and leaves health alone; anything else
+// counts against the account, and against the provider when it points there.
+func (r *Router) recordFailureHealth(ctx context.Context, provider, accountID, model string, err error) {
+	if errors.Is(err, ErrContextLengthExceeded) {
+		return // the request was too long, the account is fine
+	}
+	if errors.Is(err, ErrRateLimited) {
+		wait := r.rateLimitCooldown
+		var rle *RateLimitError
+		if errors.As(err, &rle) && rle.RetryAfter > 0 {
+			wait = rle.RetryAfter
+		}
+		if wait > 0 {
+			r.cooldown.start(accountID, model, wait)
 		}
+		return
+	}
+	r.health.RecordFailure(accountID)
+	if isProviderFault(ctx, err) {
+		r.health.RecordProviderFailure(provider, accountID)
 	}
-	return reservation, nil
 }
 
 // settleFailure handles rollback, health tracking, and metering after a provider error.
 // Returns a RouterError if the error is fatal (caller should return immediately),
 // or a CandidateError to append to the tried list.
-func (r *Router) settleFailure(ctx context.Context, c Candidate, reservation Reservation, providerErr error, duration time.Duration, attempt int) (*RouterError, CandidateError) {
-	rollbackErr := r.quotaStore.Rollback(ctx, reservation)
-	r.health.RecordFailure(c.AccountID)
+func (r *Router) settleFailure(ctx context.Context, c Candidate, g grant, providerErr error, duration time.Duration, attempt int) (*RouterError, CandidateError) {
+	ctx, span := r.tracer.Start(ctx, SpanSettle)
+	defer span.End()
+
+	rollbackErr := r.quotaStore.Rollback(ctx, g.Reservation)
+	if rollbackErr != nil {
+		span.RecordError(rollbackErr)
+	}
+	r.rateLimiter.AdjustTokens(ctx, c.AccountID, c.Model, -g.tokens)
+	r.recordFailureHealth(ctx, c.Provider.Name(), c.AccountID, c.Model, providerErr)
+	// An attempt that ran out its own budget was at least this slow; the
+	// caller's deadline says nothing about the account.
+	if duration > 0 && errors.Is(providerErr, context.DeadlineExceeded) && ctx.Err() == nil {
+		r.latency.Record(c.AccountID, c.Model, duration, 0)
+	}
 
 	resultErr := providerErr
 	if rollbackErr != nil {
@@ -250,6 +408,7 @@ func (r *Router) settleFailure(ctx context.Context, c Candidate, reservation Res
 		Success:   false,
 		Duration:  duration,
 		Error:     resultErr,
+		Hedge:     isHedge(ctx),
 	})
 
 	ce := CandidateError{
@@ -272,37 +431,89 @@ func (r *Router) settleFailure(ctx context.Context, c Candidate, reservation Res
 
 // settleSuccess handles quota commit, health tracking, spend recording, and metering
 // after a successful provider response.
-func (r *Router) settleSuccess(ctx context.Context, c Candidate, reservation Reservation, usage Usage, duration time.Duration) {
-	actualTokens := usage.TotalTokens
-	if c.QuotaUnit == QuotaRequests {
-		actualTokens = 1
-	}
-	commitErr := r.quotaStore.Commit(ctx, reservation, actualTokens)
-	r.health.RecordSuccess(c.AccountID)
+func (r *Router) settleSuccess(ctx context.Context, c Candidate, g grant, usage Usage, duration time.Duration) {
+	ctx, span := r.tracer.Start(ctx, SpanSettle)
+	defer span.End()
 
 	dollarCost := calculateSpend(c, usage)
-	if dollarCost > 0 {
-		r.spend.RecordSpend(c.AccountID, dollarCost)
-	}
+	attemptSpan(ctx).SetAttributes(usageAttributes(usage, dollarCost)...)
+	commitErr := r.quotaStore.Commit(ctx, g.Reservation, quotaAmount(c.QuotaUnit, usage.TotalTokens, dollarCost))
+	r.rateLimiter.AdjustTokens(ctx, c.AccountID, c.Model, usage.TotalTokens-g.tokens)
+	r.health.RecordSuccess(c.AccountID)
+	r.health.RecordProviderSuccess(c.Provider.Name())
+	r.latency.Record(c.AccountID, c.Model, duration, 0)
+
+	spendErr := recordSpend(ctx, r.spend, c.AccountID, dollarCost)
 
 	var meterErr error
 	if commitErr != nil {
 		meterErr = fmt.Errorf("quota commit failed: %w", commitErr)
 	}
+	meterErr = errors.Join(meterErr, spendErr)
+	if meterErr != nil {
+		span.RecordError(meterErr)
+	}
 
 	r.meter.OnResult(ResultEvent{
 		Provider:   c.Provider.Name(),
 		AccountID:  c.AccountID,
 		Model:      c.Model,
 		Free:       c.Free,
-		Success:    commitErr == nil,
+		Success:    meterErr == nil,
 		Duration:   duration,
 		Usage:      usage,
 		Error:      meterErr,
 		DollarCost: dollarCost,
+		Hedge:      isHedge(ctx),
 	})
 }
 
+// settleRejected handles a response the provider completed but the router
+// refused (ErrResponseFormatMismatch). The tokens were generated and billed,
+// so quota is committed and spend recorded exactly as on success; health is
+// left untouched because the account served the request fine. The meter sees
+// a failed result carrying the real usage and cost.
+func (r *Router) settleRejected(ctx context.Context, c Candidate, g grant, usage Usage, duration time.Duration, rejectErr error) CandidateError {
+	ctx, span := r.tracer.Start(ctx, SpanSettle)
+	defer span.End()
+
+	dollarCost := calculateSpend(c, usage)
+	attemptSpan(ctx).SetAttributes(usageAttributes(usage, dollarCost)...)
+	commitErr := r.quotaStore.Commit(ctx, g.Reservation, quotaAmount(c.QuotaUnit, usage.TotalTokens, dollarCost))
+	r.rateLimiter.AdjustTokens(ctx, c.AccountID, c.Model, usage.TotalTokens-g.tokens)
+	r.latency.Record(c.AccountID, c.Model, duration, 0)
+
+	spendErr := recordSpend(ctx, r.spend, c.AccountID, dollarCost)
+
+	resultErr := rejectErr
+	if commitErr != nil {
+		resultErr = fmt.Errorf("%w (quota commit failed: %v)", rejectErr, commitErr)
+		span.RecordError(commitErr)
+	}
+	if spendErr != nil {
+		resultErr = fmt.Errorf("%w (%v)", resultErr, spendErr)
+		span.RecordError(spendErr)
+	}
+
+	r.meter.OnResult(ResultEvent{
+		Provider:   c.Provider.Name(),
+		AccountID:  c.AccountID,
+		Model:      c.Model,
+		Free:       c.Free,
+		Success:    false,
+		Duration:   duration,
+		Usage:      usage,
+		Error:      resultErr,
+		DollarCost: dollarCost,
+		Hedge:      isHedge(ctx),
+	})
+
+	return CandidateError{
+		Provider: c.Provider.Name(), AccountID: c.AccountID, Model: c.Model,
+		Err: rejectErr,
+	}
+}
+
 // messagesHaveMedia reports whether any message carries non-text parts.
 func messagesHaveMedia(msgs []Message) bool {
 	for _, m := range msgs {
@@ -326,7 +537,16 @@ func buildProviderRequest(c Candidate, req ChatRequest, stream, hasMedia bool) P
 		TopP:        req.TopP,
 		Stop:        req.Stop,
 		Stream:      stream,
+		Tools:       req.Tools,
+		ToolChoice:  req.ToolChoice,
 		HasMedia:    hasMedia,
+
+		Seed:             req.Seed,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		N:                req.N,
+		SafetySettings:   req.SafetySettings,
+		ResponseFormat:   req.ResponseFormat,
 	}
 }
 
@@ -359,15 +579,26 @@ func allFailedError(tried []CandidateError, total int) error {
 // --- Public API ---
 
 // ChatCompletion performs a synchronous chat completion with automatic routing.
-func (r *Router) ChatCompletion(ctx context.Context, req ChatRequest) (ChatResponse, error) {
+func (r *Router) ChatCompletion(ctx context.Context, req ChatRequest) (resp ChatResponse, err error) {
+	ctx, span := r.startCall(ctx, SpanChatCompletion, req.Model)
+	defer func() { endCall(span, resp.Routing, err) }()
+
+	if err := validateResponseFormat(req.ResponseFormat); err != nil {
+		return ChatResponse{}, err
+	}
+
 	estimatedTokens := EstimateTokens(req.Messages)
-	hasMedia := messagesHaveMedia(req.Messages)
+	needs := chatNeeds(req)
 
-	ordered, err := r.prepareRoute(ctx, req.Model, hasMedia)
+	ordered, err := r.prepareRoute(ctx, newRouteContext(req, needs), needs)
 	if err != nil {
 		return ChatResponse{}, err
 	}
 
+	if r.hedger != nil {
+		return r.hedgedChatCompletion(ctx, req, needs, ordered, estimatedTokens)
+	}
+
 	var tried []CandidateError
 	for attempt, c := range ordered {
 		// The caller's budget is gone: further steps have nothing to run in.
@@ -378,8 +609,10 @@ func (r *Router) ChatCompletion(ctx context.Context, req ChatRequest) (ChatRespo
 			return ChatResponse{}, err
 		}
 
-		reservation, skip := r.acquire(ctx, c, estimatedTokens)
+		actx, aspan := r.startAttempt(ctx, c.Provider.Name(), c.AccountID, c.Model, c.Free, attempt+1, estimatedTokens)
+		g, skip := r.acquire(actx, c, estimatedTokens)
 		if skip != nil {
+			endSpan(aspan, skip)
 			tried = append(tried, *skip)
 			continue
 		}
@@ -393,20 +626,23 @@ func (r *Router) ChatCompletion(ctx context.Context, req ChatRequest) (ChatRespo
 			EstimatedIn: estimatedTokens,
 		})
 
-		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		attemptCtx, cancel := actx, context.CancelFunc(func() {})
 		if budget := r.attemptBudget(c); budget > 0 {
-			attemptCtx, cancel = context.WithTimeout(ctx, budget)
+			attemptCtx, cancel = context.WithTimeout(actx, budget)
 		}
 
-		r.inflight.Inc(c.AccountID)
+		callCtx, callSpan := r.tracer.Start(attemptCtx, SpanProviderCall)
 		start := time.Now()
-		resp, err := c.Provider.ChatCompletion(attemptCtx, buildProviderRequest(c, req, false, hasMedia))
+		presp, err := c.Provider.ChatCompletion(callCtx, buildProviderRequest(c, req, false, needs.multimodal))
 		duration := time.Since(start)
-		r.inflight.Dec(c.AccountID)
+		endSpan(callSpan, err)
+		r.inflight.Release(c.AccountID, c.Model)
 		cancel()
+		r.rateLimiter.SyncFromProvider(actx, c.AccountID, c.Model, presp.RateLimits)
 
 		if err != nil {
-			fatal, ce := r.settleFailure(ctx, c, reservation, err, duration, attempt)
+			fatal, ce := r.settleFailure(actx, c, g, err, duration, attempt)
+			endSpan(aspan, &ce)
 			if fatal != nil {
 				return ChatResponse{}, fatal
 			}
@@ -414,48 +650,108 @@ func (r *Router) ChatCompletion(ctx context.Context, req ChatRequest) (ChatRespo
 			continue
 		}
 
-		r.settleSuccess(ctx, c, reservation, resp.Usage, duration)
-
-		return ChatResponse{
-			ID:    resp.ID,
-			Model: resp.Model,
-			Choices: []Choice{{
-				Index:        0,
-				Message:      Message{Role: "assistant", Content: resp.Content},
-				FinishReason: resp.FinishReason,
-			}},
-			Usage: resp.Usage,
-			Routing: RoutingInfo{
-				Provider:  c.Provider.Name(),
-				AccountID: c.AccountID,
-				Model:     c.Model,
-				Attempts:  attempt + 1,
-				Free:      c.Free,
-			},
-		}, nil
+		if rejectErr := checkResponseChoices(req.ResponseFormat, presp); rejectErr != nil {
+			ce := r.settleRejected(actx, c, g, presp.Usage, duration, rejectErr)
+			endSpan(aspan, &ce)
+			tried = append(tried, ce)
+			continue
+		}
+
+		r.settleSuccess(actx, c, g, presp.Usage, duration)
+		aspan.End()
+		return chatResponse(c, presp, attempt+1), nil
 	}
 
 	return ChatResponse{}, allFailedError(tried, len(ordered))
 }
 
-// ChatCompletionStream performs a streaming chat completion with automatic routing.
-func (r *Router) ChatCompletionStream(ctx context.Context, req ChatRequest) (*RouterStream, error) {
-	estimatedTokens := EstimateTokens(req.Messages)
-	hasMedia := messagesHaveMedia(req.Messages)
+// chatResponse builds the caller's answer from the candidate that served it.
+func chatResponse(c Candidate, resp ProviderResponse, attempts int) ChatResponse {
+	choices := resp.Choices
+	if len(choices) == 0 {
+		choices = []Choice{{
+			Index:        0,
+			Message:      Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls},
+			FinishReason: resp.FinishReason,
+		}}
+	}
+	return ChatResponse{
+		ID:      resp.ID,
+		Model:   resp.Model,
+		Choices: choices,
+		Usage:   resp.Usage,
+		Routing: RoutingInfo{
+			Provider:  c.Provider.Name(),
+			AccountID: c.AccountID,
+			Model:     c.Model,
+			Attempts:  attempts,
+			Free:      c.Free,
+		},
+	}
+}
+
+// ChatCompletionStream performs a streaming chat completion with automatic
+// routing. The call's span stays open until the stream is closed.
+func (r *Router) ChatCompletionStream(ctx context.Context, req ChatRequest) (_ *RouterStream, err error) {
+	ctx, span := r.startCall(ctx, SpanChatCompletionStream, req.Model)
+	defer func() {
+		if err != nil {
+			endCall(span, RoutingInfo{}, err)
+		}
+	}()
+
+	if err := validateResponseFormat(req.ResponseFormat); err != nil {
+		return nil, err
+	}
+	if req.N != nil && *req.N > 1 {
+		return nil, fmt.Errorf("%w: n = %d: a stream carries one completion", ErrInvalidRequest, *req.N)
+	}
+
+	needs := chatNeeds(req)
+	needs.stream = true
 
-	ordered, err := r.prepareRoute(ctx, req.Model, hasMedia)
+	ordered, err := r.prepareRoute(ctx, newRouteContext(req, needs), needs)
 	if err != nil {
 		return nil, err
 	}
 
-	var tried []CandidateError
-	for attempt, c := range ordered {
+	stream, tried, err := r.openStream(ctx, req, needs, ordered, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	stream.callSpan = span
+
+	if r.streamFailover != StreamFailoverOff {
+		stream.failover = &streamFailoverState{
+			mode:    r.streamFailover,
+			router:  r,
+			ctx:     ctx,
+			req:     req,
+			needs:   needs,
+			ordered: ordered,
+			tried:   tried,
+		}
+	}
+	return stream, nil
+}
+
+// openStream walks ordered from index start and returns a stream on the first
+// candidate that opens one. tried carries the failures recorded so far, so a
+// mid-stream failover reports the whole history when the rest of the ladder
+// fails too.
+func (r *Router) openStream(ctx context.Context, req ChatRequest, needs routeNeeds, ordered []Candidate, start int, tried []CandidateError) (*RouterStream, []CandidateError, error) {
+	estimatedTokens := EstimateTokens(req.Messages)
+
+	for attempt := start; attempt < len(ordered); attempt++ {
+		c := ordered[attempt]
 		if err := ctx.Err(); err != nil {
-			return nil, err
+			return nil, tried, err
 		}
 
-		reservation, skip := r.acquire(ctx, c, estimatedTokens)
+		actx, aspan := r.startAttempt(ctx, c.Provider.Name(), c.AccountID, c.Model, c.Free, attempt+1, estimatedTokens)
+		g, skip := r.acquire(actx, c, estimatedTokens)
 		if skip != nil {
+			endSpan(aspan, skip)
 			tried = append(tried, *skip)
 			continue
 		}
@@ -475,44 +771,60 @@ func (r *Router) ChatCompletionStream(ctx context.Context, req ChatRequest) (*Ro
 		// budget is for. So the attempt runs under a plain cancellable context
 		// with a watchdog; once the stream is open the watchdog is disarmed and
 		// the stream lives on the caller's deadline alone.
-		attemptCtx, cancel := context.WithCancel(ctx)
+		attemptCtx, cancel := context.WithCancel(actx)
 		var watchdog *time.Timer
 		if budget := r.attemptBudget(c); budget > 0 {
 			watchdog = time.AfterFunc(budget, cancel)
 		}
 
-		r.inflight.Inc(c.AccountID)
-		stream, err := c.Provider.ChatCompletionStream(attemptCtx, buildProviderRequest(c, req, true, hasMedia))
+		// The provider call span covers opening the stream; the generation
+		// that follows shows up as the attempt span's remaining time.
+		callCtx, callSpan := r.tracer.Start(attemptCtx, SpanProviderCall)
+		attemptStart := time.Now()
+		stream, err := c.Provider.ChatCompletionStream(callCtx, buildProviderRequest(c, req, true, needs.multimodal))
+		endSpan(callSpan, err)
 		if watchdog != nil {
 			watchdog.Stop()
 		}
 		if err != nil {
-			r.inflight.Dec(c.AccountID)
+			r.inflight.Release(c.AccountID, c.Model)
 			cancel()
-			fatal, ce := r.settleFailure(ctx, c, reservation, err, 0, attempt)
+			fatal, ce := r.settleFailure(actx, c, g, err, 0, attempt)
+			endSpan(aspan, &ce)
 			if fatal != nil {
-				return nil, fatal
+				return nil, tried, fatal
 			}
 			tried = append(tried, ce)
 			continue
 		}
+		if rep, ok := stream.(RateLimitReporter); ok {
+			r.rateLimiter.SyncFromProvider(actx, c.AccountID, c.Model, rep.RateLimits())
+		}
 
 		return &RouterStream{
 			inner:       stream,
-			reservation: reservation,
+			grant:       g,
 			quotaStore:  r.quotaStore,
+			rateLimiter: r.rateLimiter,
 			meter:       r.meter,
+			tracer:      r.tracer,
 			health:      r.health,
 			spend:       r.spend,
 			inflight:    r.inflight,
+			latency:     r.latency,
 			candidate:   c,
+			ctx:         actx,
+			span:        aspan,
 			attempts:    attempt + 1,
 			cancel:      cancel,
 			startTime:   time.Now(),
-		}, nil
+
+			attemptStart:  attemptStart,
+			recordFailure: r.recordFailureHealth,
+		}, tried, nil
 	}
 
-	return nil, allFailedError(tried, len(ordered))
+	return nil, tried, allFailedError(tried, len(ordered))
 }
 
 // collectConfigWarnings reports configuration that is accepted but does less
@@ -528,6 +840,10 @@ func collectConfigWarnings(cfg Config) []string {
 			continue
 		}
 		switch {
+		case acc.QuotaUnit == QuotaDollars && acc.DailyFreeDollars > 0:
+			warnings = append(warnings, fmt.Sprintf(
+				"account %q: quota_unit dollars without a cost rate — every request "+
+					"costs zero, so the dollar quota is never drawn down", acc.ID))
 		case acc.MaxDailySpend > 0:
 			warnings = append(warnings, fmt.Sprintf(
 				"account %q: max_daily_spend is set but no cost rate is configured — "+
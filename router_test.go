@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -268,6 +270,94 @@ func TestStreaming_Passthrough(t *testing.T) {
 	assert.Greater(t, len(chunks), 0)
 }
 
+// Every completion a provider generated for N > 1 reaches the caller; a
+// stream, which follows one completion, rejects the request instead.
+func TestChoices_ReturnedWhenNAboveOne(t *testing.T) {
+	var gotN *int
+	prov := mock.New(
+		mock.WithModels("test-model"),
+		mock.WithChoices(true),
+		mock.WithResponseFunc(func(req ir.ProviderRequest) (ir.ProviderResponse, error) {
+			gotN = req.N
+			return ir.ProviderResponse{
+				Content: "a",
+				Model:   "test-model",
+				Choices: []ir.Choice{
+					{Index: 0, Message: ir.Message{Role: "assistant", Content: "a"}, FinishReason: "stop"},
+					{Index: 1, Message: ir.Message{Role: "assistant", Content: "b"}, FinishReason: "stop"},
+				},
+			}, nil
+		}),
+	)
+	cfg := ir.Config{
+		DefaultModel: "test-model",
+		Accounts: []ir.AccountConfig{
+			{Provider: "mock", ID: "free-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+	r := newTestRouter(t, cfg, []ir.Provider{prov})
+
+	n := 2
+	req := ir.ChatRequest{Messages: []ir.Message{{Role: "user", Content: "hello"}}, N: &n}
+	resp, err := r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	require.NotNil(t, gotN)
+	assert.Equal(t, 2, *gotN)
+	require.Len(t, resp.Choices, 2)
+	assert.Equal(t, "a", resp.Choices[0].Message.Content)
+	assert.Equal(t, "b", resp.Choices[1].Message.Content)
+	assert.Equal(t, 1, resp.Choices[1].Index)
+
+	_, err = r.ChatCompletionStream(context.Background(), req)
+	assert.ErrorIs(t, err, ir.ErrInvalidRequest)
+}
+
+// A provider that generates one completion is skipped for N > 1 rather than
+// answering with fewer choices than asked for.
+func TestChoices_SkipsSingleCompletionProvider(t *testing.T) {
+	single := mock.New(mock.WithName("single"), mock.WithModels("test-model"))
+	multi := mock.New(
+		mock.WithName("multi"),
+		mock.WithModels("test-model"),
+		mock.WithChoices(true),
+		mock.WithResponseFunc(func(ir.ProviderRequest) (ir.ProviderResponse, error) {
+			choices := make([]ir.Choice, 3)
+			for i := range choices {
+				choices[i] = ir.Choice{Index: i, Message: ir.Message{Role: "assistant", Content: "ok"}, FinishReason: "stop"}
+			}
+			return ir.ProviderResponse{Content: "ok", Model: "test-model", Choices: choices}, nil
+		}),
+	)
+	n := 3
+	req := ir.ChatRequest{Messages: []ir.Message{{Role: "user", Content: "hello"}}, N: &n}
+
+	// The single-completion provider alone cannot serve the request.
+	r := newTestRouter(t, ir.Config{
+		DefaultModel: "test-model",
+		Accounts: []ir.AccountConfig{
+			{Provider: "single", ID: "single-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+		},
+	}, []ir.Provider{single})
+	_, err := r.ChatCompletion(context.Background(), req)
+	assert.ErrorIs(t, err, ir.ErrNoCandidates)
+	assert.Equal(t, int64(0), single.CallCount())
+
+	// Listed first, it is passed over for the provider that returns every
+	// completion.
+	r = newTestRouter(t, ir.Config{
+		DefaultModel: "test-model",
+		Accounts: []ir.AccountConfig{
+			{Provider: "single", ID: "single-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "multi", ID: "multi-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+		},
+	}, []ir.Provider{single, multi})
+	resp, err := r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Len(t, resp.Choices, 3)
+	assert.Equal(t, "multi", resp.Routing.Provider)
+	assert.Equal(t, int64(0), single.CallCount())
+}
+
 // Test 11: Provider error mapping (retryable vs fatal)
 func TestProviderErrorMapping(t *testing.T) {
 	failProv := mock.New(
@@ -597,7 +687,6 @@ func TestNormalizeCosts_NoOverwrite(t *testing.T) {
 
 func TestSpendTracker_RecordAndGet(t *testing.T) {
 	st := ir.NewSpendTracker()
-
 	st.RecordSpend("acc1", 1.5)
 	st.RecordSpend("acc1", 0.5)
 	st.RecordSpend("acc2", 3.0)
@@ -607,6 +696,25 @@ func TestSpendTracker_RecordAndGet(t *testing.T) {
 	assert.Equal(t, 0.0, st.GetSpend("acc3"))
 }
 
+// Store exposes the same counters through the SpendStore interface.
+func TestSpendTracker_Store(t *testing.T) {
+	st := ir.NewSpendTracker()
+	store := st.Store()
+
+	require.NoError(t, store.RecordSpend(context.Background(), "acc1", 1.5))
+	st.RecordSpend("acc1", 0.5)
+
+	assert.InDelta(t, 2.0, spendOf(t, store, "acc1"), 0.001)
+	assert.InDelta(t, 2.0, st.GetSpend("acc1"), 0.001)
+}
+
+func spendOf(t *testing.T, st ir.SpendStore, accountID string) float64 {
+	t.Helper()
+	dollars, err := st.GetSpend(context.Background(), accountID)
+	require.NoError(t, err)
+	return dollars
+}
+
 // --- New tests: max_daily_spend enforcement ---
 
 func TestMaxDailySpend_Enforcement(t *testing.T) {
@@ -651,6 +759,72 @@ func TestMaxDailySpend_Enforcement(t *testing.T) {
 	assert.ErrorIs(t, err, ir.ErrNoCandidates)
 }
 
+func spendCapConfig(maxDailySpend float64) ir.Config {
+	return ir.Config{
+		AllowPaid:    true,
+		DefaultModel: "test-model",
+		Accounts: []ir.AccountConfig{{
+			Provider:      "mock",
+			ID:            "paid-1",
+			PaidEnabled:   true,
+			CostPerToken:  0.001,
+			MaxDailySpend: maxDailySpend,
+			QuotaUnit:     ir.QuotaTokens,
+		}},
+	}
+}
+
+func TestMaxDailySpend_SharedStoreAcrossRouters(t *testing.T) {
+	// Two routers on one store stand in for two gateway replicas.
+	shared := ir.NewSpendTracker()
+	newReplica := func() *ir.Router {
+		r, err := ir.NewRouter(declareLadder(spendCapConfig(0.01)),
+			[]ir.Provider{mock.New(mock.WithModels("test-model"))},
+			ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+			ir.WithSpendStore(shared.Store()),
+		)
+		require.NoError(t, err)
+		return r
+	}
+	a, b := newReplica(), newReplica()
+	req := ir.ChatRequest{Messages: []ir.Message{{Role: "user", Content: "hello"}}}
+
+	_, err := a.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+
+	// $0.03 spent through a; b sees the cap as reached.
+	_, err = b.ChatCompletion(context.Background(), req)
+	assert.ErrorIs(t, err, ir.ErrNoCandidates)
+}
+
+func TestMaxDailySpend_UnreadableStoreFailsClosed(t *testing.T) {
+	req := ir.ChatRequest{Messages: []ir.Message{{Role: "user", Content: "hello"}}}
+
+	capped, err := ir.NewRouter(declareLadder(spendCapConfig(0.01)),
+		[]ir.Provider{mock.New(mock.WithModels("test-model"))},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithSpendStore(brokenSpendStore{}),
+	)
+	require.NoError(t, err)
+	_, err = capped.ChatCompletion(context.Background(), req)
+	assert.ErrorIs(t, err, ir.ErrNoCandidates, "a cap that cannot be checked is treated as reached")
+
+	// Without a cap the store is only written to; a failed write is reported
+	// to the meter, not to the caller.
+	spy := &meterSpy{}
+	uncapped, err := ir.NewRouter(declareLadder(spendCapConfig(0)),
+		[]ir.Provider{mock.New(mock.WithModels("test-model"))},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithSpendStore(brokenSpendStore{}),
+		ir.WithMeter(spy),
+	)
+	require.NoError(t, err)
+	_, err = uncapped.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.False(t, spy.lastResult.Success)
+	assert.ErrorContains(t, spy.lastResult.Error, "spend record failed")
+}
+
 // --- New tests: Separate input/output pricing ---
 
 func TestSeparateInputOutputPricing(t *testing.T) {
@@ -715,6 +889,221 @@ func TestHealthTracker_CustomConfig(t *testing.T) {
 	assert.Equal(t, ir.HealthHealthy, ht.GetHealth("acc1"))
 }
 
+// --- Provider-level circuit breaker ---
+
+func TestHealthTracker_ProviderCircuit(t *testing.T) {
+	ht := ir.NewHealthTrackerWithConfig(ir.HealthConfig{
+		FailureThreshold:         3,
+		FailureWindow:            time.Second,
+		UnhealthyPeriod:          50 * time.Millisecond,
+		ProviderFailureThreshold: 2,
+	})
+
+	// Repeated failures of one account are that account's problem.
+	ht.RecordProviderFailure("gemini", "acc-1")
+	ht.RecordProviderFailure("gemini", "acc-1")
+	assert.Equal(t, ir.HealthHealthy, ht.GetProviderHealth("gemini"))
+
+	ht.RecordProviderFailure("gemini", "acc-2")
+	assert.Equal(t, ir.HealthUnhealthy, ht.GetProviderHealth("gemini"))
+	assert.Equal(t, ir.HealthHealthy, ht.GetProviderHealth("other"))
+
+	// A failed probe trips it again straight away.
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, ir.HealthHalfOpen, ht.GetProviderHealth("gemini"))
+	ht.RecordProviderFailure("gemini", "acc-3")
+	assert.Equal(t, ir.HealthUnhealthy, ht.GetProviderHealth("gemini"))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, ir.HealthHalfOpen, ht.GetProviderHealth("gemini"))
+	ht.RecordProviderSuccess("gemini")
+	assert.Equal(t, ir.HealthHealthy, ht.GetProviderHealth("gemini"))
+}
+
+func TestHealthTracker_ProviderCircuitDisabled(t *testing.T) {
+	ht := ir.NewHealthTrackerWithConfig(ir.HealthConfig{
+		FailureThreshold: 3,
+		FailureWindow:    time.Second,
+		UnhealthyPeriod:  time.Second,
+	})
+	for _, acc := range []string{"a", "b", "c"} {
+		ht.RecordProviderFailure("gemini", acc)
+	}
+	assert.Equal(t, ir.HealthHealthy, ht.GetProviderHealth("gemini"))
+}
+
+func providerOutageConfig() ir.Config {
+	return ir.Config{
+		DefaultModel: "m",
+		Accounts: []ir.AccountConfig{
+			{Provider: "down", ID: "down-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "down", ID: "down-2", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "down", ID: "down-3", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "up", ID: "up-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+}
+
+func TestProviderCircuit_SkipsAllAccountsOfTrippedProvider(t *testing.T) {
+	down := mock.New(mock.WithName("down"), mock.WithModels("m"), mock.WithError(ir.ErrProviderUnavailable))
+	up := mock.New(mock.WithName("up"), mock.WithModels("m"))
+	r := newTestRouter(t, providerOutageConfig(), []ir.Provider{down, up})
+	req := ir.ChatRequest{Messages: []ir.Message{{Role: "user", Content: "hello"}}}
+
+	// The first request still walks the outage, tripping the provider.
+	resp, err := r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "up-1", resp.Routing.AccountID)
+	assert.Equal(t, 4, resp.Routing.Attempts)
+
+	// From then on no account of it is attempted, not even down-3 whose own
+	// breaker never saw a failure.
+	calls := down.CallCount()
+	resp, err = r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "up-1", resp.Routing.AccountID)
+	assert.Equal(t, 1, resp.Routing.Attempts)
+	assert.Equal(t, calls, down.CallCount())
+}
+
+func TestProviderCircuit_RateLimitsDoNotTrip(t *testing.T) {
+	down := mock.New(mock.WithName("down"), mock.WithModels("m"), mock.WithError(ir.ErrRateLimited))
+	up := mock.New(mock.WithName("up"), mock.WithModels("m"))
+	r := newTestRouter(t, providerOutageConfig(), []ir.Provider{down, up})
+	req := ir.ChatRequest{Messages: []ir.Message{{Role: "user", Content: "hello"}}}
+
+	for range 2 {
+		resp, err := r.ChatCompletion(context.Background(), req)
+		require.NoError(t, err)
+		// 429s put the accounts in cooldown (skipped on the second pass)
+		// and never take the provider out as a whole.
+		assert.Equal(t, 4, resp.Routing.Attempts)
+	}
+}
+
+// --- Rate limit header feedback ---
+
+func TestRateLimitHeaders_SkipExhaustedAccount(t *testing.T) {
+	zero := int64(0)
+	exhausted := mock.New(mock.WithName("cerebras"), mock.WithModels("m"),
+		mock.WithResponseFunc(func(ir.ProviderRequest) (ir.ProviderResponse, error) {
+			return ir.ProviderResponse{
+				Content:    "last one",
+				RateLimits: &ir.RateLimitInfo{RemainingRequests: &zero, ResetRequests: time.Hour},
+			}, nil
+		}))
+	backup := mock.New(mock.WithName("backup"), mock.WithModels("m"))
+
+	cfg := ir.Config{
+		DefaultModel: "m",
+		Accounts: []ir.AccountConfig{
+			{Provider: "cerebras", ID: "cerebras-free", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "backup", ID: "backup-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+	r := newTestRouter(t, cfg, []ir.Provider{exhausted, backup})
+	req := ir.ChatRequest{Messages: []ir.Message{{Role: "user", Content: "hello"}}}
+
+	resp, err := r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "cerebras-free", resp.Routing.AccountID)
+
+	// The provider said the budget is gone: no attempt is spent on it.
+	resp, err = r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "backup-1", resp.Routing.AccountID)
+	assert.Equal(t, int64(1), exhausted.CallCount())
+}
+
+// --- 429 cooldown ---
+
+func cooldownConfig() ir.Config {
+	return ir.Config{
+		DefaultModel: "m",
+		Accounts: []ir.AccountConfig{
+			{Provider: "limited", ID: "limited-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "backup", ID: "backup-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+}
+
+func TestRateLimitCooldown_HonoursRetryAfterWithoutHealthPenalty(t *testing.T) {
+	var limited atomic.Bool
+	limited.Store(true)
+	prov := mock.New(mock.WithName("limited"), mock.WithModels("m"),
+		mock.WithResponseFunc(func(ir.ProviderRequest) (ir.ProviderResponse, error) {
+			if limited.Load() {
+				return ir.ProviderResponse{}, &ir.RateLimitError{RetryAfter: 80 * time.Millisecond}
+			}
+			return ir.ProviderResponse{Content: "ok"}, nil
+		}))
+	backup := mock.New(mock.WithName("backup"), mock.WithModels("m"))
+	health := ir.NewHealthTracker()
+	r, err := ir.NewRouter(declareLadder(cooldownConfig()), []ir.Provider{prov, backup},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithHealthTracker(health),
+	)
+	require.NoError(t, err)
+	req := ir.ChatRequest{Messages: []ir.Message{{Role: "user", Content: "hello"}}}
+
+	resp, err := r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "backup-1", resp.Routing.AccountID)
+	assert.Equal(t, ir.HealthHealthy, health.GetHealth("limited-1"), "a 429 is not a fault")
+
+	// Within Retry-After the account is skipped without a call.
+	for range 3 {
+		_, err = r.ChatCompletion(context.Background(), req)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(1), prov.CallCount())
+	assert.Equal(t, ir.HealthHealthy, health.GetHealth("limited-1"))
+
+	// Once it has passed, the account is first in line again.
+	limited.Store(false)
+	time.Sleep(100 * time.Millisecond)
+	resp, err = r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "limited-1", resp.Routing.AccountID)
+}
+
+func TestRateLimitCooldown_DefaultAndSkipError(t *testing.T) {
+	prov := mock.New(mock.WithName("limited"), mock.WithModels("m"), mock.WithError(ir.ErrRateLimited))
+	cfg := ir.Config{
+		DefaultModel: "m",
+		Accounts: []ir.AccountConfig{
+			{Provider: "limited", ID: "limited-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+	r, err := ir.NewRouter(declareLadder(cfg), []ir.Provider{prov},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithRateLimitCooldown(time.Minute),
+	)
+	require.NoError(t, err)
+	req := ir.ChatRequest{Messages: []ir.Message{{Role: "user", Content: "hello"}}}
+
+	_, err = r.ChatCompletion(context.Background(), req)
+	require.ErrorIs(t, err, ir.ErrAllFailed)
+
+	// A 429 without Retry-After falls back to the configured cooldown, and
+	// the skip tells the caller how long is left.
+	_, err = r.ChatCompletion(context.Background(), req)
+	var routerErr *ir.RouterError
+	require.ErrorAs(t, err, &routerErr)
+	require.Len(t, routerErr.Tried, 1)
+	var rle *ir.RateLimitError
+	require.ErrorAs(t, routerErr.Tried[0].Err, &rle)
+	assert.InDelta(t, float64(time.Minute), float64(rle.RetryAfter), float64(time.Second))
+	assert.Equal(t, int64(1), prov.CallCount())
+}
+
+func TestRateLimitError(t *testing.T) {
+	err := error(&ir.RateLimitError{RetryAfter: 30 * time.Second, Detail: "slow down"})
+	assert.ErrorIs(t, err, ir.ErrRateLimited)
+	assert.True(t, ir.IsRetryable(err))
+	assert.Equal(t, "inferrouter: rate limited by provider (retry after 30s): slow down", err.Error())
+}
+
 // --- New tests: BlendedCost ---
 
 func TestBlendedCost(t *testing.T) {
@@ -1045,6 +1434,17 @@ type meterSpy struct {
 func (m *meterSpy) OnRoute(e ir.RouteEvent)   { m.lastRoute = e }
 func (m *meterSpy) OnResult(e ir.ResultEvent) { m.lastResult = e }
 
+// brokenSpendStore is a SpendStore whose backend is unreachable.
+type brokenSpendStore struct{}
+
+func (brokenSpendStore) RecordSpend(context.Context, string, float64) error {
+	return errors.New("spend backend down")
+}
+
+func (brokenSpendStore) GetSpend(context.Context, string) (float64, error) {
+	return 0, errors.New("spend backend down")
+}
+
 type failingCommitQuotaStore struct{}
 
 func (f *failingCommitQuotaStore) Reserve(_ context.Context, accountID string, amount int64, unit ir.QuotaUnit, _ string) (ir.Reservation, error) {
@@ -1253,6 +1653,58 @@ func TestRPM_ModelLimits_NegativeRejected(t *testing.T) {
 	assert.Contains(t, err.Error(), "model_limits")
 }
 
+func TestTokenAndConcurrencyLimits_NegativeRejected(t *testing.T) {
+	for name, limits := range map[string]ir.Limits{
+		"tpm":             {TPM: -1},
+		"tpd":             {TPD: -1},
+		"max_concurrency": {MaxConcurrency: -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := ir.Config{
+				Accounts: []ir.AccountConfig{{
+					Provider:    "mock",
+					ID:          "acc1",
+					DailyFree:   1000,
+					QuotaUnit:   ir.QuotaTokens,
+					ModelLimits: map[string]ir.Limits{"model-a": limits},
+				}},
+			}
+			err := cfg.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "model_limits")
+		})
+	}
+}
+
+func TestTPM_CorrectedByActualUsage(t *testing.T) {
+	// The mock reports 30 tokens; "hello" is estimated at 8.
+	mockProv := mock.New(mock.WithModels("test-model"))
+
+	cfg := ir.Config{
+		DefaultModel: "test-model",
+		Accounts: []ir.AccountConfig{
+			{
+				Provider: "mock", ID: "limited", DailyFree: 1000, QuotaUnit: ir.QuotaTokens,
+				ModelLimits: map[string]ir.Limits{"test-model": {TPM: 35}},
+			},
+			{Provider: "mock", ID: "backup", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+
+	r := newTestRouter(t, cfg, []ir.Provider{mockProv})
+	req := ir.ChatRequest{Messages: []ir.Message{{Role: "user", Content: "hello"}}}
+
+	resp1, err := r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "limited", resp1.Routing.AccountID)
+
+	// On the estimate alone (8+8) the second request would fit; with the
+	// first one corrected to 30 it does not.
+	resp2, err := r.ChatCompletion(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "backup", resp2.Routing.AccountID)
+}
+
 func TestRPM_StreamingSameAsSync(t *testing.T) {
 	mockProv := mock.New(mock.WithModels("test-model"))
 
@@ -1290,3 +1742,119 @@ func TestRPM_StreamingSameAsSync(t *testing.T) {
 	require.NoError(t, err)
 	stream2.Close()
 }
+
+func TestAccountStatuses(t *testing.T) {
+	cfg := ir.Config{
+		DefaultModel: "test-model",
+		AllowPaid:    true,
+		Accounts: []ir.AccountConfig{
+			{Provider: "mock", ID: "free-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "mock", ID: "paid-1", PaidEnabled: true, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+	health := ir.NewHealthTracker()
+	r, err := ir.NewRouter(declareLadder(cfg), []ir.Provider{mock.New(mock.WithModels("test-model"))},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithHealthTracker(health),
+	)
+	require.NoError(t, err)
+
+	_, err = r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+	for range 3 {
+		health.RecordFailure("paid-1")
+	}
+
+	statuses := r.AccountStatuses(context.Background())
+	require.Len(t, statuses, 2)
+
+	assert.Equal(t, "free-1", statuses[0].AccountID)
+	assert.True(t, statuses[0].HasQuota)
+	assert.EqualValues(t, 1000-30, statuses[0].Remaining)
+	assert.Equal(t, ir.HealthHealthy, statuses[0].Health)
+
+	assert.Equal(t, "paid-1", statuses[1].AccountID)
+	assert.False(t, statuses[1].HasQuota)
+	assert.Equal(t, ir.HealthUnhealthy, statuses[1].Health)
+	assert.Zero(t, statuses[1].Inflight)
+}
+
+// contextPolicy is a RequestPolicy that records the RouteContext it was
+// given and routes high-priority requests to paid candidates first.
+type contextPolicy struct {
+	seen []ir.RouteContext
+}
+
+func (p *contextPolicy) Select(candidates []ir.Candidate) []ir.Candidate {
+	panic("Select called on a RequestPolicy")
+}
+
+func (p *contextPolicy) SelectRequest(rc ir.RouteContext, candidates []ir.Candidate) []ir.Candidate {
+	p.seen = append(p.seen, rc)
+	if rc.Labels["tenant"] == "blocked" {
+		return nil
+	}
+	out := append([]ir.Candidate(nil), candidates...)
+	if rc.Priority > 0 {
+		sort.SliceStable(out, func(i, j int) bool { return !out[i].Free && out[j].Free })
+	}
+	return out
+}
+
+func TestRequestPolicy(t *testing.T) {
+	cfg := declareLadder(ir.Config{
+		DefaultModel: "test-model",
+		AllowPaid:    true,
+		Accounts: []ir.AccountConfig{
+			{Provider: "mock", ID: "free-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "mock", ID: "paid-1", PaidEnabled: true, QuotaUnit: ir.QuotaTokens},
+		},
+	})
+	pol := &contextPolicy{}
+	r, err := ir.NewRouter(cfg, []ir.Provider{mock.New(mock.WithModels("test-model"), mock.WithMultimodal(true))},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithPolicy(pol))
+	require.NoError(t, err)
+
+	maxTokens := 256
+	resp, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages:  []ir.Message{{Role: "user", Content: "hello"}},
+		MaxTokens: &maxTokens,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "free-1", resp.Routing.AccountID)
+
+	resp, err = r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Parts: []ir.Part{
+			{Type: ir.PartText, Text: "what is this"},
+			{Type: ir.PartImage, MIMEType: "image/png", Data: []byte{1}},
+		}}},
+		Priority: 1,
+		Labels:   map[string]string{"tenant": "acme"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "paid-1", resp.Routing.AccountID, "priority request goes to the paid key")
+
+	stream, err := r.ChatCompletionStream(context.Background(), ir.ChatRequest{
+		Model:    "test-model",
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+
+	require.Len(t, pol.seen, 3)
+	assert.Equal(t, ir.RouteContext{EstimatedTokens: 8, MaxTokens: 256}, pol.seen[0])
+	assert.True(t, pol.seen[1].HasMedia)
+	assert.Equal(t, 1, pol.seen[1].Priority)
+	assert.Equal(t, "acme", pol.seen[1].Labels["tenant"])
+	assert.True(t, pol.seen[2].Stream)
+	assert.Equal(t, "test-model", pol.seen[2].Model)
+
+	_, err = r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+		Labels:   map[string]string{"tenant": "blocked"},
+	})
+	assert.ErrorIs(t, err, ir.ErrNoCandidates)
+}
@@ -0,0 +1,218 @@
+package inferrouter
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// validateSchema checks doc (decoded with encoding/json into any) against a
+// JSON Schema (decoded the same way). It implements the subset structured
+// output relies on: type, enum, const, properties, required,
+// additionalProperties, items, minItems/maxItems, minLength/maxLength,
+// minimum/maximum, anyOf, oneOf and allOf. Keywords outside that subset are
+// ignored, so an unsupported constraint can only make the check more lenient,
+// never reject a conforming answer.
+//
+// path is a JSONPath-like location used in error messages ("$.items[2].id").
+func validateSchema(schema, doc any, path string) error {
+	s, ok := schema.(map[string]any)
+	if !ok {
+		// true/false schemas: false rejects everything, true accepts.
+		if b, isBool := schema.(bool); isBool && !b {
+			return fmt.Errorf("%s: not allowed", path)
+		}
+		return nil
+	}
+
+	if t, ok := s["type"]; ok && !matchesType(t, doc) {
+		return fmt.Errorf("%s: want type %v, got %s", path, t, jsonTypeOf(doc))
+	}
+
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, v := range enum {
+			if reflect.DeepEqual(v, doc) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value not in enum", path)
+		}
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, doc) {
+		return fmt.Errorf("%s: value does not equal const", path)
+	}
+
+	switch v := doc.(type) {
+	case map[string]any:
+		if err := validateObject(s, v, path); err != nil {
+			return err
+		}
+	case []any:
+		if err := validateArray(s, v, path); err != nil {
+			return err
+		}
+	case string:
+		n := float64(len([]rune(v)))
+		if min, ok := s["minLength"].(float64); ok && n < min {
+			return fmt.Errorf("%s: shorter than minLength %v", path, min)
+		}
+		if max, ok := s["maxLength"].(float64); ok && n > max {
+			return fmt.Errorf("%s: longer than maxLength %v", path, max)
+		}
+	case float64:
+		if min, ok := s["minimum"].(float64); ok && v < min {
+			return fmt.Errorf("%s: %v below minimum %v", path, v, min)
+		}
+		if max, ok := s["maximum"].(float64); ok && v > max {
+			return fmt.Errorf("%s: %v above maximum %v", path, v, max)
+		}
+	}
+
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			if err := validateSchema(sub, doc, path); err != nil {
+				return err
+			}
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if validateSchema(sub, doc, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: matches none of anyOf", path)
+		}
+	}
+	if oneOf, ok := s["oneOf"].([]any); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if validateSchema(sub, doc, path) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s: matches %d of oneOf, want exactly 1", path, matches)
+		}
+	}
+	return nil
+}
+
+func validateObject(s map[string]any, obj map[string]any, path string) error {
+	if req, ok := s["required"].([]any); ok {
+		for _, name := range req {
+			key, _ := name.(string)
+			if _, present := obj[key]; !present {
+				return fmt.Errorf("%s: missing required property %q", path, key)
+			}
+		}
+	}
+
+	props, _ := s["properties"].(map[string]any)
+	for key, val := range obj {
+		sub := path + "." + key
+		if ps, ok := props[key]; ok {
+			if err := validateSchema(ps, val, sub); err != nil {
+				return err
+			}
+			continue
+		}
+		switch ap := s["additionalProperties"].(type) {
+		case bool:
+			if !ap {
+				return fmt.Errorf("%s: additional property not allowed", sub)
+			}
+		case map[string]any:
+			if err := validateSchema(ap, val, sub); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateArray(s map[string]any, arr []any, path string) error {
+	n := float64(len(arr))
+	if min, ok := s["minItems"].(float64); ok && n < min {
+		return fmt.Errorf("%s: fewer than minItems %v", path, min)
+	}
+	if max, ok := s["maxItems"].(float64); ok && n > max {
+		return fmt.Errorf("%s: more than maxItems %v", path, max)
+	}
+	if items, ok := s["items"]; ok {
+		for i, v := range arr {
+			if err := validateSchema(items, v, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether doc satisfies a "type" keyword, which is
+// either a single name or a list of names.
+func matchesType(t any, doc any) bool {
+	switch tv := t.(type) {
+	case string:
+		return matchesTypeName(tv, doc)
+	case []any:
+		for _, name := range tv {
+			if n, ok := name.(string); ok && matchesTypeName(n, doc) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, doc any) bool {
+	switch strings.ToLower(name) {
+	case "object":
+		_, ok := doc.(map[string]any)
+		return ok
+	case "array":
+		_, ok := doc.([]any)
+		return ok
+	case "string":
+		_, ok := doc.(string)
+		return ok
+	case "number":
+		_, ok := doc.(float64)
+		return ok
+	case "integer":
+		f, ok := doc.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := doc.(bool)
+		return ok
+	case "null":
+		return doc == nil
+	}
+	return true
+}
+
+func jsonTypeOf(doc any) string {
+	switch doc.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", doc)
+}
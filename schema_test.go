@@ -0,0 +1,107 @@
+package inferrouter
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"properties": {
+			"name":  {"type": "string", "minLength": 1},
+			"age":   {"type": "integer", "minimum": 0},
+			"tags":  {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"kind":  {"enum": ["a", "b"]},
+			"extra": {"anyOf": [{"type": "null"}, {"type": "number"}]}
+		},
+		"required": ["name"],
+		"additionalProperties": false
+	}`
+
+	tests := []struct {
+		name string
+		doc  string
+		ok   bool
+	}{
+		{"minimal", `{"name":"x"}`, true},
+		{"full", `{"name":"x","age":3,"tags":["a"],"kind":"b","extra":null}`, true},
+		{"missing required", `{"age":3}`, false},
+		{"wrong type", `{"name":5}`, false},
+		{"empty string", `{"name":""}`, false},
+		{"fractional integer", `{"name":"x","age":1.5}`, false},
+		{"below minimum", `{"name":"x","age":-1}`, false},
+		{"too many items", `{"name":"x","tags":["a","b","c"]}`, false},
+		{"item type", `{"name":"x","tags":[1]}`, false},
+		{"enum", `{"name":"x","kind":"c"}`, false},
+		{"anyOf", `{"name":"x","extra":"s"}`, false},
+		{"additional property", `{"name":"x","other":1}`, false},
+		{"not an object", `[]`, false},
+	}
+
+	var s any
+	if err := json.Unmarshal([]byte(schema), &s); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc any
+			if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			err := validateSchema(s, doc, "$")
+			if tt.ok && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("expected a validation error")
+			}
+		})
+	}
+}
+
+func TestCheckResponseFormat(t *testing.T) {
+	jsonMode := &ResponseFormat{Type: ResponseFormatJSON, Validate: true}
+	if err := checkResponseFormat(jsonMode, `{"a":1}`); err != nil {
+		t.Errorf("valid JSON: %v", err)
+	}
+	if err := checkResponseFormat(jsonMode, "plain text"); !errors.Is(err, ErrResponseFormatMismatch) {
+		t.Errorf("invalid JSON: got %v, want ErrResponseFormatMismatch", err)
+	}
+
+	// Validation is opt-in.
+	if err := checkResponseFormat(&ResponseFormat{Type: ResponseFormatJSON}, "plain text"); err != nil {
+		t.Errorf("validation off: %v", err)
+	}
+	if err := checkResponseFormat(nil, "plain text"); err != nil {
+		t.Errorf("nil format: %v", err)
+	}
+}
+
+func TestValidateResponseFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		rf   *ResponseFormat
+		ok   bool
+	}{
+		{"nil", nil, true},
+		{"text", &ResponseFormat{Type: ResponseFormatText}, true},
+		{"json", &ResponseFormat{Type: ResponseFormatJSON}, true},
+		{"schema", &ResponseFormat{Type: ResponseFormatJSONSchema, Schema: json.RawMessage(`{"type":"object"}`)}, true},
+		{"schema missing", &ResponseFormat{Type: ResponseFormatJSONSchema}, false},
+		{"schema broken", &ResponseFormat{Type: ResponseFormatJSONSchema, Schema: json.RawMessage(`{`)}, false},
+		{"unknown type", &ResponseFormat{Type: "yaml"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateResponseFormat(tt.rf)
+			if tt.ok && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidRequest) {
+				t.Errorf("got %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
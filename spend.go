@@ -1,10 +1,27 @@
 package inferrouter
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"time"
 )
 
+// SpendStore records per-account dollar spend for the current UTC day. The
+// router reads it when building candidates, to enforce MaxDailySpend, and
+// adds to it whenever a paid attempt settles.
+//
+// SpendTracker.Store adapts the in-process tracker: it resets on restart and
+// is not shared between replicas, so a cap is per process. quota/redis and
+// quota/postgres provide stores that make MaxDailySpend a real budget.
+type SpendStore interface {
+	// RecordSpend adds dollars to the account's spend for today.
+	RecordSpend(ctx context.Context, accountID string, dollars float64) error
+
+	// GetSpend returns the account's spend for today.
+	GetSpend(ctx context.Context, accountID string) (float64, error)
+}
+
 // SpendTracker tracks per-account dollar spend with daily reset.
 type SpendTracker struct {
 	mu        sync.Mutex
@@ -53,6 +70,44 @@ func (s *SpendTracker) GetSpend(accountID string) float64 {
 	return as.amount
 }
 
+// Store returns s as a SpendStore. It never fails; ctx is ignored.
+func (s *SpendTracker) Store() SpendStore { return trackerStore{s} }
+
+// trackerStore adapts SpendTracker to SpendStore.
+type trackerStore struct{ t *SpendTracker }
+
+func (s trackerStore) RecordSpend(_ context.Context, accountID string, dollars float64) error {
+	s.t.RecordSpend(accountID, dollars)
+	return nil
+}
+
+func (s trackerStore) GetSpend(_ context.Context, accountID string) (float64, error) {
+	return s.t.GetSpend(accountID), nil
+}
+
+// currentSpend reads the account's spend for the candidate list. A store
+// that cannot be read fails closed for a capped account: a budget that
+// cannot be checked is treated as spent. Uncapped accounts are unaffected.
+func currentSpend(ctx context.Context, spend SpendStore, acc AccountConfig) float64 {
+	dollars, err := spend.GetSpend(ctx, acc.ID)
+	if err != nil {
+		return acc.MaxDailySpend
+	}
+	return dollars
+}
+
+// recordSpend adds a settled attempt's cost to the store; zero costs (free
+// accounts, unpriced accounts) are not written.
+func recordSpend(ctx context.Context, spend SpendStore, accountID string, dollars float64) error {
+	if dollars <= 0 {
+		return nil
+	}
+	if err := spend.RecordSpend(ctx, accountID, dollars); err != nil {
+		return fmt.Errorf("spend record failed: %w", err)
+	}
+	return nil
+}
+
 // checkReset resets all spend if the UTC date has changed. Must be called with lock held.
 func (s *SpendTracker) checkReset() {
 	today := time.Now().UTC().Format("2006-01-02")
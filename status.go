@@ -0,0 +1,46 @@
+package inferrouter
+
+import "context"
+
+// AccountStatus is a point-in-time view of one configured account: what the
+// router would see if it built candidates for it now. It is meant for
+// metrics and health endpoints, which read state rather than events.
+type AccountStatus struct {
+	Provider  string
+	AccountID string
+
+	// QuotaUnit and Remaining describe the local free allowance. HasQuota is
+	// false for accounts without one (daily_free unset), whose Remaining is
+	// meaningless; RemainingErr is set when the QuotaStore could not answer.
+	QuotaUnit    QuotaUnit
+	HasQuota     bool
+	Remaining    int64
+	RemainingErr error
+
+	Inflight       int64
+	Health         HealthState
+	ProviderHealth HealthState
+}
+
+// AccountStatuses reports every configured account in config order. It reads
+// the QuotaStore once per account with a free allowance, so a scrape against
+// a remote store costs one round trip per account.
+func (r *Router) AccountStatuses(ctx context.Context) []AccountStatus {
+	statuses := make([]AccountStatus, 0, len(r.cfg.Accounts))
+	for _, acc := range r.cfg.Accounts {
+		st := AccountStatus{
+			Provider:       acc.Provider,
+			AccountID:      acc.ID,
+			QuotaUnit:      acc.QuotaUnit,
+			HasQuota:       acc.dailyQuota() > 0,
+			Inflight:       r.inflight.Get(acc.ID),
+			Health:         r.health.GetHealth(acc.ID),
+			ProviderHealth: r.health.GetProviderHealth(acc.Provider),
+		}
+		if st.HasQuota {
+			st.Remaining, st.RemainingErr = r.quotaStore.Remaining(ctx, acc.ID)
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses
+}
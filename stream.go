@@ -5,34 +5,109 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 )
 
+// StreamFailover selects what a RouterStream does when the provider stream
+// fails after it was opened.
+type StreamFailover int
+
+const (
+	// StreamFailoverOff ends the response with the provider's error. Default.
+	StreamFailoverOff StreamFailover = iota
+
+	// StreamFailoverBeforeContent moves to the next candidate only while
+	// nothing but role markers has been delivered to the caller. Once text
+	// or a tool call went out, the error ends the response as with Off.
+	StreamFailoverBeforeContent
+
+	// StreamFailoverResume also fails over after text was delivered: the next
+	// candidate receives the original messages plus the partial answer as an
+	// assistant turn and is asked to continue from where it stopped. The
+	// caller keeps reading one stream; the seam is only as clean as the
+	// continuing model makes it. A stream that already emitted tool calls is
+	// never resumed.
+	StreamFailoverResume
+)
+
+// resumePrompt follows the partial answer when StreamFailoverResume hands the
+// response to the next candidate.
+const resumePrompt = "Your previous answer was cut off. Continue it exactly where it stopped, " +
+	"without repeating any of the text above and without commentary."
+
 // RouterStream wraps a ProviderStream with quota commit on close.
 type RouterStream struct {
 	inner       ProviderStream
-	reservation Reservation
+	grant       grant
 	quotaStore  QuotaStore
+	rateLimiter RateLimitStore
 	meter       Meter
+	tracer      Tracer
 	health      *HealthTracker
-	spend       *SpendTracker
+	spend       SpendStore
 	inflight    *InflightTracker // nil-safe; decremented once on Close
+	latency     *LatencyTracker  // nil-safe
 	candidate   Candidate
 	attempts    int
-	startTime   time.Time
-	totalUsage  Usage
-	closed      bool
-	streamErr   error // first error encountered during streaming
+
+	// ctx is the caller's context, carrying the current attempt's span. It
+	// is only consulted when settling, to tell a caller that went away from a
+	// provider that failed.
+	ctx context.Context
+
+	// span is the current candidate's attempt span, ended when it settles;
+	// callSpan is the ChatCompletionStream span, ended on Close.
+	span     Span
+	callSpan Span
+
+	// recordFailure is the router's recordFailureHealth: health for faults,
+	// a cooldown for 429s.
+	recordFailure func(ctx context.Context, provider, accountID, model string, err error)
+
+	startTime  time.Time
+	totalUsage Usage
+	closed     bool
+	streamErr  error // first error encountered during streaming
+
+	// attemptStart is when the provider was asked to open the stream; ttft
+	// is the delay from it to the first chunk carrying content.
+	attemptStart time.Time
+	ttft         time.Duration
+
+	// settled is set once the current candidate's reservation has been
+	// committed or rolled back — on Close, or when failover gives it up.
+	settled bool
 
 	// cancel releases the context the provider request was opened with. It is
 	// held for the life of the stream and called on Close — cancelling it any
 	// earlier would tear down the response body mid-generation.
 	cancel context.CancelFunc
+
+	// failover is nil unless the router was built WithStreamFailover.
+	failover *streamFailoverState
 }
 
-// Routing reports which step of the ladder opened this stream. The unary path
-// returns the same information on ChatResponse; without it here, callers were
-// left inferring the provider from chunk metadata after the fact.
+// streamFailoverState is what a RouterStream needs to reopen the request on
+// the rest of the ladder.
+type streamFailoverState struct {
+	mode    StreamFailover
+	router  *Router
+	ctx     context.Context
+	req     ChatRequest
+	needs   routeNeeds
+	ordered []Candidate
+	tried   []CandidateError
+
+	partial   strings.Builder // text delivered to the caller, across candidates
+	toolCalls bool            // a tool-call delta was delivered
+}
+
+// Routing reports which step of the ladder is serving this stream. The unary
+// path returns the same information on ChatResponse; without it here, callers
+// were left inferring the provider from chunk metadata after the fact. After a
+// mid-stream failover it names the candidate that took over, and Attempts
+// counts every candidate tried.
 func (s *RouterStream) Routing() RoutingInfo {
 	return RoutingInfo{
 		Provider:  s.candidate.Provider.Name(),
@@ -45,20 +120,136 @@ func (s *RouterStream) Routing() RoutingInfo {
 
 // Next returns the next chunk from the stream.
 func (s *RouterStream) Next() (StreamChunk, error) {
-	chunk, err := s.inner.Next()
-	if err != nil {
-		if s.streamErr == nil {
-			s.streamErr = err
+	for {
+		if s.settled {
+			// Closed, or failover ran out of candidates and the stream
+			// stays failed.
+			if s.streamErr == nil {
+				return StreamChunk{}, io.EOF
+			}
+			return StreamChunk{}, s.streamErr
 		}
-		return chunk, err
+
+		chunk, err := s.inner.Next()
+		if err != nil {
+			if s.canFailover(err) {
+				s.failOver(err)
+				continue
+			}
+			if s.streamErr == nil {
+				s.streamErr = err
+			}
+			return chunk, err
+		}
+
+		// Track usage from the final chunk.
+		if chunk.Usage != nil {
+			s.totalUsage = *chunk.Usage
+		}
+		if s.ttft == 0 && carriesContent(chunk) {
+			s.ttft = time.Since(s.attemptStart)
+		}
+		if s.failover != nil {
+			s.failover.track(chunk)
+		}
+
+		return chunk, nil
 	}
+}
 
-	// Track usage from the final chunk.
-	if chunk.Usage != nil {
-		s.totalUsage = *chunk.Usage
+// canFailover reports whether err may be absorbed by moving to the next
+// candidate instead of being returned to the caller.
+func (s *RouterStream) canFailover(err error) bool {
+	f := s.failover
+	if f == nil || s.streamErr != nil || errors.Is(err, io.EOF) || IsFatal(err) {
+		return false
+	}
+	// The caller gave up; another candidate would not be read either.
+	if f.ctx.Err() != nil {
+		return false
 	}
+	switch f.mode {
+	case StreamFailoverBeforeContent:
+		return f.partial.Len() == 0 && !f.toolCalls
+	case StreamFailoverResume:
+		return !f.toolCalls
+	default:
+		return false
+	}
+}
+
+// failOver settles the failed candidate and opens the request on the rest of
+// the ladder. If nothing opens, the stream is left settled with the combined
+// error, which Next and Close then report.
+func (s *RouterStream) failOver(err error) {
+	f := s.failover
+	s.streamErr = err
+	s.settle()
+	f.tried = append(f.tried, CandidateError{
+		Provider:  s.candidate.Provider.Name(),
+		AccountID: s.candidate.AccountID,
+		Model:     s.candidate.Model,
+		Err:       err,
+	})
 
-	return chunk, nil
+	req := f.req
+	if f.partial.Len() > 0 {
+		req = resumeRequest(f.req, f.partial.String())
+	}
+
+	next, tried, openErr := f.router.openStream(f.ctx, req, f.needs, f.ordered, s.attempts, f.tried)
+	f.tried = tried
+	if openErr != nil {
+		s.streamErr = openErr
+		return
+	}
+
+	s.inner = next.inner
+	s.grant = next.grant
+	s.ctx = next.ctx
+	s.span = next.span
+	s.candidate = next.candidate
+	s.attempts = next.attempts
+	s.cancel = next.cancel
+	s.startTime = next.startTime
+	s.attemptStart = next.attemptStart
+	s.ttft = 0
+	s.totalUsage = Usage{}
+	s.streamErr = nil
+	s.settled = false
+}
+
+// track records what has been delivered to the caller.
+func (f *streamFailoverState) track(chunk StreamChunk) {
+	for _, choice := range chunk.Choices {
+		f.partial.WriteString(choice.Delta.Content)
+		if len(choice.Delta.ToolCalls) > 0 {
+			f.toolCalls = true
+		}
+	}
+}
+
+// carriesContent reports whether a chunk delivers text or a tool call, as
+// opposed to a role marker or a usage-only tail.
+func carriesContent(chunk StreamChunk) bool {
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" || len(choice.Delta.ToolCalls) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resumeRequest asks the next candidate to continue a partial answer.
+func resumeRequest(req ChatRequest, partial string) ChatRequest {
+	msgs := make([]Message, 0, len(req.Messages)+2)
+	msgs = append(msgs, req.Messages...)
+	msgs = append(msgs,
+		Message{Role: "assistant", Content: partial},
+		Message{Role: "user", Content: resumePrompt},
+	)
+	req.Messages = msgs
+	return req
 }
 
 // Close releases the stream and commits quota.
@@ -68,12 +259,36 @@ func (s *RouterStream) Close() error {
 	}
 	s.closed = true
 
+	// Failover may already have released the last candidate and found no
+	// other.
+	err := s.streamErr
+	if !s.settled {
+		err = s.settle()
+	}
+	if s.callSpan != nil {
+		endCall(s.callSpan, s.Routing(), err)
+	}
+	return err
+}
+
+// settle closes the current provider stream and commits or rolls back its
+// reservation depending on how it ended.
+func (s *RouterStream) settle() error {
+	s.settled = true
+
+	_, span := s.tracer.Start(s.ctx, SpanSettle)
+
 	if s.cancel != nil {
 		defer s.cancel()
 	}
 
 	if s.inflight != nil {
-		s.inflight.Dec(s.candidate.AccountID)
+		s.inflight.Release(s.candidate.AccountID, s.candidate.Model)
+	}
+	// Whatever the provider generated counts against the token windows; a
+	// stream that died without reporting usage gives its estimate back.
+	if s.rateLimiter != nil {
+		s.rateLimiter.AdjustTokens(context.Background(), s.candidate.AccountID, s.candidate.Model, s.totalUsage.TotalTokens-s.grant.tokens)
 	}
 
 	err := s.inner.Close()
@@ -83,24 +298,25 @@ func (s *RouterStream) Close() error {
 	isSuccess := s.streamErr == nil || errors.Is(s.streamErr, io.EOF)
 
 	var quotaErr error
+	var dollarCost float64
 	if isSuccess {
-		actualTokens := s.totalUsage.TotalTokens
-		if s.candidate.QuotaUnit == QuotaRequests {
-			actualTokens = 1
-		}
-		quotaErr = s.quotaStore.Commit(context.Background(), s.reservation, actualTokens)
+		dollarCost = calculateSpend(s.candidate, s.totalUsage)
+		actual := quotaAmount(s.candidate.QuotaUnit, s.totalUsage.TotalTokens, dollarCost)
+		quotaErr = s.quotaStore.Commit(context.Background(), s.grant.Reservation, actual)
 		s.health.RecordSuccess(s.candidate.AccountID)
+		s.health.RecordProviderSuccess(s.candidate.Provider.Name())
+		// Only the first token: open to close is the generation length.
+		if s.latency != nil && s.ttft > 0 {
+			s.latency.Record(s.candidate.AccountID, s.candidate.Model, 0, s.ttft)
+		}
 	} else {
-		quotaErr = s.quotaStore.Rollback(context.Background(), s.reservation)
-		s.health.RecordFailure(s.candidate.AccountID)
+		quotaErr = s.quotaStore.Rollback(context.Background(), s.grant.Reservation)
+		s.recordFailure(s.ctx, s.candidate.Provider.Name(), s.candidate.AccountID, s.candidate.Model, s.streamErr)
 	}
 
-	var dollarCost float64
+	var spendErr error
 	if isSuccess {
-		dollarCost = calculateSpend(s.candidate, s.totalUsage)
-		if dollarCost > 0 {
-			s.spend.RecordSpend(s.candidate.AccountID, dollarCost)
-		}
+		spendErr = recordSpend(context.Background(), s.spend, s.candidate.AccountID, dollarCost)
 	}
 
 	// Build the error to return to caller.
@@ -112,16 +328,33 @@ func (s *RouterStream) Close() error {
 	if quotaErr != nil {
 		resultErr = fmt.Errorf("quota operation failed: %w", quotaErr)
 	}
+	resultErr = errors.Join(resultErr, spendErr)
 	if err != nil && resultErr == nil {
 		resultErr = fmt.Errorf("stream close: %w", err)
 	}
 
+	if quotaErr != nil || spendErr != nil {
+		span.RecordError(errors.Join(quotaErr, spendErr))
+	}
+	span.End()
+	s.span.SetAttributes(usageAttributes(s.totalUsage, dollarCost)...)
+	if !isSuccess {
+		endSpan(s.span, &CandidateError{
+			Provider:  s.candidate.Provider.Name(),
+			AccountID: s.candidate.AccountID,
+			Model:     s.candidate.Model,
+			Err:       s.streamErr,
+		})
+	} else {
+		s.span.End()
+	}
+
 	s.meter.OnResult(ResultEvent{
 		Provider:   s.candidate.Provider.Name(),
 		AccountID:  s.candidate.AccountID,
 		Model:      s.candidate.Model,
 		Free:       s.candidate.Free,
-		Success:    isSuccess && quotaErr == nil,
+		Success:    isSuccess && quotaErr == nil && spendErr == nil,
 		Duration:   duration,
 		Usage:      s.totalUsage,
 		Error:      resultErr,
@@ -0,0 +1,223 @@
+package inferrouter_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedStreamProvider opens a stream that emits the given text pieces and
+// then ends with failErr (io.EOF for a clean finish). It keeps the messages of
+// every request it received.
+type scriptedStreamProvider struct {
+	name    string
+	log     *attemptLog
+	pieces  []string
+	failErr error
+
+	mu   sync.Mutex
+	reqs [][]ir.Message
+}
+
+func (p *scriptedStreamProvider) Name() string              { return p.name }
+func (p *scriptedStreamProvider) SupportsModel(string) bool { return true }
+func (p *scriptedStreamProvider) SupportsMultimodal() bool  { return false }
+
+func (p *scriptedStreamProvider) ChatCompletion(context.Context, ir.ProviderRequest) (ir.ProviderResponse, error) {
+	return ir.ProviderResponse{}, ir.ErrProviderUnavailable
+}
+
+func (p *scriptedStreamProvider) ChatCompletionStream(_ context.Context, req ir.ProviderRequest) (ir.ProviderStream, error) {
+	p.log.record(p.name)
+	p.mu.Lock()
+	p.reqs = append(p.reqs, req.Messages)
+	p.mu.Unlock()
+	return &scriptedStream{pieces: p.pieces, failErr: p.failErr}, nil
+}
+
+func (p *scriptedStreamProvider) lastMessages() []ir.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.reqs) == 0 {
+		return nil
+	}
+	return p.reqs[len(p.reqs)-1]
+}
+
+type scriptedStream struct {
+	pieces  []string
+	failErr error
+}
+
+func (s *scriptedStream) Next() (ir.StreamChunk, error) {
+	if len(s.pieces) == 0 {
+		return ir.StreamChunk{}, s.failErr
+	}
+	piece := s.pieces[0]
+	s.pieces = s.pieces[1:]
+	return ir.StreamChunk{Choices: []ir.StreamDelta{{Delta: ir.Delta{Content: piece}}}}, nil
+}
+
+func (s *scriptedStream) Close() error { return nil }
+
+func scriptedStep(log *attemptLog, name string, failErr error, pieces ...string) *scriptedStreamProvider {
+	return &scriptedStreamProvider{name: name, log: log, pieces: pieces, failErr: failErr}
+}
+
+// readAll drains the stream and returns the concatenated text.
+func readAll(s *ir.RouterStream) (string, error) {
+	var b strings.Builder
+	for {
+		chunk, err := s.Next()
+		if errors.Is(err, io.EOF) {
+			return b.String(), nil
+		}
+		if err != nil {
+			return b.String(), err
+		}
+		for _, c := range chunk.Choices {
+			b.WriteString(c.Delta.Content)
+		}
+	}
+}
+
+func streamLadder(t *testing.T, mode ir.StreamFailover, steps ...*scriptedStreamProvider) *ir.Router {
+	t.Helper()
+	names := make([]string, len(steps))
+	providers := make([]ir.Provider, len(steps))
+	for i, s := range steps {
+		names[i] = s.name
+		providers[i] = s
+	}
+	r, err := ir.NewRouter(ladderConfig(names...), providers,
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithStreamFailover(mode))
+	require.NoError(t, err)
+	return r
+}
+
+var streamReq = ir.ChatRequest{
+	Model:    "ladder",
+	Messages: []ir.Message{{Role: "user", Content: "tell me"}},
+}
+
+// Without the option, a stream that dies ends the response — the historical
+// behaviour.
+func TestStreamFailover_OffByDefault(t *testing.T) {
+	log := &attemptLog{}
+	r := streamLadder(t, ir.StreamFailoverOff,
+		scriptedStep(log, "dies", ir.ErrProviderUnavailable),
+		scriptedStep(log, "spare", io.EOF, "never"))
+
+	stream, err := r.ChatCompletionStream(context.Background(), streamReq)
+	require.NoError(t, err)
+	_, err = readAll(stream)
+	assert.ErrorIs(t, err, ir.ErrProviderUnavailable)
+	assert.ErrorIs(t, stream.Close(), ir.ErrProviderUnavailable)
+	assert.Equal(t, []string{"dies"}, log.snapshot())
+}
+
+// A stream that dies before any content moves to the next step; Routing
+// names the step that finished and counts both attempts.
+func TestStreamFailover_BeforeContent(t *testing.T) {
+	log := &attemptLog{}
+	r := streamLadder(t, ir.StreamFailoverBeforeContent,
+		scriptedStep(log, "dies", ir.ErrProviderUnavailable),
+		scriptedStep(log, "finishes", io.EOF, "hello ", "world"))
+
+	stream, err := r.ChatCompletionStream(context.Background(), streamReq)
+	require.NoError(t, err)
+	text, err := readAll(stream)
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+
+	assert.Equal(t, "hello world", text)
+	assert.Equal(t, []string{"dies", "finishes"}, log.snapshot())
+	assert.Equal(t, "finishes", stream.Routing().Provider)
+	assert.Equal(t, 2, stream.Routing().Attempts)
+}
+
+// Once text was delivered, BeforeContent no longer fails over.
+func TestStreamFailover_BeforeContentStopsAfterText(t *testing.T) {
+	log := &attemptLog{}
+	r := streamLadder(t, ir.StreamFailoverBeforeContent,
+		scriptedStep(log, "dies", ir.ErrProviderUnavailable, "half"),
+		scriptedStep(log, "spare", io.EOF, "never"))
+
+	stream, err := r.ChatCompletionStream(context.Background(), streamReq)
+	require.NoError(t, err)
+	text, err := readAll(stream)
+	assert.ErrorIs(t, err, ir.ErrProviderUnavailable)
+	assert.Equal(t, "half", text)
+	assert.Equal(t, []string{"dies"}, log.snapshot())
+	_ = stream.Close()
+}
+
+// Resume hands the partial answer to the next step, which continues it.
+func TestStreamFailover_Resume(t *testing.T) {
+	log := &attemptLog{}
+	next := scriptedStep(log, "continues", io.EOF, "world")
+	r := streamLadder(t, ir.StreamFailoverResume,
+		scriptedStep(log, "dies", ir.ErrProviderUnavailable, "hello "),
+		next)
+
+	stream, err := r.ChatCompletionStream(context.Background(), streamReq)
+	require.NoError(t, err)
+	text, err := readAll(stream)
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+
+	assert.Equal(t, "hello world", text)
+	msgs := next.lastMessages()
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "tell me", msgs[0].Content)
+	assert.Equal(t, ir.Message{Role: "assistant", Content: "hello "}, msgs[1])
+	assert.Equal(t, "user", msgs[2].Role)
+	assert.Equal(t, "continues", stream.Routing().Provider)
+}
+
+// Fatal errors are not failed over, whatever the mode.
+func TestStreamFailover_FatalEndsStream(t *testing.T) {
+	log := &attemptLog{}
+	r := streamLadder(t, ir.StreamFailoverResume,
+		scriptedStep(log, "rejects", ir.ErrInvalidRequest),
+		scriptedStep(log, "spare", io.EOF, "never"))
+
+	stream, err := r.ChatCompletionStream(context.Background(), streamReq)
+	require.NoError(t, err)
+	_, err = readAll(stream)
+	assert.ErrorIs(t, err, ir.ErrInvalidRequest)
+	assert.Equal(t, []string{"rejects"}, log.snapshot())
+	_ = stream.Close()
+}
+
+// When every remaining step fails too, the caller gets ErrAllFailed listing
+// the mid-stream failures, from Next and again from Close.
+func TestStreamFailover_Exhausted(t *testing.T) {
+	log := &attemptLog{}
+	r := streamLadder(t, ir.StreamFailoverBeforeContent,
+		scriptedStep(log, "one", ir.ErrProviderUnavailable),
+		scriptedStep(log, "two", ir.ErrProviderUnavailable))
+
+	stream, err := r.ChatCompletionStream(context.Background(), streamReq)
+	require.NoError(t, err)
+	_, err = readAll(stream)
+	require.ErrorIs(t, err, ir.ErrAllFailed)
+
+	var rerr *ir.RouterError
+	require.True(t, errors.As(err, &rerr))
+	require.Len(t, rerr.Tried, 2)
+	assert.Equal(t, "one", rerr.Tried[0].Provider)
+	assert.Equal(t, "two", rerr.Tried[1].Provider)
+
+	assert.ErrorIs(t, stream.Close(), ir.ErrAllFailed)
+	assert.Equal(t, []string{"one", "two"}, log.snapshot())
+}
@@ -0,0 +1,151 @@
+package inferrouter_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/provider/mock"
+	"github.com/ineyio/inferrouter/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var weatherTool = ir.Tool{
+	Type: "function",
+	Function: ir.ToolFunction{
+		Name:        "get_weather",
+		Description: "Current weather for a city",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+	},
+}
+
+// toolCallingStep answers every request with a single get_weather call and
+// records the tools it was handed.
+func toolCallingStep(log *attemptLog, name string, got *ir.ProviderRequest) *mock.Provider {
+	return mock.New(
+		mock.WithName(name),
+		mock.WithModels("ladder-model"),
+		mock.WithTools(true),
+		mock.WithResponseFunc(func(req ir.ProviderRequest) (ir.ProviderResponse, error) {
+			log.record(name)
+			*got = req
+			return ir.ProviderResponse{
+				ID:           "resp-tools",
+				Model:        "ladder-model",
+				FinishReason: "tool_calls",
+				ToolCalls: []ir.ToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: ir.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+				}},
+				Usage: ir.Usage{PromptTokens: 5, CompletionTokens: 5, TotalTokens: 10},
+			}, nil
+		}),
+	)
+}
+
+// A step without tool support is skipped like a text-only step for media:
+// not attempted, not fatal, the remaining steps keep their order.
+func TestTools_NonCapableStepSkipped(t *testing.T) {
+	log := &attemptLog{}
+	var got ir.ProviderRequest
+	textOnly := failingStep(log, "text-step")
+	capable := toolCallingStep(log, "tool-step", &got)
+
+	cfg := ladderConfig("text-step", "tool-step")
+	r, err := ir.NewRouter(cfg, []ir.Provider{textOnly, capable},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	require.NoError(t, err)
+
+	choice := &ir.ToolChoice{Mode: ir.ToolChoiceRequired}
+	resp, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Model:      "ladder",
+		Messages:   []ir.Message{{Role: "user", Content: "weather in Paris?"}},
+		Tools:      []ir.Tool{weatherTool},
+		ToolChoice: choice,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"tool-step"}, log.snapshot())
+	require.Len(t, got.Tools, 1, "tools must reach the provider")
+	assert.Equal(t, "get_weather", got.Tools[0].Function.Name)
+	assert.Equal(t, choice, got.ToolChoice)
+
+	msg := resp.Choices[0].Message
+	require.Len(t, msg.ToolCalls, 1)
+	assert.Equal(t, "call_1", msg.ToolCalls[0].ID)
+	assert.Equal(t, `{"city":"Paris"}`, msg.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+}
+
+// No tool-capable step → the specific sentinel, and nothing is attempted.
+func TestTools_NoCapableStep(t *testing.T) {
+	log := &attemptLog{}
+	cfg := ladderConfig("text-one", "text-two")
+	r, err := ir.NewRouter(cfg,
+		[]ir.Provider{failingStep(log, "text-one"), failingStep(log, "text-two")},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	require.NoError(t, err)
+
+	_, err = r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Model:    "ladder",
+		Messages: []ir.Message{{Role: "user", Content: "hi"}},
+		Tools:    []ir.Tool{weatherTool},
+	})
+	assert.True(t, errors.Is(err, ir.ErrToolsUnavailable), "got %v", err)
+	assert.Empty(t, log.snapshot())
+
+	// A tool result in the history needs a capable provider too.
+	_, err = r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Model: "ladder",
+		Messages: []ir.Message{
+			{Role: "assistant", ToolCalls: []ir.ToolCall{{ID: "call_1", Type: "function",
+				Function: ir.FunctionCall{Name: "get_weather", Arguments: `{}`}}}},
+			{Role: "tool", ToolCallID: "call_1", Content: `{"temp":21}`},
+		},
+	})
+	assert.True(t, errors.Is(err, ir.ErrToolsUnavailable), "got %v", err)
+}
+
+func TestTools_StreamCarriesToolCallDeltas(t *testing.T) {
+	log := &attemptLog{}
+	var got ir.ProviderRequest
+	cfg := ladderConfig("tool-step")
+	r, err := ir.NewRouter(cfg, []ir.Provider{toolCallingStep(log, "tool-step", &got)},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()))
+	require.NoError(t, err)
+
+	stream, err := r.ChatCompletionStream(context.Background(), ir.ChatRequest{
+		Model:    "ladder",
+		Messages: []ir.Message{{Role: "user", Content: "weather?"}},
+		Tools:    []ir.Tool{weatherTool},
+	})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var deltas []ir.ToolCallDelta
+	var finish string
+	for {
+		chunk, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		for _, c := range chunk.Choices {
+			deltas = append(deltas, c.Delta.ToolCalls...)
+			if c.FinishReason != "" {
+				finish = c.FinishReason
+			}
+		}
+	}
+
+	require.Len(t, deltas, 1)
+	assert.Equal(t, "call_1", deltas[0].ID)
+	assert.Equal(t, "get_weather", deltas[0].Function.Name)
+	assert.Equal(t, "tool_calls", finish)
+	require.Len(t, got.Tools, 1)
+}
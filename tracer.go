@@ -0,0 +1,168 @@
+package inferrouter
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer records the structure of routed calls as spans. The router opens one
+// span per public call (ChatCompletion, ChatCompletionStream, Embed,
+// EmbedBatch), a child per candidate attempt, and under each attempt a span
+// for the acquire, provider call and settle phases. A skipped or failed
+// attempt records its CandidateError; a failed call records the RouterError,
+// whose Tried list says why every step was passed over.
+//
+// The router has no tracing dependency of its own: tracing/otel adapts
+// OpenTelemetry to this interface.
+type Tracer interface {
+	// Start begins a span as a child of the span in ctx, if any, and returns
+	// a context carrying the new one.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is one unit of work started by a Tracer.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+
+	// RecordError marks the span failed with err.
+	RecordError(err error)
+
+	End()
+}
+
+// Attribute is a span attribute. Value is a string, bool, int64 or float64.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// TracePropagator is an optional Tracer capability: writing the trace context
+// of ctx into the headers of an outgoing request, so the provider's side of
+// the call joins the trace. Providers reach it through InjectTraceHeaders.
+type TracePropagator interface {
+	Inject(ctx context.Context, header http.Header)
+}
+
+// InjectTraceHeaders writes the trace context of ctx into h. Providers call it
+// on every HTTP request they build. It does nothing unless ctx came from a
+// router whose Tracer implements TracePropagator.
+func InjectTraceHeaders(ctx context.Context, h http.Header) {
+	if p, ok := ctx.Value(propagatorKey{}).(TracePropagator); ok {
+		p.Inject(ctx, h)
+	}
+}
+
+// Span names passed to Tracer.Start.
+const (
+	SpanChatCompletion       = "inferrouter.ChatCompletion"
+	SpanChatCompletionStream = "inferrouter.ChatCompletionStream"
+	SpanEmbed                = "inferrouter.Embed"
+	SpanEmbedBatch           = "inferrouter.EmbedBatch"
+	SpanAttempt              = "inferrouter.attempt"
+	SpanAcquire              = "inferrouter.acquire"
+	SpanProviderCall         = "inferrouter.provider_call"
+	SpanSettle               = "inferrouter.settle"
+)
+
+// Attribute keys set on the spans.
+const (
+	AttrRequestModel     = "inferrouter.request.model"
+	AttrProvider         = "inferrouter.provider"
+	AttrAccount          = "inferrouter.account"
+	AttrModel            = "inferrouter.model"
+	AttrFree             = "inferrouter.free"
+	AttrAttempt          = "inferrouter.attempt"
+	AttrAttempts         = "inferrouter.attempts"
+	AttrHedge            = "inferrouter.hedge"
+	AttrEstimatedTokens  = "inferrouter.tokens.estimated"
+	AttrPromptTokens     = "inferrouter.tokens.prompt"
+	AttrCompletionTokens = "inferrouter.tokens.completion"
+	AttrCachedTokens     = "inferrouter.tokens.cached"
+	AttrCostDollars      = "inferrouter.cost_dollars"
+)
+
+// propagatorKey carries the router's TracePropagator to the providers.
+type propagatorKey struct{}
+
+// attemptSpanKey carries the attempt span to the settle functions, which
+// know the usage and cost it should report.
+type attemptSpanKey struct{}
+
+// startCall opens the span of a public call.
+func (r *Router) startCall(ctx context.Context, name, model string) (context.Context, Span) {
+	ctx, span := r.tracer.Start(ctx, name, Attribute{AttrRequestModel, model})
+	if p, ok := r.tracer.(TracePropagator); ok {
+		ctx = context.WithValue(ctx, propagatorKey{}, p)
+	}
+	return ctx, span
+}
+
+// endCall closes a call span, naming the step that served it or the error.
+func endCall(span Span, routing RoutingInfo, err error) {
+	if routing.Provider != "" {
+		span.SetAttributes(
+			Attribute{AttrProvider, routing.Provider},
+			Attribute{AttrAccount, routing.AccountID},
+			Attribute{AttrModel, routing.Model},
+			Attribute{AttrFree, routing.Free},
+			Attribute{AttrAttempts, int64(routing.Attempts)},
+		)
+	}
+	endSpan(span, err)
+}
+
+// startAttempt opens the span of one candidate attempt.
+func (r *Router) startAttempt(ctx context.Context, provider, accountID, model string, free bool, attempt int, estimatedTokens int64) (context.Context, Span) {
+	attrs := []Attribute{
+		{AttrProvider, provider},
+		{AttrAccount, accountID},
+		{AttrModel, model},
+		{AttrFree, free},
+		{AttrAttempt, int64(attempt)},
+		{AttrEstimatedTokens, estimatedTokens},
+	}
+	if isHedge(ctx) {
+		attrs = append(attrs, Attribute{AttrHedge, true})
+	}
+	ctx, span := r.tracer.Start(ctx, SpanAttempt, attrs...)
+	return context.WithValue(ctx, attemptSpanKey{}, span), span
+}
+
+// attemptSpan returns the attempt span in ctx, or a no-op span.
+func attemptSpan(ctx context.Context) Span {
+	if span, ok := ctx.Value(attemptSpanKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
+
+// usageAttributes reports what an attempt consumed.
+func usageAttributes(usage Usage, dollarCost float64) []Attribute {
+	return []Attribute{
+		{AttrPromptTokens, usage.PromptTokens},
+		{AttrCompletionTokens, usage.CompletionTokens},
+		{AttrCachedTokens, usage.CachedTokens},
+		{AttrCostDollars, dollarCost},
+	}
+}
+
+// endSpan records err, if any, and ends span.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// noopTracer is the default Tracer: spans that record nothing.
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
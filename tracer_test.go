@@ -0,0 +1,337 @@
+package inferrouter_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/provider/mock"
+	"github.com/ineyio/inferrouter/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spanTracer is a Tracer that keeps every span it starts, linked to its
+// parent through the context.
+type spanTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	tracer *spanTracer
+	name   string
+	parent *recordedSpan
+	attrs  map[string]any
+	errs   []error
+	ended  bool
+}
+
+type spanCtxKey struct{}
+
+func (t *spanTracer) Start(ctx context.Context, name string, attrs ...ir.Attribute) (context.Context, ir.Span) {
+	parent, _ := ctx.Value(spanCtxKey{}).(*recordedSpan)
+	s := &recordedSpan{tracer: t, name: name, parent: parent, attrs: map[string]any{}}
+	s.SetAttributes(attrs...)
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanCtxKey{}, s), s
+}
+
+// Inject makes spanTracer a TracePropagator: the header names the span
+// current at the time of the request.
+func (t *spanTracer) Inject(ctx context.Context, h http.Header) {
+	if s, ok := ctx.Value(spanCtxKey{}).(*recordedSpan); ok {
+		h.Set("X-Test-Span", s.name)
+	}
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...ir.Attribute) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+func (s *recordedSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+}
+
+// named returns the spans called name, in start order.
+func (t *spanTracer) named(name string) []*recordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []*recordedSpan
+	for _, s := range t.spans {
+		if s.name == name {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// children returns the names of the spans started under parent.
+func (t *spanTracer) children(parent *recordedSpan) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []string
+	for _, s := range t.spans {
+		if s.parent == parent {
+			out = append(out, s.name)
+		}
+	}
+	return out
+}
+
+func failoverLadder() ir.Config {
+	return ir.Config{
+		DefaultModel: "test-model",
+		Models: []ir.ModelMapping{{
+			Alias: "test-model",
+			Models: []ir.ModelRef{
+				{Provider: "broken", Model: "test-model"},
+				{Provider: "mock", Model: "test-model"},
+			},
+		}},
+		Accounts: []ir.AccountConfig{
+			{Provider: "broken", ID: "broken-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "mock", ID: "free-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+}
+
+func newTracedRouter(t *testing.T, cfg ir.Config, tracer ir.Tracer, providers ...ir.Provider) *ir.Router {
+	t.Helper()
+	r, err := ir.NewRouter(cfg, providers,
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithTracer(tracer),
+	)
+	require.NoError(t, err)
+	return r
+}
+
+func TestTracing_ChatCompletionFailover(t *testing.T) {
+	tracer := &spanTracer{}
+	r := newTracedRouter(t, failoverLadder(), tracer,
+		mock.New(mock.WithName("broken"), mock.WithModels("test-model"), mock.WithError(ir.ErrProviderUnavailable)),
+		mock.New(mock.WithModels("test-model")),
+	)
+
+	_, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Model:    "test-model",
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+
+	calls := tracer.named("inferrouter.ChatCompletion")
+	require.Len(t, calls, 1)
+	call := calls[0]
+	assert.True(t, call.ended)
+	assert.Empty(t, call.errs)
+	assert.Equal(t, "test-model", call.attrs[ir.AttrRequestModel])
+	assert.Equal(t, "free-1", call.attrs[ir.AttrAccount])
+	assert.EqualValues(t, 2, call.attrs[ir.AttrAttempts])
+	assert.Equal(t, []string{"inferrouter.attempt", "inferrouter.attempt"}, tracer.children(call))
+
+	attempts := tracer.named("inferrouter.attempt")
+	require.Len(t, attempts, 2)
+	for _, a := range attempts {
+		assert.True(t, a.ended)
+		assert.Equal(t, []string{"inferrouter.acquire", "inferrouter.provider_call", "inferrouter.settle"}, tracer.children(a))
+	}
+
+	failed := attempts[0]
+	assert.Equal(t, "broken-1", failed.attrs[ir.AttrAccount])
+	assert.EqualValues(t, 1, failed.attrs[ir.AttrAttempt])
+	require.Len(t, failed.errs, 1)
+	var ce *ir.CandidateError
+	require.ErrorAs(t, failed.errs[0], &ce)
+	assert.Equal(t, "broken-1", ce.AccountID)
+	assert.ErrorIs(t, ce, ir.ErrProviderUnavailable)
+
+	served := attempts[1]
+	assert.Empty(t, served.errs)
+	assert.EqualValues(t, 2, served.attrs[ir.AttrAttempt])
+	assert.EqualValues(t, 8, served.attrs[ir.AttrEstimatedTokens])
+	assert.EqualValues(t, 10, served.attrs[ir.AttrPromptTokens])
+	assert.EqualValues(t, 20, served.attrs[ir.AttrCompletionTokens])
+}
+
+func TestTracing_AllFailedRecordsRouterError(t *testing.T) {
+	tracer := &spanTracer{}
+	r := newTracedRouter(t, failoverLadder(), tracer,
+		mock.New(mock.WithName("broken"), mock.WithModels("test-model"), mock.WithError(ir.ErrProviderUnavailable)),
+		mock.New(mock.WithModels("test-model"), mock.WithError(ir.ErrProviderUnavailable)),
+	)
+
+	_, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	})
+	require.ErrorIs(t, err, ir.ErrAllFailed)
+
+	call := tracer.named("inferrouter.ChatCompletion")[0]
+	require.Len(t, call.errs, 1)
+	var re *ir.RouterError
+	require.ErrorAs(t, call.errs[0], &re)
+	assert.Len(t, re.Tried, 2)
+	assert.NotContains(t, call.attrs, ir.AttrAccount)
+}
+
+func TestTracing_AcquireSkipRecorded(t *testing.T) {
+	cfg := failoverLadder()
+	cfg.Accounts[0].DailyFree = 1 // cannot reserve the estimate
+
+	tracer := &spanTracer{}
+	r := newTracedRouter(t, cfg, tracer,
+		mock.New(mock.WithName("broken"), mock.WithModels("test-model")),
+		mock.New(mock.WithModels("test-model")),
+	)
+
+	_, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+
+	skipped := tracer.named("inferrouter.attempt")[0]
+	assert.Equal(t, []string{"inferrouter.acquire"}, tracer.children(skipped))
+	require.Len(t, skipped.errs, 1)
+	assert.ErrorIs(t, skipped.errs[0], ir.ErrQuotaExceeded)
+	assert.Len(t, tracer.named("inferrouter.acquire")[0].errs, 1)
+}
+
+func TestTracing_StreamSpanEndsOnClose(t *testing.T) {
+	tracer := &spanTracer{}
+	r := newTracedRouter(t, failoverLadder(), tracer,
+		mock.New(mock.WithName("broken"), mock.WithModels("test-model"), mock.WithError(ir.ErrProviderUnavailable)),
+		mock.New(mock.WithModels("test-model")),
+	)
+
+	stream, err := r.ChatCompletionStream(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+
+	call := tracer.named("inferrouter.ChatCompletionStream")[0]
+	attempts := tracer.named("inferrouter.attempt")
+	require.Len(t, attempts, 2)
+	assert.True(t, attempts[0].ended)
+	assert.False(t, attempts[1].ended, "the serving attempt lasts as long as the stream")
+	assert.False(t, call.ended)
+
+	for {
+		if _, err := stream.Next(); err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			break
+		}
+	}
+	require.NoError(t, stream.Close())
+
+	assert.True(t, attempts[1].ended)
+	assert.True(t, call.ended)
+	assert.Equal(t, "free-1", call.attrs[ir.AttrAccount])
+	assert.Contains(t, tracer.children(attempts[1]), "inferrouter.settle")
+}
+
+func TestTracing_EmbedBatch(t *testing.T) {
+	cfg := ir.Config{
+		DefaultModel: "test-embed",
+		Accounts: []ir.AccountConfig{
+			{Provider: "mock-embed", ID: "embed-1", DailyFree: 100000, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+	tracer := &spanTracer{}
+	embedProv := mock.NewEmbed(mock.WithEmbedSupportedModels("test-embed"), mock.WithEmbedMaxBatch(2))
+	r := newTracedRouter(t, declareLadder(cfg), tracer, embedProviderAsProvider(embedProv))
+
+	_, err := r.EmbedBatch(context.Background(), ir.EmbedRequest{Model: "test-embed", Inputs: []string{"a", "b", "c"}})
+	require.NoError(t, err)
+
+	calls := tracer.named("inferrouter.EmbedBatch")
+	require.Len(t, calls, 1)
+	assert.True(t, calls[0].ended)
+	// Two sub-batches, one attempt each.
+	assert.Equal(t, []string{"inferrouter.attempt", "inferrouter.attempt"}, tracer.children(calls[0]))
+	assert.Equal(t, "embed-1", calls[0].attrs[ir.AttrAccount])
+}
+
+// headerProvider records the trace headers it would send upstream.
+type headerProvider struct {
+	*mock.Provider
+	header http.Header
+}
+
+func (p *headerProvider) ChatCompletion(ctx context.Context, req ir.ProviderRequest) (ir.ProviderResponse, error) {
+	p.header = http.Header{}
+	ir.InjectTraceHeaders(ctx, p.header)
+	return p.Provider.ChatCompletion(ctx, req)
+}
+
+func TestTracing_PropagatesIntoProviderRequests(t *testing.T) {
+	cfg := ir.Config{
+		DefaultModel: "test-model",
+		Accounts:     []ir.AccountConfig{{Provider: "mock", ID: "free-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens}},
+	}
+	prov := &headerProvider{Provider: mock.New(mock.WithModels("test-model"))}
+	r := newTracedRouter(t, declareLadder(cfg), &spanTracer{}, prov)
+
+	_, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "inferrouter.provider_call", prov.header.Get("X-Test-Span"))
+
+	// Without a propagating tracer nothing is written.
+	h := http.Header{}
+	ir.InjectTraceHeaders(context.Background(), h)
+	assert.Empty(t, h)
+}
+
+func TestTracing_HedgeLoserRecordsError(t *testing.T) {
+	tracer := &spanTracer{}
+	release := make(chan struct{})
+	slow := mock.New(mock.WithName("broken"), mock.WithModels("test-model"),
+		mock.WithResponseFunc(func(ir.ProviderRequest) (ir.ProviderResponse, error) {
+			<-release
+			return ir.ProviderResponse{}, errors.New("too late")
+		}))
+	r, err := ir.NewRouter(failoverLadder(), []ir.Provider{slow, mock.New(mock.WithModels("test-model"))},
+		ir.WithQuotaStore(quota.NewMemoryQuotaStore()),
+		ir.WithTracer(tracer),
+		ir.WithHedging(ir.HedgeConfig{Delay: 1}),
+	)
+	require.NoError(t, err)
+
+	_, err = r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+	close(release)
+
+	attempts := tracer.named("inferrouter.attempt")
+	require.Len(t, attempts, 2)
+	assert.Equal(t, true, attempts[1].attrs[ir.AttrHedge])
+	require.Eventually(t, func() bool {
+		tracer.mu.Lock()
+		defer tracer.mu.Unlock()
+		return attempts[0].ended
+	}, time.Second, time.Millisecond)
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	require.Len(t, attempts[0].errs, 1)
+	assert.ErrorIs(t, attempts[0].errs[0], ir.ErrHedgeLost)
+}
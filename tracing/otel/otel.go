@@ -0,0 +1,152 @@
+// Package otel provides an OpenTelemetry inferrouter.Tracer.
+//
+// Each routed call becomes a trace: a span for the call, a child per
+// candidate attempt, and under each attempt the acquire, provider call and
+// settle phases. Failed attempts record their inferrouter.CandidateError; a
+// failed call records one exception event per step it tried. The provider
+// call span is a client span, and its context is injected into the
+// provider's HTTP request headers, so an instrumented upstream joins the
+// trace.
+package otel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	gootel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ineyio/inferrouter"
+)
+
+// ScopeName is the instrumentation scope the spans are reported under.
+const ScopeName = "github.com/ineyio/inferrouter"
+
+// Tracer adapts an OpenTelemetry TracerProvider to inferrouter.Tracer.
+type Tracer struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	tracer     trace.Tracer
+}
+
+var (
+	_ inferrouter.Tracer          = (*Tracer)(nil)
+	_ inferrouter.TracePropagator = (*Tracer)(nil)
+)
+
+// Option configures Tracer.
+type Option func(*Tracer)
+
+// WithTracerProvider sets the TracerProvider (default the global one).
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(t *Tracer) { t.provider = tp }
+}
+
+// WithPropagator sets how trace context is written into provider requests
+// (default the global TextMapPropagator).
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(t *Tracer) { t.propagator = p }
+}
+
+// New creates a Tracer. Pass it to the router with inferrouter.WithTracer.
+func New(opts ...Option) *Tracer {
+	t := &Tracer{}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.provider == nil {
+		t.provider = gootel.GetTracerProvider()
+	}
+	if t.propagator == nil {
+		t.propagator = gootel.GetTextMapPropagator()
+	}
+	t.tracer = t.provider.Tracer(ScopeName)
+	return t
+}
+
+// Start begins a span. The provider call is a client span; the rest are
+// internal.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...inferrouter.Attribute) (context.Context, inferrouter.Span) {
+	kind := trace.SpanKindInternal
+	if name == inferrouter.SpanProviderCall {
+		kind = trace.SpanKindClient
+	}
+	ctx, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(convert(attrs)...),
+	)
+	return ctx, &otelSpan{span: span}
+}
+
+// Inject writes the trace context of ctx into h.
+func (t *Tracer) Inject(ctx context.Context, h http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) SetAttributes(attrs ...inferrouter.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+// RecordError records err as an exception event and marks the span failed.
+// A CandidateError carries the step it came from as event attributes; a
+// RouterError that tried several steps adds one such event per step.
+func (s *otelSpan) RecordError(err error) {
+	s.span.SetStatus(codes.Error, err.Error())
+
+	var re *inferrouter.RouterError
+	if errors.As(err, &re) && len(re.Tried) > 0 {
+		for i := range re.Tried {
+			ce := &re.Tried[i]
+			s.span.RecordError(ce, trace.WithAttributes(candidateAttrs(ce)...))
+		}
+		return
+	}
+	var ce *inferrouter.CandidateError
+	if errors.As(err, &ce) {
+		s.span.RecordError(err, trace.WithAttributes(candidateAttrs(ce)...))
+		return
+	}
+	s.span.RecordError(err)
+}
+
+func (s *otelSpan) End() {
+	s.span.End()
+}
+
+func candidateAttrs(ce *inferrouter.CandidateError) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String(inferrouter.AttrProvider, ce.Provider),
+		attribute.String(inferrouter.AttrAccount, ce.AccountID),
+		attribute.String(inferrouter.AttrModel, ce.Model),
+	}
+}
+
+func convert(attrs []inferrouter.Attribute) []attribute.KeyValue {
+	out := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			out = append(out, attribute.String(a.Key, v))
+		case bool:
+			out = append(out, attribute.Bool(a.Key, v))
+		case int64:
+			out = append(out, attribute.Int64(a.Key, v))
+		case int:
+			out = append(out, attribute.Int(a.Key, v))
+		case float64:
+			out = append(out, attribute.Float64(a.Key, v))
+		default:
+			out = append(out, attribute.String(a.Key, fmt.Sprint(v)))
+		}
+	}
+	return out
+}
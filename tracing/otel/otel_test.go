@@ -0,0 +1,190 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	ir "github.com/ineyio/inferrouter"
+	"github.com/ineyio/inferrouter/provider/mock"
+	"github.com/ineyio/inferrouter/provider/openaicompat"
+	"github.com/ineyio/inferrouter/quota"
+)
+
+const okBody = `{"id":"r1","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],` +
+	`"usage":{"prompt_tokens":5,"completion_tokens":7,"total_tokens":12}}`
+
+func newTestTracer() (*Tracer, *tracetest.SpanRecorder) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	return New(WithTracerProvider(tp), WithPropagator(propagation.TraceContext{})), rec
+}
+
+func spansNamed(rec *tracetest.SpanRecorder, name string) []sdktrace.ReadOnlySpan {
+	var out []sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		if s.Name() == name {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func attr(s sdktrace.ReadOnlySpan, key string) (string, bool) {
+	for _, kv := range s.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.Emit(), true
+		}
+	}
+	return "", false
+}
+
+func TestTracerFailoverTrace(t *testing.T) {
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(okBody))
+	}))
+	defer srv.Close()
+
+	cfg := ir.Config{
+		DefaultModel: "m",
+		Models: []ir.ModelMapping{{
+			Alias: "m",
+			Models: []ir.ModelRef{
+				{Provider: "broken", Model: "m"},
+				{Provider: "upstream", Model: "m"},
+			},
+		}},
+		Accounts: []ir.AccountConfig{
+			{Provider: "broken", ID: "broken-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+			{Provider: "upstream", ID: "up-1", DailyFree: 1000, QuotaUnit: ir.QuotaTokens},
+		},
+	}
+	tracer, rec := newTestTracer()
+	r, err := ir.NewRouter(cfg, []ir.Provider{
+		mock.New(mock.WithName("broken"), mock.WithModels("m"), mock.WithError(ir.ErrProviderUnavailable)),
+		openaicompat.New("upstream", srv.URL, openaicompat.WithModels("m")),
+	}, ir.WithQuotaStore(quota.NewMemoryQuotaStore()), ir.WithTracer(tracer))
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	if _, err := r.ChatCompletion(context.Background(), ir.ChatRequest{
+		Model:    "m",
+		Messages: []ir.Message{{Role: "user", Content: "hello"}},
+	}); err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+
+	calls := spansNamed(rec, ir.SpanChatCompletion)
+	if len(calls) != 1 {
+		t.Fatalf("call spans = %d, want 1", len(calls))
+	}
+	call := calls[0]
+	if v, _ := attr(call, ir.AttrAccount); v != "up-1" {
+		t.Errorf("call account = %q, want up-1", v)
+	}
+
+	attempts := spansNamed(rec, ir.SpanAttempt)
+	if len(attempts) != 2 {
+		t.Fatalf("attempt spans = %d, want 2", len(attempts))
+	}
+	for _, a := range attempts {
+		if a.Parent().SpanID() != call.SpanContext().SpanID() {
+			t.Errorf("attempt %s is not a child of the call span", a.SpanContext().SpanID())
+		}
+	}
+
+	failed := attempts[0]
+	if failed.Status().Code != codes.Error {
+		t.Errorf("failed attempt status = %v, want Error", failed.Status().Code)
+	}
+	events := failed.Events()
+	if len(events) != 1 || events[0].Name != "exception" {
+		t.Fatalf("failed attempt events = %+v, want one exception", events)
+	}
+	var account string
+	for _, kv := range events[0].Attributes {
+		if string(kv.Key) == ir.AttrAccount {
+			account = kv.Value.AsString()
+		}
+	}
+	if account != "broken-1" {
+		t.Errorf("exception account = %q, want broken-1", account)
+	}
+
+	served := attempts[1]
+	if v, _ := attr(served, ir.AttrCompletionTokens); v != "7" {
+		t.Errorf("completion tokens = %q, want 7", v)
+	}
+
+	var providerCall sdktrace.ReadOnlySpan
+	for _, s := range spansNamed(rec, ir.SpanProviderCall) {
+		if s.Parent().SpanID() == served.SpanContext().SpanID() {
+			providerCall = s
+		}
+	}
+	if providerCall == nil {
+		t.Fatal("no provider call span under the served attempt")
+	}
+	if providerCall.SpanKind() != trace.SpanKindClient {
+		t.Errorf("provider call kind = %v, want client", providerCall.SpanKind())
+	}
+
+	// The upstream saw the provider call span as its parent.
+	want := "00-" + providerCall.SpanContext().TraceID().String() + "-" + providerCall.SpanContext().SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("traceparent = %q, want %q", traceparent, want)
+	}
+}
+
+func TestTracerRecordsTriedSteps(t *testing.T) {
+	tracer, rec := newTestTracer()
+	_, span := tracer.Start(context.Background(), ir.SpanChatCompletion)
+	span.RecordError(&ir.RouterError{
+		Err:      ir.ErrAllFailed,
+		Attempts: 2,
+		Tried: []ir.CandidateError{
+			{Provider: "p", AccountID: "a1", Model: "m", Err: ir.ErrRateLimited},
+			{Provider: "p", AccountID: "a2", Model: "m", Err: ir.ErrProviderUnavailable},
+		},
+	})
+	span.End()
+
+	s := rec.Ended()[0]
+	if s.Status().Code != codes.Error {
+		t.Errorf("status = %v, want Error", s.Status().Code)
+	}
+	if got := len(s.Events()); got != 2 {
+		t.Errorf("exception events = %d, want one per tried step", got)
+	}
+}
+
+func TestTracerPlainError(t *testing.T) {
+	tracer, rec := newTestTracer()
+	_, span := tracer.Start(context.Background(), ir.SpanSettle,
+		ir.Attribute{Key: "n", Value: 3}, ir.Attribute{Key: "cost", Value: 0.5})
+	span.RecordError(errors.New("commit failed"))
+	span.End()
+
+	s := rec.Ended()[0]
+	if len(s.Events()) != 1 {
+		t.Errorf("events = %d, want 1", len(s.Events()))
+	}
+	if v, _ := attr(s, "n"); v != "3" {
+		t.Errorf("n = %q, want 3", v)
+	}
+	if v, _ := attr(s, "cost"); v != "0.5" {
+		t.Errorf("cost = %q, want 0.5", v)
+	}
+}
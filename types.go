@@ -1,5 +1,11 @@
 package inferrouter
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
 // ChatRequest represents a chat completion request.
 type ChatRequest struct {
 	Model       string    `json:"model"`
@@ -9,16 +15,162 @@ type ChatRequest struct {
 	TopP        *float64  `json:"top_p,omitempty"`
 	Stream      bool      `json:"stream,omitempty"`
 	Stop        []string  `json:"stop,omitempty"`
+
+	// Seed, PresencePenalty and FrequencyPenalty are passed to providers
+	// that support them and ignored by the rest.
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+
+	// N asks the provider for this many alternative completions (OpenAI's
+	// n, Gemini's candidateCount); ChatResponse.Choices carries all of them.
+	// With N above 1 the router skips providers that generate one (see
+	// ChoicesProvider). A stream follows a single completion, so
+	// ChatCompletionStream rejects N above 1.
+	N *int `json:"n,omitempty"`
+
+	// SafetySettings adjusts the provider's content filters. Only Gemini
+	// applies them; other providers ignore the field.
+	SafetySettings []SafetySetting `json:"safety_settings,omitempty"`
+
+	// Tools declares the functions the model may call. A request carrying
+	// tools (or a conversation that already contains tool calls/results) is
+	// routed only to providers that implement ToolProvider.
+	Tools []Tool `json:"tools,omitempty"`
+
+	// ToolChoice constrains whether and which tool the model calls. Nil
+	// leaves the decision to the provider default ("auto" when tools are set).
+	ToolChoice *ToolChoice `json:"tool_choice,omitempty"`
+
+	// ResponseFormat requests JSON or JSON-Schema-constrained output. Nil
+	// means free-form text. See ResponseFormat.Validate for router-side
+	// checking.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// Labels and Priority are caller-supplied routing hints: a tenant, a
+	// feature, an urgency. The router does not interpret them; it hands them
+	// to a RequestPolicy in the RouteContext.
+	Labels   map[string]string `json:"labels,omitempty"`
+	Priority int               `json:"priority,omitempty"`
+
+	// Route overrides routing for this request only; see RouteOptions.
+	Route *RouteOptions `json:"-"`
+}
+
+// SafetySetting sets the blocking threshold of one harm category, using the
+// Gemini names, e.g. Category "HARM_CATEGORY_HARASSMENT" with Threshold
+// "BLOCK_ONLY_HIGH".
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
 }
 
 // Message represents a chat message.
 //
 // For text-only messages, set Content. For multimodal messages (image/audio/video),
 // set Parts. If Parts is non-empty, it takes precedence over Content.
+//
+// Tool use follows the OpenAI shape: an assistant message carries ToolCalls,
+// and each result is sent back as a message with Role "tool", the originating
+// ToolCallID, and the result in Content. Name is the called function's name;
+// it is optional for OpenAI-compatible backends but lets adapters whose wire
+// format keys results by function name (Gemini) skip the lookup.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content,omitempty"`
-	Parts   []Part `json:"parts,omitempty"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content,omitempty"`
+	Parts      []Part     `json:"parts,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	Name       string     `json:"name,omitempty"`
+}
+
+// Tool declares a function the model may call.
+type Tool struct {
+	Type     string       `json:"type"` // always "function" today
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function. Parameters is a JSON Schema
+// object passed through to the provider verbatim.
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolChoiceMode is the coarse tool-calling mode of a request.
+type ToolChoiceMode string
+
+const (
+	ToolChoiceAuto     ToolChoiceMode = "auto"
+	ToolChoiceNone     ToolChoiceMode = "none"
+	ToolChoiceRequired ToolChoiceMode = "required"
+)
+
+// ToolChoice constrains tool calling. When Function is set the model is
+// forced to call that function and Mode is ignored.
+//
+// In JSON it takes OpenAI's tool_choice shape: the mode as a string ("auto",
+// "none", "required"), or {"type":"function","function":{"name":...}} when
+// Function is set. An empty Mode encodes as "auto".
+type ToolChoice struct {
+	Mode     ToolChoiceMode
+	Function string
+}
+
+// wireToolChoice is the object form of OpenAI's tool_choice.
+type wireToolChoice struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+// MarshalJSON encodes c as OpenAI's tool_choice.
+func (c ToolChoice) MarshalJSON() ([]byte, error) {
+	if c.Function != "" {
+		var w wireToolChoice
+		w.Type = "function"
+		w.Function.Name = c.Function
+		return json.Marshal(w)
+	}
+	mode := c.Mode
+	if mode == "" {
+		mode = ToolChoiceAuto
+	}
+	return json.Marshal(string(mode))
+}
+
+// UnmarshalJSON decodes either form of OpenAI's tool_choice.
+func (c *ToolChoice) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		*c = ToolChoice{Mode: ToolChoiceMode(mode)}
+		return nil
+	}
+	var w wireToolChoice
+	if err := json.Unmarshal(data, &w); err != nil {
+		return fmt.Errorf("tool_choice: %w", err)
+	}
+	if w.Function.Name == "" {
+		return errors.New("tool_choice: expected a mode or a function name")
+	}
+	*c = ToolChoice{Function: w.Function.Name}
+	return nil
+}
+
+// ToolCall is a function invocation requested by the model.
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"` // always "function" today
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall names the function and carries its arguments as a JSON
+// document encoded in a string, exactly as the model produced it.
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // PartType identifies the kind of content in a Part.
@@ -114,8 +266,19 @@ type StreamDelta struct {
 
 // Delta represents incremental content in a stream.
 type Delta struct {
-	Role    string `json:"role,omitempty"`
-	Content string `json:"content,omitempty"`
+	Role      string          `json:"role,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	ToolCalls []ToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// ToolCallDelta is an incremental piece of a streamed tool call. The first
+// delta for a given Index carries ID and Function.Name; later ones append to
+// Function.Arguments. Consumers concatenate Arguments per Index.
+type ToolCallDelta struct {
+	Index    int          `json:"index"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
 }
 
 // IntPtr returns a pointer to the given int.
@@ -123,3 +286,6 @@ func IntPtr(v int) *int { return &v }
 
 // Float64Ptr returns a pointer to the given float64.
 func Float64Ptr(v float64) *float64 { return &v }
+
+// BoolPtr returns a pointer to the given bool.
+func BoolPtr(v bool) *bool { return &v }
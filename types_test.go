@@ -1,6 +1,9 @@
 package inferrouter
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+)
 
 func TestPartIsMedia(t *testing.T) {
 	cases := []struct {
@@ -95,3 +98,47 @@ func TestInputTokenBreakdownInvariant(t *testing.T) {
 		t.Errorf("breakdown sum %d != PromptTokens %d", sum, u.PromptTokens)
 	}
 }
+
+func TestToolChoiceJSON(t *testing.T) {
+	cases := []struct {
+		choice ToolChoice
+		json   string
+	}{
+		{ToolChoice{Mode: ToolChoiceNone}, `"none"`},
+		{ToolChoice{Mode: ToolChoiceRequired}, `"required"`},
+		{ToolChoice{Function: "get_weather"}, `{"type":"function","function":{"name":"get_weather"}}`},
+	}
+	for _, tc := range cases {
+		got, err := json.Marshal(tc.choice)
+		if err != nil {
+			t.Fatalf("Marshal(%+v): %v", tc.choice, err)
+		}
+		if string(got) != tc.json {
+			t.Errorf("Marshal(%+v) = %s, want %s", tc.choice, got, tc.json)
+		}
+		var back ToolChoice
+		if err := json.Unmarshal([]byte(tc.json), &back); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", tc.json, err)
+		}
+		if back != tc.choice {
+			t.Errorf("Unmarshal(%s) = %+v, want %+v", tc.json, back, tc.choice)
+		}
+	}
+
+	// Inside a request, as an OpenAI client sends it.
+	var req ChatRequest
+	if err := json.Unmarshal([]byte(`{"tool_choice":{"type":"function","function":{"name":"f"}}}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.ToolChoice == nil || req.ToolChoice.Function != "f" {
+		t.Errorf("ToolChoice = %+v, want function f", req.ToolChoice)
+	}
+
+	var empty ToolChoice
+	if got, _ := json.Marshal(empty); string(got) != `"auto"` {
+		t.Errorf("zero ToolChoice = %s, want \"auto\"", got)
+	}
+	if err := json.Unmarshal([]byte(`{"type":"function"}`), &empty); err == nil {
+		t.Error("a function choice without a name should not decode")
+	}
+}